@@ -3,43 +3,75 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pion/webrtc/v3"
+	v1 "github.com/pojntfx/weron/pkg/api/webrtc/v1"
 	"github.com/pojntfx/weron/pkg/services"
 	"github.com/pojntfx/weron/pkg/wrtcchat"
 	"github.com/pojntfx/weron/pkg/wrtcconn"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
 )
 
 const (
-	timeoutFlag    = "timeout"
-	keyFlag        = "key"
-	namesFlag      = "names"
-	channelsFlag   = "channels"
-	idChannelFlag  = "id-channel"
-	iceFlag        = "ice"
-	forceRelayFlag = "force-relay"
-	kicksFlag      = "kicks"
+	whoCommand  = "/who"  // Chat command to list the names of the connected peers
+	nickCommand = "/nick" // Chat command to rename oneself, followed by a comma-separated list of names to try and claim one from
+
+	timeoutFlag             = "timeout"
+	keyFlag                 = "key"
+	namesFlag               = "names"
+	channelsFlag            = "channels"
+	idChannelFlag           = "id-channel"
+	idCodecFlag             = "id-codec"
+	iceFlag                 = "ice"
+	forceRelayFlag          = "force-relay"
+	kicksFlag               = "kicks"
+	claimAloneFlag          = "claim-alone"
+	compressionFlag         = "compression"
+	legacyKeyDerivationFlag = "legacy-key-derivation"
+	legacyAuthFlag          = "legacy-authentication"
+	createCommunityFlag     = "create-community"
+	identityFlag            = "identity"
+	knownPeersFlag          = "known-peers"
+	requireIdentityFlag     = "require-identity"
+	certificateFlag         = "certificate"
+	additionalKeysFlag      = "additional-keys"
+	tlsCAFlag               = "tls-ca"
+	tlsClientCertFlag       = "tls-client-cert"
+	tlsClientKeyFlag        = "tls-client-key"
+	oidcTokenFileFlag       = "oidc-token-file"
+	oidcScopesFlag          = "oidc-scopes"
 )
 
 var (
 	errMissingKey       = errors.New("missing key")
 	errMissingUsernames = errors.New("missing usernames")
+	errInvalidTLSCA     = errors.New("could not parse any certificates from TLS CA")
+	errMissingIDToken   = errors.New("OIDC issuer did not return an ID token")
+	errNoDeviceCodeFlow = errors.New("OIDC issuer does not support the device code flow")
 )
 
 func addInterruptHandler(cancel func(), closer io.Closer, before func()) {
-	s := make(chan os.Signal)
+	s := make(chan os.Signal, 1)
 	signal.Notify(s, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-s
@@ -68,6 +100,227 @@ func addInterruptHandler(cancel func(), closer io.Closer, before func()) {
 	}()
 }
 
+func logCompressionStats(stats map[string]wrtcconn.CompressionStats) {
+	for channelID, s := range stats {
+		log.Info().
+			Str("channel", channelID).
+			Int64("uncompressedBytes", s.UncompressedBytes).
+			Int64("compressedBytes", s.CompressedBytes).
+			Int64("compressed", s.Compressed).
+			Int64("skipped", s.Skipped).
+			Float64("ratio", s.Ratio()).
+			Msg("Compression statistics")
+	}
+}
+
+func addIdentityFlags(f *pflag.FlagSet) {
+	knownPeers := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		knownPeers = filepath.Join(configDir, "weron", "known_peers.json")
+	}
+
+	f.String(identityFlag, "", "Path to the Ed25519 identity key to sign introductions and claims with (will be generated if it doesn't exist; if empty, no identity will be used)")
+	f.String(knownPeersFlag, knownPeers, "Path to the store of trusted peer identities, which are remembered per signaler and community; only used if peers claim stable IDs such as names, static IPs or MAC addresses (if empty, identities will be verified but not remembered)")
+	f.Bool(requireIdentityFlag, false, "Reject peers which don't present an identity")
+	f.String(certificateFlag, "", "Path to the DTLS certificate to use for peer connections (will be generated if it doesn't exist; if empty, a new certificate will be generated on every start)")
+}
+
+// getIdentityConfig loads the identity; known peers are only used if peer IDs are stable (i.e. names instead of random IDs)
+func getIdentityConfig(stableIDs bool) (ed25519.PrivateKey, wrtcconn.KnownPeers, error) {
+	var identity ed25519.PrivateKey
+	if path := viper.GetString(identityFlag); strings.TrimSpace(path) != "" {
+		var err error
+		identity, err = wrtcconn.LoadOrCreateIdentity(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		log.Info().
+			Str("publicKey", base64.StdEncoding.EncodeToString(identity.Public().(ed25519.PublicKey))).
+			Msg("Using identity")
+	}
+
+	var knownPeers wrtcconn.KnownPeers
+	if path := viper.GetString(knownPeersFlag); stableIDs && strings.TrimSpace(path) != "" {
+		knownPeers = wrtcconn.NewFileKnownPeers(path)
+	}
+
+	return identity, knownPeers, nil
+}
+
+// getCertificateConfig loads the DTLS certificate; its fingerprint is signed with the identity so that peers can detect intercepted connections
+func getCertificateConfig() (*webrtc.Certificate, error) {
+	path := viper.GetString(certificateFlag)
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+
+	return wrtcconn.LoadOrCreateCertificate(path)
+}
+
+func addKeyFlags(f *pflag.FlagSet) {
+	f.Bool(legacyKeyDerivationFlag, false, "Send and accept signaling messages in the format of versions which predate envelopes so that peers running them can join the community (disables replay protection for those messages)")
+	f.StringSlice(additionalKeysFlag, []string{}, "Additional encryption keys for community which are accepted when decrypting, i.e. while rotating the key (comma-separated)")
+}
+
+func addSignalerAuthFlags(f *pflag.FlagSet) {
+	f.Bool(legacyAuthFlag, false, "Send the community password to signalers which don't support challenges, or which ask for it because the community has been created before verifiers were introduced (the password can end up in the logs of the signaler and of proxies in front of it)")
+	f.Bool(createCommunityFlag, true, "Create the community as an ephemeral community if it doesn't exist yet (if disabled, signalers which ask to create it are rejected)")
+}
+
+func addTLSClientFlags(f *pflag.FlagSet) {
+	f.String(tlsCAFlag, "", "Path to the PEM-encoded CA certificates to verify the signaler with (if empty, the system's CA certificates will be used)")
+	f.String(tlsClientCertFlag, "", "Path to the PEM-encoded TLS client certificate to present to the signaler (optional)")
+	f.String(tlsClientKeyFlag, "", "Path to the PEM-encoded TLS client key")
+}
+
+func addOIDCClientFlags(f *pflag.FlagSet) {
+	f.String(oidcTokenFileFlag, "", "Path to a file with the OIDC ID token to join communities which require OIDC with; it is read again before every connection to the signaler, so it can be refreshed externally")
+	f.String(oidcIssuerFlag, "", "OIDC Issuer to log in with using the device code flow to join communities which require OIDC (i.e. https://pojntfx.eu.auth0.com/); ignored if --"+oidcTokenFileFlag+" is set")
+	f.String(oidcClientIDFlag, "", "OIDC Client ID to log in with using the device code flow (i.e. myoidcclientid)")
+	f.StringSlice(oidcScopesFlag, []string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess, "profile", "email", "groups"}, "Comma-separated list of OIDC scopes to request when logging in with the device code flow")
+}
+
+// getIDTokenSource returns a function which returns the OIDC ID token to join communities which require OIDC with; it logs in with the device code flow if no token file is set, and returns nil if OIDC is not configured
+func getIDTokenSource(ctx context.Context) (func() (string, error), error) {
+	if path := strings.TrimSpace(viper.GetString(oidcTokenFileFlag)); path != "" {
+		return func() (string, error) {
+			token, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+
+			return strings.TrimSpace(string(token)), nil
+		}, nil
+	}
+
+	if strings.TrimSpace(viper.GetString(oidcIssuerFlag)) == "" || strings.TrimSpace(viper.GetString(oidcClientIDFlag)) == "" {
+		return nil, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, viper.GetString(oidcIssuerFlag))
+	if err != nil {
+		return nil, err
+	}
+
+	config := &oauth2.Config{
+		ClientID: viper.GetString(oidcClientIDFlag),
+		Endpoint: provider.Endpoint(),
+		Scopes:   viper.GetStringSlice(oidcScopesFlag),
+	}
+
+	if strings.TrimSpace(config.Endpoint.DeviceAuthURL) == "" {
+		return nil, errNoDeviceCodeFlow
+	}
+
+	res, err := config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(res.VerificationURIComplete) != "" {
+		fmt.Fprintf(os.Stderr, "To join communities which require OIDC, visit %v and confirm the code %v\n", res.VerificationURIComplete, res.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "To join communities which require OIDC, visit %v and enter the code %v\n", res.VerificationURI, res.UserCode)
+	}
+
+	token, err := config.DeviceAccessToken(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is refreshed once it has expired if the issuer has returned a refresh token
+	tokens := oauth2.ReuseTokenSource(token, config.TokenSource(ctx, token))
+
+	return func() (string, error) {
+		token, err := tokens.Token()
+		if err != nil {
+			return "", err
+		}
+
+		idToken, ok := token.Extra("id_token").(string)
+		if !ok || strings.TrimSpace(idToken) == "" {
+			return "", errMissingIDToken
+		}
+
+		return idToken, nil
+	}, nil
+}
+
+// getTLSClientConfig loads the TLS configuration to connect to the signaler with; it returns nil if the defaults should be used
+func getTLSClientConfig() (*tls.Config, error) {
+	caPath := strings.TrimSpace(viper.GetString(tlsCAFlag))
+	certPath := strings.TrimSpace(viper.GetString(tlsClientCertFlag))
+	keyPath := strings.TrimSpace(viper.GetString(tlsClientKeyFlag))
+
+	if caPath == "" && certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+
+	if caPath != "" {
+		ca, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errInvalidTLSCA
+		}
+	}
+
+	if certPath != "" || keyPath != "" {
+		certificate, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// newOnKeyUnused creates a handler which tells operators that an additional key can be removed
+func newOnKeyUnused(community string) func(keyID string) {
+	return func(keyID string) {
+		for i, key := range viper.GetStringSlice(additionalKeysFlag) {
+			id, err := wrtcconn.GetKeyID(key, community)
+			if err != nil {
+				log.Error().Err(err).Msg("Could not get ID of additional key")
+
+				return
+			}
+
+			if id == keyID {
+				log.Info().
+					Str("keyID", keyID).
+					Int("index", i).
+					Msg("Additional key has not been used by any peer for a while, it can be removed")
+
+				return
+			}
+		}
+	}
+}
+
+func onConflict(id string) {
+	log.Info().
+		Str("id", id).
+		Msg("Another peer has claimed the same ID and won the tie-break, re-claiming")
+}
+
+func onUnknownIdentity(peerID string, key ed25519.PublicKey) bool {
+	log.Info().
+		Str("id", peerID).
+		Str("publicKey", base64.StdEncoding.EncodeToString(key)).
+		Msg("Trusting identity of peer on first use")
+
+	return true
+}
+
 var chatCmd = &cobra.Command{
 	Use:     "chat",
 	Aliases: []string{"cht", "c"},
@@ -77,6 +330,10 @@ var chatCmd = &cobra.Command{
 			return err
 		}
 
+		if err := applyInvite(); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -108,7 +365,32 @@ var chatCmd = &cobra.Command{
 		q.Set("password", viper.GetString(passwordFlag))
 		u.RawQuery = q.Encode()
 
+		compression := map[string]string{}
+		for _, channel := range viper.GetStringSlice(channelsFlag) {
+			compression[channel] = viper.GetString(compressionFlag)
+		}
+
 		id := ""
+		identity, knownPeers, err := getIdentityConfig(true)
+		if err != nil {
+			return err
+		}
+
+		certificate, err := getCertificateConfig()
+		if err != nil {
+			return err
+		}
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		idToken, err := getIDTokenSource(ctx)
+		if err != nil {
+			return err
+		}
+
 		adapter := wrtcchat.NewAdapter(
 			u.String(),
 			viper.GetString(keyFlag),
@@ -131,15 +413,39 @@ var chatCmd = &cobra.Command{
 					fmt.Printf("\r\u001b[0K%v@%v: %s\n", m.PeerID, m.ChannelID, m.Body)
 					fmt.Printf("\r\u001b[0K%v> ", id)
 				},
+				OnPeerRename: func(oldID, newID string) {
+					fmt.Printf("\r\u001b[0K~%v=%v\n", oldID, newID)
+					fmt.Printf("\r\u001b[0K%v> ", id)
+				},
+				OnRosterChange: func(names []string) {
+					fmt.Printf("\r\u001b[0K*%v\n", strings.Join(names, ", "))
+					fmt.Printf("\r\u001b[0K%v> ", id)
+				},
 				Channels: viper.GetStringSlice(channelsFlag),
 				NamedAdapterConfig: &wrtcconn.NamedAdapterConfig{
 					AdapterConfig: &wrtcconn.AdapterConfig{
-						Timeout:    viper.GetDuration(timeoutFlag),
-						ForceRelay: viper.GetBool(forceRelayFlag),
+						Timeout:              viper.GetDuration(timeoutFlag),
+						ForceRelay:           viper.GetBool(forceRelayFlag),
+						LegacyKeyDerivation:  viper.GetBool(legacyKeyDerivationFlag),
+						Keys:                 viper.GetStringSlice(additionalKeysFlag),
+						OnKeyUnused:          newOnKeyUnused(viper.GetString(communityFlag)),
+						Identity:             identity,
+						KnownPeers:           knownPeers,
+						RequireIdentity:      viper.GetBool(requireIdentityFlag),
+						Certificate:          certificate,
+						TLSConfig:            tlsConfig,
+						IDToken:              idToken,
+						LegacyAuthentication: viper.GetBool(legacyAuthFlag),
+						CreateCommunity:      viper.GetBool(createCommunityFlag),
+						OnUnknownIdentity:    onUnknownIdentity,
+						Compression:          compression,
 					},
-					IDChannel: viper.GetString(idChannelFlag),
-					Names:     viper.GetStringSlice(namesFlag),
-					Kicks:     viper.GetDuration(kicksFlag),
+					IDChannel:  viper.GetString(idChannelFlag),
+					Codec:      viper.GetString(idCodecFlag),
+					Names:      viper.GetStringSlice(namesFlag),
+					Kicks:      viper.GetDuration(kicksFlag),
+					ClaimAlone: viper.GetBool(claimAloneFlag),
+					OnConflict: onConflict,
 				},
 			},
 			ctx,
@@ -148,13 +454,34 @@ var chatCmd = &cobra.Command{
 		if err := adapter.Open(); err != nil {
 			return err
 		}
-		addInterruptHandler(cancel, adapter, nil)
+		addInterruptHandler(cancel, adapter, func() {
+			logCompressionStats(adapter.CompressionStats())
+		})
 
 		go func() {
 			reader := bufio.NewScanner(os.Stdin)
 
 			for reader.Scan() {
-				adapter.SendMessage([]byte(reader.Text() + "\n"))
+				line := strings.TrimSpace(reader.Text())
+
+				switch {
+				case line == whoCommand:
+					fmt.Printf("\r\u001b[0K*%v\n", strings.Join(adapter.Roster(), ", "))
+				case strings.HasPrefix(line, nickCommand+" "):
+					candidates := []string{}
+					for _, candidate := range strings.Split(strings.TrimPrefix(line, nickCommand+" "), ",") {
+						if candidate = strings.TrimSpace(candidate); candidate != "" {
+							candidates = append(candidates, candidate)
+						}
+					}
+
+					if err := adapter.Rename(candidates); err != nil {
+						fmt.Printf("\r\u001b[0K!%v\n", err)
+					}
+				default:
+					adapter.SendMessage([]byte(reader.Text() + "\n"))
+				}
+
 				fmt.Printf("\r\u001b[0K%v> ", id)
 			}
 		}()
@@ -172,9 +499,18 @@ func init() {
 	chatCmd.PersistentFlags().StringSlice(namesFlag, []string{}, "Comma-separated list of names to try and claim one from")
 	chatCmd.PersistentFlags().StringSlice(channelsFlag, []string{services.ChatPrimary}, "Comma-separated list of channels in community to join")
 	chatCmd.PersistentFlags().String(idChannelFlag, services.ChatID, "Channel to use to negotiate names")
+	chatCmd.PersistentFlags().String(idCodecFlag, v1.CodecCBOR, "Codec to use for name negotiation messages if the peer supports it ("+v1.CodecCBOR+" or "+v1.CodecJSON+" to keep them readable for debugging)")
 	chatCmd.PersistentFlags().StringSlice(iceFlag, []string{"stun:stun.l.google.com:19302"}, "Comma-separated list of STUN servers (in format stun:host:port) and TURN servers to use (in format username:credential@turn:host:port) (i.e. username:credential@turn:global.turn.twilio.com:3478?transport=tcp)")
 	chatCmd.PersistentFlags().Bool(forceRelayFlag, false, "Force usage of TURN servers")
-	chatCmd.PersistentFlags().Duration(kicksFlag, time.Second*5, "Time to wait for kicks")
+	addKeyFlags(chatCmd.PersistentFlags())
+	addIdentityFlags(chatCmd.PersistentFlags())
+	addTLSClientFlags(chatCmd.PersistentFlags())
+	addSignalerAuthFlags(chatCmd.PersistentFlags())
+	addOIDCClientFlags(chatCmd.PersistentFlags())
+	addInviteFlags(chatCmd.PersistentFlags())
+	chatCmd.PersistentFlags().Duration(kicksFlag, time.Second*5, "Maximum time to wait for kicks; names are claimed earlier once all other clients have acknowledged the greeting")
+	chatCmd.PersistentFlags().Bool(claimAloneFlag, true, "Claim names immediately if the signaler reports no other clients in the community")
+	chatCmd.PersistentFlags().String(compressionFlag, wrtcconn.CompressionNone, "Compression algorithm to use for outgoing channels (empty, "+wrtcconn.CompressionZstd+" or "+wrtcconn.CompressionSnappy+")")
 
 	viper.AutomaticEnv()
 
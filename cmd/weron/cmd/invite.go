@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pojntfx/weron/pkg/wrtcconn"
+	"github.com/pojntfx/weron/pkg/wrtcmgr"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	inviteFlag        = "invite"
+	inviteIssuerFlag  = "invite-issuer"
+	inviteIssuersFlag = "invite-issuers"
+)
+
+var (
+	errMissingInviteIssuer = errors.New("missing invite issuer; set --" + inviteIssuerFlag + " or --" + inviteIssuersFlag)
+)
+
+func addInviteFlags(f *pflag.FlagSet) {
+	inviteIssuers := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		inviteIssuers = filepath.Join(configDir, "weron", "invite_issuers.json")
+	}
+
+	f.String(inviteFlag, "", "Invite token or URL to join with (replaces the --"+raddrFlag+", --"+communityFlag+", --"+passwordFlag+", --"+keyFlag+", --"+iceFlag+" and --"+forceRelayFlag+" flags)")
+	f.String(inviteIssuerFlag, "", "Base64-encoded public key of the expected issuer of the invite (if empty, the issuer is trusted on first use for the community and remembered in --"+inviteIssuersFlag+")")
+	f.String(inviteIssuersFlag, inviteIssuers, "Path to the store of trusted invite issuers per community")
+}
+
+// applyInvite replaces the connection flags with the values from the invite
+func applyInvite() error {
+	token := viper.GetString(inviteFlag)
+	if strings.TrimSpace(token) == "" {
+		return nil
+	}
+
+	var invite *wrtcmgr.Invite
+	if rawIssuer := viper.GetString(inviteIssuerFlag); strings.TrimSpace(rawIssuer) != "" {
+		issuer, err := base64.StdEncoding.DecodeString(rawIssuer)
+		if err != nil {
+			return err
+		}
+
+		invite, err = wrtcmgr.ParseInvite(token, ed25519.PublicKey(issuer))
+		if err != nil {
+			return err
+		}
+	} else {
+		path := viper.GetString(inviteIssuersFlag)
+		if strings.TrimSpace(path) == "" {
+			return errMissingInviteIssuer
+		}
+
+		var (
+			pinned bool
+			err    error
+		)
+		invite, pinned, err = wrtcmgr.ParseInviteTOFU(token, wrtcconn.NewFileKnownPeers(path))
+		if err != nil {
+			return err
+		}
+
+		// Anyone can create an invite for a community, so the issuer needs to be verified out-of-band
+		if pinned {
+			log.Warn().
+				Str("community", invite.Community).
+				Str("issuer", base64.StdEncoding.EncodeToString(invite.Issuer)).
+				Msg("No expected invite issuer has been set, trusting the issuer of this invite for the community from now on; verify it with the sender or set --" + inviteIssuerFlag)
+		}
+	}
+
+	log.Info().
+		Str("community", invite.Community).
+		Str("issuer", base64.StdEncoding.EncodeToString(invite.Issuer)).
+		Time("expires", invite.Expires).
+		Msg("Using invite")
+
+	viper.Set(raddrFlag, invite.Signaler)
+	viper.Set(communityFlag, invite.Community)
+	viper.Set(passwordFlag, invite.Password)
+	viper.Set(keyFlag, invite.Key)
+
+	if len(invite.ICE) > 0 {
+		viper.Set(iceFlag, invite.ICE)
+	}
+
+	if invite.ForceRelay {
+		viper.Set(forceRelayFlag, true)
+	}
+
+	return nil
+}
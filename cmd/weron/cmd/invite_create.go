@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pojntfx/weron/pkg/wrtcconn"
+	"github.com/pojntfx/weron/pkg/wrtcmgr"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	expiryFlag = "expiry"
+	urlFlag    = "url"
+)
+
+var inviteCreateCmd = &cobra.Command{
+	Use:     "create",
+	Aliases: []string{"ctr", "c", "mk"},
+	Short:   "Create a signed invite to a community",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(viper.GetString(communityFlag)) == "" {
+			return errMissingCommunity
+		}
+
+		if strings.TrimSpace(viper.GetString(passwordFlag)) == "" {
+			return errMissingPassword
+		}
+
+		if strings.TrimSpace(viper.GetString(keyFlag)) == "" {
+			return errMissingKey
+		}
+
+		path := viper.GetString(identityFlag)
+		if strings.TrimSpace(path) == "" {
+			return wrtcmgr.ErrMissingInviteIdentity
+		}
+
+		identity, err := wrtcconn.LoadOrCreateIdentity(path)
+		if err != nil {
+			return err
+		}
+
+		// Recipients need the issuer to verify the invite with --invite-issuer
+		log.Info().
+			Str("issuer", base64.StdEncoding.EncodeToString(identity.Public().(ed25519.PublicKey))).
+			Msg("Signing invite")
+
+		var expires time.Time
+		if expiry := viper.GetDuration(expiryFlag); expiry > 0 {
+			expires = time.Now().Add(expiry)
+		}
+
+		token, err := wrtcmgr.CreateInvite(wrtcmgr.Invite{
+			Signaler:   viper.GetString(raddrFlag),
+			Community:  viper.GetString(communityFlag),
+			Password:   viper.GetString(passwordFlag),
+			Key:        viper.GetString(keyFlag),
+			ICE:        viper.GetStringSlice(iceFlag),
+			ForceRelay: viper.GetBool(forceRelayFlag),
+			Expires:    expires,
+		}, identity)
+		if err != nil {
+			return err
+		}
+
+		if viper.GetBool(urlFlag) {
+			token = wrtcmgr.GetInviteURL(token)
+		}
+
+		fmt.Println(token)
+
+		return nil
+	},
+}
+
+func init() {
+	identity := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		identity = filepath.Join(configDir, "weron", "invite_identity.pem")
+	}
+
+	inviteCreateCmd.PersistentFlags().String(raddrFlag, "wss://weron.up.railway.app/", "Remote address")
+	inviteCreateCmd.PersistentFlags().String(communityFlag, "", "ID of community to invite to")
+	inviteCreateCmd.PersistentFlags().String(passwordFlag, "", "Password for community")
+	inviteCreateCmd.PersistentFlags().String(keyFlag, "", "Encryption key for community")
+	inviteCreateCmd.PersistentFlags().StringSlice(iceFlag, []string{"stun:stun.l.google.com:19302"}, "Comma-separated list of STUN servers (in format stun:host:port) and TURN servers to use (in format username:credential@turn:host:port) (i.e. username:credential@turn:global.turn.twilio.com:3478?transport=tcp)")
+	inviteCreateCmd.PersistentFlags().Bool(forceRelayFlag, false, "Force usage of TURN servers")
+	inviteCreateCmd.PersistentFlags().Duration(expiryFlag, 0, "Time after which clients refuse to use the invite (if zero, the invite doesn't expire; this is only advisory, since the invite contains the password and key, so rotate them to revoke access)")
+	inviteCreateCmd.PersistentFlags().String(identityFlag, identity, "Path to the Ed25519 identity key to sign the invite with (will be generated if it doesn't exist); recipients verify invites with its public key")
+	inviteCreateCmd.PersistentFlags().Bool(urlFlag, true, "Output the invite as an URL instead of a token")
+
+	viper.AutomaticEnv()
+
+	inviteCmd.AddCommand(inviteCreateCmd)
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var inviteCmd = &cobra.Command{
+	Use:     "invite",
+	Aliases: []string{"inv"},
+	Short:   "Manage invites to communities",
+}
+
+func init() {
+	viper.AutomaticEnv()
+
+	rootCmd.AddCommand(inviteCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+
+	"github.com/pojntfx/weron/pkg/wrtcconn"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var keyGenerateCmd = &cobra.Command{
+	Use:     "generate",
+	Aliases: []string{"gen", "g"},
+	Short:   "Generate a new encryption key for a community",
+	Long: `Generate a new encryption key for a community. To rotate the key without downtime:
+1. Start all peers with the new key in --additional-keys
+2. Restart all peers with the new key as --key and the previous key in --additional-keys
+3. Once peers report that the previous key hasn't been used for a while, remove it from --additional-keys`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(viper.GetString(communityFlag)) == "" {
+			return errMissingCommunity
+		}
+
+		key, err := wrtcconn.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		id, err := wrtcconn.GetKeyID(key, viper.GetString(communityFlag))
+		if err != nil {
+			return err
+		}
+
+		w := csv.NewWriter(os.Stdout)
+		defer w.Flush()
+
+		if err := w.Write([]string{"key", "id"}); err != nil {
+			return err
+		}
+
+		return w.Write([]string{key, id})
+	},
+}
+
+func init() {
+	keyGenerateCmd.PersistentFlags().String(communityFlag, "", "ID of community to generate the key for")
+
+	viper.AutomaticEnv()
+
+	keyCmd.AddCommand(keyGenerateCmd)
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var keyCmd = &cobra.Command{
+	Use:     "key",
+	Aliases: []string{"k", "keys"},
+	Short:   "Manage community encryption keys",
+}
+
+func init() {
+	viper.AutomaticEnv()
+
+	rootCmd.AddCommand(keyCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pojntfx/weron/pkg/wrtcmgr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	limitFlag = "limit"
+)
+
+var (
+	auditEventCSVHeader = []string{"id", "timestamp", "actor", "action", "target", "sourceIP"}
+)
+
+var managerAuditCmd = &cobra.Command{
+	Use:     "audit",
+	Aliases: []string{"aud", "a"},
+	Short:   "List the management operations which have been done on the signaler, newest first",
+	PreRunE: validateRemoteFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(viper.GetString(apiPasswordFlag)) == "" {
+			return errMissingAPIPassword
+		}
+
+		if strings.TrimSpace(viper.GetString(apiUsernameFlag)) == "" {
+			return errMissingAPIUsername
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		manager := wrtcmgr.NewManager(
+			viper.GetString(raddrFlag),
+			viper.GetString(apiUsernameFlag),
+			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
+			ctx,
+		)
+
+		e, err := manager.ListAuditEvents(viper.GetInt(limitFlag))
+		if err != nil {
+			return err
+		}
+
+		w := csv.NewWriter(os.Stdout)
+		defer w.Flush()
+
+		if err := w.Write(auditEventCSVHeader); err != nil {
+			return err
+		}
+
+		for _, event := range e {
+			if err := w.Write([]string{
+				fmt.Sprintf("%v", event.ID),
+				event.Timestamp.Format(time.RFC3339),
+				event.Actor,
+				event.Action,
+				event.Target,
+				event.SourceIP,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	addRemoteFlags(managerAuditCmd.PersistentFlags())
+	managerAuditCmd.PersistentFlags().Int(limitFlag, 100, "Maximum amount of audit events to list (0 lists all audit events)")
+
+	viper.AutomaticEnv()
+
+	managerCmd.AddCommand(managerAuditCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pojntfx/weron/pkg/wrtcmgr"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	clientCSVHeader = []string{"id", "remoteIP", "userAgent", "connectedSince", "bytesReceived", "bytesSent", "replica"}
+)
+
+var managerClientsCmd = &cobra.Command{
+	Use:     "clients",
+	Aliases: []string{"cli", "cl"},
+	Short:   "List the clients of a community which are connected to the signaler",
+	PreRunE: validateRemoteFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(viper.GetString(apiPasswordFlag)) == "" {
+			return errMissingAPIPassword
+		}
+
+		if strings.TrimSpace(viper.GetString(apiUsernameFlag)) == "" {
+			return errMissingAPIUsername
+		}
+
+		if strings.TrimSpace(viper.GetString(communityFlag)) == "" {
+			return errMissingCommunity
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		manager := wrtcmgr.NewManager(
+			viper.GetString(raddrFlag),
+			viper.GetString(apiUsernameFlag),
+			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
+			ctx,
+		)
+
+		c, err := manager.GetClients(viper.GetString(communityFlag))
+		if err != nil {
+			return err
+		}
+
+		if !c.Complete {
+			log.Warn().
+				Strs("replicas", c.Replicas).
+				Msg("Not all signalers have listed their clients in time, so the list is incomplete")
+		}
+
+		w := csv.NewWriter(os.Stdout)
+		defer w.Flush()
+
+		if err := w.Write(clientCSVHeader); err != nil {
+			return err
+		}
+
+		for _, client := range c.Clients {
+			if err := w.Write([]string{
+				client.ID,
+				client.RemoteIP,
+				client.UserAgent,
+				client.ConnectedSince.Format(time.RFC3339),
+				fmt.Sprintf("%v", client.BytesReceived),
+				fmt.Sprintf("%v", client.BytesSent),
+				client.Replica,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	addRemoteFlags(managerClientsCmd.PersistentFlags())
+	managerClientsCmd.PersistentFlags().String(communityFlag, "", "ID of community to list the clients of")
+
+	viper.AutomaticEnv()
+
+	managerCmd.AddCommand(managerClientsCmd)
+}
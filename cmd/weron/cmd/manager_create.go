@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/csv"
 	"errors"
-	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/pojntfx/weron/internal/persisters"
 	"github.com/pojntfx/weron/pkg/wrtcmgr"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,8 +20,15 @@ var (
 )
 
 const (
-	communityFlag = "community"
-	passwordFlag  = "password"
+	communityFlag      = "community"
+	passwordFlag       = "password"
+	maxClientsFlag     = "max-clients"
+	ephemeralJoinsFlag = "ephemeral-joins"
+	expiresAtFlag      = "expires-at"
+	descriptionFlag    = "description"
+	labelsFlag         = "labels"
+	oidcRequiredFlag   = "oidc-required"
+	oidcGroupsFlag     = "oidc-groups"
 )
 
 var managerCreateCmd = &cobra.Command{
@@ -52,14 +60,40 @@ var managerCreateCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
 		manager := wrtcmgr.NewManager(
 			viper.GetString(raddrFlag),
 			viper.GetString(apiUsernameFlag),
 			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
 			ctx,
 		)
 
-		c, err := manager.CreatePersistentCommunity(viper.GetString(communityFlag), viper.GetString(passwordFlag))
+		policy := persisters.Policy{
+			MaxClients:     viper.GetInt(maxClientsFlag),
+			EphemeralJoins: viper.GetBool(ephemeralJoinsFlag),
+			Description:    viper.GetString(descriptionFlag),
+			Labels:         viper.GetStringMapString(labelsFlag),
+			OIDCRequired:   viper.GetBool(oidcRequiredFlag),
+			OIDCGroups:     viper.GetStringSlice(oidcGroupsFlag),
+		}
+
+		if v := viper.GetString(expiresAtFlag); strings.TrimSpace(v) != "" {
+			expiresAt, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return err
+			}
+
+			policy.ExpiresAt = &expiresAt
+		}
+
+		c, err := manager.CreatePersistentCommunityWithPolicy(viper.GetString(communityFlag), viper.GetString(passwordFlag), policy)
 		if err != nil {
 			return err
 		}
@@ -67,11 +101,11 @@ var managerCreateCmd = &cobra.Command{
 		w := csv.NewWriter(os.Stdout)
 		defer w.Flush()
 
-		if err := w.Write([]string{"id", "clients", "persistent"}); err != nil {
+		if err := w.Write(communityCSVHeader); err != nil {
 			return err
 		}
 
-		return w.Write([]string{c.ID, fmt.Sprintf("%v", c.Clients), fmt.Sprintf("%v", c.Persistent)})
+		return w.Write(getCommunityCSVRecord(*c))
 	},
 }
 
@@ -79,6 +113,13 @@ func init() {
 	addRemoteFlags(managerCreateCmd.PersistentFlags())
 	managerCreateCmd.PersistentFlags().String(communityFlag, "", "ID of community to create")
 	managerCreateCmd.PersistentFlags().String(passwordFlag, "", "Password for community")
+	managerCreateCmd.PersistentFlags().Int(maxClientsFlag, 0, "Maximum amount of clients in the community (0 disables the limit)")
+	managerCreateCmd.PersistentFlags().String(expiresAtFlag, "", "Time after which the community expires, in RFC 3339 format (i.e. 2006-01-02T15:04:05Z) (if empty, the community doesn't expire)")
+	managerCreateCmd.PersistentFlags().Bool(ephemeralJoinsFlag, false, "Allow clients to join the community ephemerally once it has expired, that is only while it has clients left")
+	managerCreateCmd.PersistentFlags().String(descriptionFlag, "", "Description of the community")
+	managerCreateCmd.PersistentFlags().StringToString(labelsFlag, map[string]string{}, "Comma-separated list of labels for the community (i.e. team=infra,env=prod)")
+	managerCreateCmd.PersistentFlags().Bool(oidcRequiredFlag, false, "Require clients to present a valid OIDC ID token to join the community (in addition to the password)")
+	managerCreateCmd.PersistentFlags().StringSlice(oidcGroupsFlag, []string{}, "Comma-separated list of groups of which clients have to be in at least one to join the community if OIDC is required (if empty, all clients with a valid ID token can join)")
 
 	viper.AutomaticEnv()
 
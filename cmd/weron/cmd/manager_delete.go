@@ -34,10 +34,18 @@ var managerDeleteCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
 		manager := wrtcmgr.NewManager(
 			viper.GetString(raddrFlag),
 			viper.GetString(apiUsernameFlag),
 			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
 			ctx,
 		)
 
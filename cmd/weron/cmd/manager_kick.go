@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/pojntfx/weron/pkg/wrtcmgr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	clientFlag = "client"
+)
+
+var (
+	errMissingClient = errors.New("missing client")
+)
+
+var managerKickCmd = &cobra.Command{
+	Use:     "kick",
+	Aliases: []string{"k"},
+	Short:   "Disconnect a single client from a community",
+	PreRunE: validateRemoteFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(viper.GetString(apiPasswordFlag)) == "" {
+			return errMissingAPIPassword
+		}
+
+		if strings.TrimSpace(viper.GetString(apiUsernameFlag)) == "" {
+			return errMissingAPIUsername
+		}
+
+		if strings.TrimSpace(viper.GetString(communityFlag)) == "" {
+			return errMissingCommunity
+		}
+
+		if strings.TrimSpace(viper.GetString(clientFlag)) == "" {
+			return errMissingClient
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		manager := wrtcmgr.NewManager(
+			viper.GetString(raddrFlag),
+			viper.GetString(apiUsernameFlag),
+			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
+			ctx,
+		)
+
+		return manager.KickClient(
+			viper.GetString(communityFlag),
+			viper.GetString(clientFlag),
+		)
+	},
+}
+
+func init() {
+	addRemoteFlags(managerKickCmd.PersistentFlags())
+	managerKickCmd.PersistentFlags().String(communityFlag, "", "ID of community to kick the client from")
+	managerKickCmd.PersistentFlags().String(clientFlag, "", "ID of client to kick (see the clients command)")
+
+	viper.AutomaticEnv()
+
+	managerCmd.AddCommand(managerKickCmd)
+}
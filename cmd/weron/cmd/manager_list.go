@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/pojntfx/weron/internal/persisters"
 	"github.com/pojntfx/weron/pkg/wrtcmgr"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -23,6 +26,8 @@ const (
 var (
 	errMissingAPIPassword = errors.New("missing API password")
 	errMissingAPIUsername = errors.New("missing API username")
+
+	communityCSVHeader = []string{"id", "clients", "persistent", "maxClients", "ephemeralJoins", "expiresAt", "description", "labels", "owner", "oidcRequired", "oidcGroups"}
 )
 
 var managerListCmd = &cobra.Command{
@@ -46,10 +51,18 @@ var managerListCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
 		manager := wrtcmgr.NewManager(
 			viper.GetString(raddrFlag),
 			viper.GetString(apiUsernameFlag),
 			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
 			ctx,
 		)
 
@@ -61,12 +74,12 @@ var managerListCmd = &cobra.Command{
 		w := csv.NewWriter(os.Stdout)
 		defer w.Flush()
 
-		if err := w.Write([]string{"id", "clients", "persistent"}); err != nil {
+		if err := w.Write(communityCSVHeader); err != nil {
 			return err
 		}
 
 		for _, community := range c {
-			if err := w.Write([]string{community.ID, fmt.Sprintf("%v", community.Clients), fmt.Sprintf("%v", community.Persistent)}); err != nil {
+			if err := w.Write(getCommunityCSVRecord(community)); err != nil {
 				return err
 			}
 		}
@@ -83,10 +96,39 @@ func init() {
 	managerCmd.AddCommand(managerListCmd)
 }
 
+// getCommunityCSVRecord formats a community for the CSV output of the manager commands; labels are sorted by key
+func getCommunityCSVRecord(c persisters.Community) []string {
+	expiresAt := ""
+	if c.ExpiresAt != nil {
+		expiresAt = c.ExpiresAt.Format(time.RFC3339)
+	}
+
+	labels := []string{}
+	for key, value := range c.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+
+	return []string{
+		c.ID,
+		fmt.Sprintf("%v", c.Clients),
+		fmt.Sprintf("%v", c.Persistent),
+		fmt.Sprintf("%v", c.MaxClients),
+		fmt.Sprintf("%v", c.EphemeralJoins),
+		expiresAt,
+		c.Description,
+		strings.Join(labels, ","),
+		c.Owner,
+		fmt.Sprintf("%v", c.OIDCRequired),
+		strings.Join(c.OIDCGroups, ","),
+	}
+}
+
 func addRemoteFlags(f *pflag.FlagSet) {
 	f.String(apiUsernameFlag, "admin", "Username for the management API (can also be set using the API_USERNAME env variable). Ignored if any of the OIDC parameters are set.")
 	f.String(apiPasswordFlag, "", "Password for the management API (can also be set using the API_PASSWORD env variable). Ignored if any of the OIDC parameters are set.")
 	f.String(raddrFlag, "https://weron.up.railway.app/", "Remote address")
+	addTLSClientFlags(f)
 }
 
 func validateRemoteFlags(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pojntfx/weron/pkg/wrtcmgr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	graceFlag = "grace"
+)
+
+var managerUpdateCmd = &cobra.Command{
+	Use:     "update",
+	Aliases: []string{"upd", "u"},
+	Short:   "Change the password of a persistent or ephemeral community without kicking its peers",
+	PreRunE: validateRemoteFlags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(viper.GetString(apiPasswordFlag)) == "" {
+			return errMissingAPIPassword
+		}
+
+		if strings.TrimSpace(viper.GetString(apiUsernameFlag)) == "" {
+			return errMissingAPIUsername
+		}
+
+		if strings.TrimSpace(viper.GetString(communityFlag)) == "" {
+			return errMissingCommunity
+		}
+
+		if strings.TrimSpace(viper.GetString(passwordFlag)) == "" {
+			return errMissingPassword
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		manager := wrtcmgr.NewManager(
+			viper.GetString(raddrFlag),
+			viper.GetString(apiUsernameFlag),
+			viper.GetString(apiPasswordFlag),
+			&wrtcmgr.ManagerConfig{
+				TLSConfig: tlsConfig,
+			},
+			ctx,
+		)
+
+		return manager.UpdateCommunityPassword(
+			viper.GetString(communityFlag),
+			viper.GetString(passwordFlag),
+			viper.GetDuration(graceFlag),
+		)
+	},
+}
+
+func init() {
+	addRemoteFlags(managerUpdateCmd.PersistentFlags())
+	managerUpdateCmd.PersistentFlags().String(communityFlag, "", "ID of community to update")
+	managerUpdateCmd.PersistentFlags().String(passwordFlag, "", "New password for community")
+	managerUpdateCmd.PersistentFlags().Duration(graceFlag, 0, "Time during which the previous password keeps working, so that peers can be migrated to the new password (if 0, the previous password is revoked immediately)")
+
+	viper.AutomaticEnv()
+
+	managerCmd.AddCommand(managerUpdateCmd)
+}
@@ -25,6 +25,22 @@ const (
 	apiPasswordFlag          = "api-password"
 	oidcIssuerFlag           = "oidc-issuer"
 	oidcClientIDFlag         = "oidc-client-id"
+	metricsFlag              = "metrics"
+	metricsLaddrFlag         = "metrics-laddr"
+	drainFlag                = "drain"
+	maxFrameSizeFlag         = "max-frame-size"
+	messagesPerSecondFlag    = "messages-per-second"
+	messageBurstFlag         = "message-burst"
+	maxConnectionsPerIPFlag  = "max-connections-per-ip"
+	communitiesPerHourFlag   = "ephemeral-communities-per-hour"
+	trustForwardedForFlag    = "trust-forwarded-for"
+	tlsCertFlag              = "tls-cert"
+	tlsKeyFlag               = "tls-key"
+	tlsClientCAFlag          = "tls-client-ca"
+	oidcRolesClaimFlag       = "oidc-roles-claim"
+	oidcRolesFlag            = "oidc-roles"
+	oidcOwnershipFlag        = "oidc-community-ownership"
+	oidcJoinAudiencesFlag    = "oidc-join-audiences"
 )
 
 var signalerCmd = &cobra.Command{
@@ -106,6 +122,26 @@ var signalerCmd = &cobra.Command{
 				APIPassword:          viper.GetString(apiPasswordFlag),
 				OIDCIssuer:           viper.GetString(oidcIssuerFlag),
 				OIDCClientID:         viper.GetString(oidcClientIDFlag),
+				Metrics:              viper.GetBool(metricsFlag),
+				MetricsLaddr:         viper.GetString(metricsLaddrFlag),
+				Drain:                viper.GetDuration(drainFlag),
+
+				MaxFrameSize:                viper.GetInt64(maxFrameSizeFlag),
+				MessagesPerSecond:           viper.GetFloat64(messagesPerSecondFlag),
+				MessageBurst:                viper.GetInt(messageBurstFlag),
+				MaxConnectionsPerIP:         viper.GetInt(maxConnectionsPerIPFlag),
+				EphemeralCommunitiesPerHour: viper.GetInt(communitiesPerHourFlag),
+				TrustForwardedFor:           viper.GetBool(trustForwardedForFlag),
+
+				TLSCertFile:     viper.GetString(tlsCertFlag),
+				TLSKeyFile:      viper.GetString(tlsKeyFlag),
+				TLSClientCAFile: viper.GetString(tlsClientCAFlag),
+
+				OIDCRolesClaim:         viper.GetString(oidcRolesClaimFlag),
+				OIDCRoles:              viper.GetStringMapString(oidcRolesFlag),
+				OIDCCommunityOwnership: viper.GetBool(oidcOwnershipFlag),
+				OIDCJoinAudiences:      viper.GetStringSlice(oidcJoinAudiencesFlag),
+
 				OnConnect: func(raddr, community string) {
 					log.Info().
 						Str("address", raddr).
@@ -146,6 +182,22 @@ func init() {
 	signalerCmd.PersistentFlags().String(apiPasswordFlag, "", "Password for the management API (can also be set using the API_PASSWORD env variable). Ignored if any of the OIDC parameters are set.")
 	signalerCmd.PersistentFlags().String(oidcIssuerFlag, "", "OIDC Issuer (i.e. https://pojntfx.eu.auth0.com/) (can also be set using the OIDC_ISSUER env variable)")
 	signalerCmd.PersistentFlags().String(oidcClientIDFlag, "", "OIDC Client ID (i.e. myoidcclientid) (can also be set using the OIDC_CLIENT_ID env variable)")
+	signalerCmd.PersistentFlags().String(oidcRolesClaimFlag, wrtcsgl.DefaultOIDCRolesClaim, "Claim of the OIDC token which contains the groups or roles of the client")
+	signalerCmd.PersistentFlags().StringToString(oidcRolesFlag, map[string]string{}, "Comma-separated list of values of the roles claim and the role they map to ("+wrtcsgl.RoleViewer+", "+wrtcsgl.RoleOperator+" or "+wrtcsgl.RoleAdmin+") (i.e. weron-admins=admin,developers=operator); if empty, all clients with a valid OIDC token are admins")
+	signalerCmd.PersistentFlags().Bool(oidcOwnershipFlag, false, "Only allow operators to manage the communities which they have created (admins can manage all communities)")
+	signalerCmd.PersistentFlags().StringSlice(oidcJoinAudiencesFlag, []string{}, "Comma-separated list of audiences of the ID tokens with which clients can join communities which require OIDC (defaults to --"+oidcClientIDFlag+")")
+	signalerCmd.PersistentFlags().Bool(metricsFlag, false, "Expose Prometheus metrics at "+wrtcsgl.MetricsPath+" (metrics include community IDs, so consider using --"+metricsLaddrFlag+" to expose them on a private address)")
+	signalerCmd.PersistentFlags().Int64(maxFrameSizeFlag, 64*1024, "Maximum size of a WebSocket frame from a client in bytes (0 disables the limit)")
+	signalerCmd.PersistentFlags().Float64(messagesPerSecondFlag, 0, "Maximum rate of messages per connection (0 disables the limit)")
+	signalerCmd.PersistentFlags().Int(messageBurstFlag, 0, "Maximum amount of messages per connection which may be sent at once (defaults to --"+messagesPerSecondFlag+")")
+	signalerCmd.PersistentFlags().Int(maxConnectionsPerIPFlag, 0, "Maximum amount of concurrent connections per remote IP (0 disables the limit)")
+	signalerCmd.PersistentFlags().Int(communitiesPerHourFlag, 0, "Maximum amount of new ephemeral communities per remote IP per hour (0 disables the limit)")
+	signalerCmd.PersistentFlags().Bool(trustForwardedForFlag, false, "Take the remote IP from the X-Forwarded-For header (only enable this behind a trusted proxy)")
+	signalerCmd.PersistentFlags().Duration(drainFlag, 0, "Time to report the signaler as not ready at "+wrtcsgl.ReadinessPath+" before shutting down, so that load balancers can stop sending new clients")
+	signalerCmd.PersistentFlags().String(tlsCertFlag, "", "Path to the PEM-encoded TLS certificate; if set, the signaler serves wss:// and https:// directly (the certificate and key are reloaded when the files change)")
+	signalerCmd.PersistentFlags().String(tlsKeyFlag, "", "Path to the PEM-encoded TLS key")
+	signalerCmd.PersistentFlags().String(tlsClientCAFlag, "", "Path to the PEM-encoded CA certificates to verify client certificates with; if set, clients must present a certificate signed by one of them (except for the health probes)")
+	signalerCmd.PersistentFlags().String(metricsLaddrFlag, "", "Listening address for the metrics endpoint (i.e. 127.0.0.1:9090); if empty, metrics are exposed on the main listening address")
 
 	viper.AutomaticEnv()
 
@@ -29,6 +29,10 @@ var utilityLatencyCommand = &cobra.Command{
 			return err
 		}
 
+		if err := applyInvite(); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -56,6 +60,26 @@ var utilityLatencyCommand = &cobra.Command{
 		q.Set("password", viper.GetString(passwordFlag))
 		u.RawQuery = q.Encode()
 
+		identity, knownPeers, err := getIdentityConfig(false)
+		if err != nil {
+			return err
+		}
+
+		certificate, err := getCertificateConfig()
+		if err != nil {
+			return err
+		}
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		idToken, err := getIDTokenSource(ctx)
+		if err != nil {
+			return err
+		}
+
 		adapter := wrtcltc.NewAdapter(
 			u.String(),
 			viper.GetString(keyFlag),
@@ -77,8 +101,20 @@ var utilityLatencyCommand = &cobra.Command{
 						Msg("Disconnected from peer")
 				},
 				AdapterConfig: &wrtcconn.AdapterConfig{
-					Timeout:    viper.GetDuration(timeoutFlag),
-					ForceRelay: viper.GetBool(forceRelayFlag),
+					Timeout:              viper.GetDuration(timeoutFlag),
+					ForceRelay:           viper.GetBool(forceRelayFlag),
+					LegacyKeyDerivation:  viper.GetBool(legacyKeyDerivationFlag),
+					Keys:                 viper.GetStringSlice(additionalKeysFlag),
+					OnKeyUnused:          newOnKeyUnused(viper.GetString(communityFlag)),
+					Identity:             identity,
+					KnownPeers:           knownPeers,
+					RequireIdentity:      viper.GetBool(requireIdentityFlag),
+					Certificate:          certificate,
+					TLSConfig:            tlsConfig,
+					IDToken:              idToken,
+					LegacyAuthentication: viper.GetBool(legacyAuthFlag),
+					CreateCommunity:      viper.GetBool(createCommunityFlag),
+					OnUnknownIdentity:    onUnknownIdentity,
 				},
 				Server:       viper.GetBool(serverFlag),
 				PacketLength: viper.GetInt(packetLengthFlag),
@@ -145,6 +181,12 @@ func init() {
 	utilityLatencyCommand.PersistentFlags().String(keyFlag, "", "Encryption key for community")
 	utilityLatencyCommand.PersistentFlags().StringSlice(iceFlag, []string{"stun:stun.l.google.com:19302"}, "Comma-separated list of STUN servers (in format stun:host:port) and TURN servers to use (in format username:credential@turn:host:port) (i.e. username:credential@turn:global.turn.twilio.com:3478?transport=tcp)")
 	utilityLatencyCommand.PersistentFlags().Bool(forceRelayFlag, false, "Force usage of TURN servers")
+	addKeyFlags(utilityLatencyCommand.PersistentFlags())
+	addIdentityFlags(utilityLatencyCommand.PersistentFlags())
+	addTLSClientFlags(utilityLatencyCommand.PersistentFlags())
+	addSignalerAuthFlags(utilityLatencyCommand.PersistentFlags())
+	addOIDCClientFlags(utilityLatencyCommand.PersistentFlags())
+	addInviteFlags(utilityLatencyCommand.PersistentFlags())
 	utilityLatencyCommand.PersistentFlags().Bool(serverFlag, false, "Act as a server")
 	utilityLatencyCommand.PersistentFlags().Int(packetLengthFlag, 128, "Size of packet to send and acknowledge")
 	utilityLatencyCommand.PersistentFlags().Duration(pauseFlag, time.Second*1, "Time to wait before sending next packet")
@@ -31,6 +31,10 @@ var utilityThroughputCmd = &cobra.Command{
 			return err
 		}
 
+		if err := applyInvite(); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -58,6 +62,26 @@ var utilityThroughputCmd = &cobra.Command{
 		q.Set("password", viper.GetString(passwordFlag))
 		u.RawQuery = q.Encode()
 
+		identity, knownPeers, err := getIdentityConfig(false)
+		if err != nil {
+			return err
+		}
+
+		certificate, err := getCertificateConfig()
+		if err != nil {
+			return err
+		}
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		idToken, err := getIDTokenSource(ctx)
+		if err != nil {
+			return err
+		}
+
 		adapter := wrtcthr.NewAdapter(
 			u.String(),
 			viper.GetString(keyFlag),
@@ -79,8 +103,20 @@ var utilityThroughputCmd = &cobra.Command{
 						Msg("Disconnected from peer")
 				},
 				AdapterConfig: &wrtcconn.AdapterConfig{
-					Timeout:    viper.GetDuration(timeoutFlag),
-					ForceRelay: viper.GetBool(forceRelayFlag),
+					Timeout:              viper.GetDuration(timeoutFlag),
+					ForceRelay:           viper.GetBool(forceRelayFlag),
+					LegacyKeyDerivation:  viper.GetBool(legacyKeyDerivationFlag),
+					Keys:                 viper.GetStringSlice(additionalKeysFlag),
+					OnKeyUnused:          newOnKeyUnused(viper.GetString(communityFlag)),
+					Identity:             identity,
+					KnownPeers:           knownPeers,
+					RequireIdentity:      viper.GetBool(requireIdentityFlag),
+					Certificate:          certificate,
+					TLSConfig:            tlsConfig,
+					IDToken:              idToken,
+					LegacyAuthentication: viper.GetBool(legacyAuthFlag),
+					CreateCommunity:      viper.GetBool(createCommunityFlag),
+					OnUnknownIdentity:    onUnknownIdentity,
 				},
 				Server:       viper.GetBool(serverFlag),
 				PacketLength: viper.GetInt(packetLengthFlag),
@@ -161,6 +197,12 @@ func init() {
 	utilityThroughputCmd.PersistentFlags().String(keyFlag, "", "Encryption key for community")
 	utilityThroughputCmd.PersistentFlags().StringSlice(iceFlag, []string{"stun:stun.l.google.com:19302"}, "Comma-separated list of STUN servers (in format stun:host:port) and TURN servers to use (in format username:credential@turn:host:port) (i.e. username:credential@turn:global.turn.twilio.com:3478?transport=tcp)")
 	utilityThroughputCmd.PersistentFlags().Bool(forceRelayFlag, false, "Force usage of TURN servers")
+	addKeyFlags(utilityThroughputCmd.PersistentFlags())
+	addIdentityFlags(utilityThroughputCmd.PersistentFlags())
+	addTLSClientFlags(utilityThroughputCmd.PersistentFlags())
+	addSignalerAuthFlags(utilityThroughputCmd.PersistentFlags())
+	addOIDCClientFlags(utilityThroughputCmd.PersistentFlags())
+	addInviteFlags(utilityThroughputCmd.PersistentFlags())
 	utilityThroughputCmd.PersistentFlags().Bool(serverFlag, false, "Act as a server")
 	utilityThroughputCmd.PersistentFlags().Int(packetLengthFlag, 50000, "Size of packet to send")
 	utilityThroughputCmd.PersistentFlags().Int(packetCountFlag, 1000, "Amount of packets to send before waiting for acknowledgement")
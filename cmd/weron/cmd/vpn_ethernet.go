@@ -9,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/pojntfx/weron/pkg/services"
 	"github.com/pojntfx/weron/pkg/wrtcconn"
 	"github.com/pojntfx/weron/pkg/wrtceth"
 	"github.com/spf13/cobra"
@@ -30,6 +31,10 @@ var vpnEthernetCmd = &cobra.Command{
 			return err
 		}
 
+		if err := applyInvite(); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -55,6 +60,26 @@ var vpnEthernetCmd = &cobra.Command{
 		q.Set("password", viper.GetString(passwordFlag))
 		u.RawQuery = q.Encode()
 
+		identity, knownPeers, err := getIdentityConfig(strings.TrimSpace(viper.GetString(macFlag)) != "")
+		if err != nil {
+			return err
+		}
+
+		certificate, err := getCertificateConfig()
+		if err != nil {
+			return err
+		}
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		idToken, err := getIDTokenSource(ctx)
+		if err != nil {
+			return err
+		}
+
 		adapter := wrtceth.NewAdapter(
 			u.String(),
 			viper.GetString(keyFlag),
@@ -78,9 +103,24 @@ var vpnEthernetCmd = &cobra.Command{
 				},
 				Parallel: viper.GetInt(parallelFlag),
 				AdapterConfig: &wrtcconn.AdapterConfig{
-					Timeout:    viper.GetDuration(timeoutFlag),
-					ID:         viper.GetString(macFlag),
-					ForceRelay: viper.GetBool(forceRelayFlag),
+					Timeout:              viper.GetDuration(timeoutFlag),
+					ID:                   viper.GetString(macFlag),
+					ForceRelay:           viper.GetBool(forceRelayFlag),
+					LegacyKeyDerivation:  viper.GetBool(legacyKeyDerivationFlag),
+					Keys:                 viper.GetStringSlice(additionalKeysFlag),
+					OnKeyUnused:          newOnKeyUnused(viper.GetString(communityFlag)),
+					Identity:             identity,
+					KnownPeers:           knownPeers,
+					RequireIdentity:      viper.GetBool(requireIdentityFlag),
+					Certificate:          certificate,
+					TLSConfig:            tlsConfig,
+					IDToken:              idToken,
+					LegacyAuthentication: viper.GetBool(legacyAuthFlag),
+					CreateCommunity:      viper.GetBool(createCommunityFlag),
+					OnUnknownIdentity:    onUnknownIdentity,
+					Compression: map[string]string{
+						services.EthernetPrimary: viper.GetString(compressionFlag),
+					},
 				},
 			},
 			ctx,
@@ -93,7 +133,9 @@ var vpnEthernetCmd = &cobra.Command{
 		if err := adapter.Open(); err != nil {
 			return err
 		}
-		addInterruptHandler(cancel, adapter, nil)
+		addInterruptHandler(cancel, adapter, func() {
+			logCompressionStats(adapter.CompressionStats())
+		})
 
 		return adapter.Wait()
 	},
@@ -107,9 +149,16 @@ func init() {
 	vpnEthernetCmd.PersistentFlags().String(keyFlag, "", "Encryption key for community")
 	vpnEthernetCmd.PersistentFlags().StringSlice(iceFlag, []string{"stun:stun.l.google.com:19302"}, "Comma-separated list of STUN servers (in format stun:host:port) and TURN servers to use (in format username:credential@turn:host:port) (i.e. username:credential@turn:global.turn.twilio.com:3478?transport=tcp)")
 	vpnEthernetCmd.PersistentFlags().Bool(forceRelayFlag, false, "Force usage of TURN servers")
+	addKeyFlags(vpnEthernetCmd.PersistentFlags())
+	addIdentityFlags(vpnEthernetCmd.PersistentFlags())
+	addTLSClientFlags(vpnEthernetCmd.PersistentFlags())
+	addSignalerAuthFlags(vpnEthernetCmd.PersistentFlags())
+	addOIDCClientFlags(vpnEthernetCmd.PersistentFlags())
+	addInviteFlags(vpnEthernetCmd.PersistentFlags())
 	vpnEthernetCmd.PersistentFlags().String(devFlag, "", "Name to give to the TAP device (i.e. weron0) (default is auto-generated; only supported on Linux and macOS)")
 	vpnEthernetCmd.PersistentFlags().String(macFlag, "", "MAC address to give to the TAP device (i.e. 3a:f8:de:7b:ef:52) (default is auto-generated; only supported on Linux)")
 	vpnEthernetCmd.PersistentFlags().Int(parallelFlag, runtime.NumCPU(), "Amount of threads to use to decode frames")
+	vpnEthernetCmd.PersistentFlags().String(compressionFlag, wrtcconn.CompressionNone, "Compression algorithm to use for outgoing frames (empty, "+wrtcconn.CompressionZstd+" or "+wrtcconn.CompressionSnappy+")")
 
 	viper.AutomaticEnv()
 
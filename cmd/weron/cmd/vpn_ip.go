@@ -11,6 +11,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	v1 "github.com/pojntfx/weron/pkg/api/webrtc/v1"
 	"github.com/pojntfx/weron/pkg/services"
 	"github.com/pojntfx/weron/pkg/wrtcconn"
 	"github.com/pojntfx/weron/pkg/wrtcip"
@@ -37,6 +38,10 @@ var vpnIPCmd = &cobra.Command{
 			return err
 		}
 
+		if err := applyInvite(); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -72,6 +77,26 @@ var vpnIPCmd = &cobra.Command{
 		q.Set("password", viper.GetString(passwordFlag))
 		u.RawQuery = q.Encode()
 
+		identity, knownPeers, err := getIdentityConfig(viper.GetBool(staticFlag))
+		if err != nil {
+			return err
+		}
+
+		certificate, err := getCertificateConfig()
+		if err != nil {
+			return err
+		}
+
+		tlsConfig, err := getTLSClientConfig()
+		if err != nil {
+			return err
+		}
+
+		idToken, err := getIDTokenSource(ctx)
+		if err != nil {
+			return err
+		}
+
 		adapter := wrtcip.NewAdapter(
 			u.String(),
 			viper.GetString(keyFlag),
@@ -98,11 +123,29 @@ var vpnIPCmd = &cobra.Command{
 				Parallel:   viper.GetInt(parallelFlag),
 				NamedAdapterConfig: &wrtcconn.NamedAdapterConfig{
 					AdapterConfig: &wrtcconn.AdapterConfig{
-						Timeout:    viper.GetDuration(timeoutFlag),
-						ForceRelay: viper.GetBool(forceRelayFlag),
+						Timeout:              viper.GetDuration(timeoutFlag),
+						ForceRelay:           viper.GetBool(forceRelayFlag),
+						LegacyKeyDerivation:  viper.GetBool(legacyKeyDerivationFlag),
+						Keys:                 viper.GetStringSlice(additionalKeysFlag),
+						OnKeyUnused:          newOnKeyUnused(viper.GetString(communityFlag)),
+						Identity:             identity,
+						KnownPeers:           knownPeers,
+						RequireIdentity:      viper.GetBool(requireIdentityFlag),
+						Certificate:          certificate,
+						TLSConfig:            tlsConfig,
+						IDToken:              idToken,
+						LegacyAuthentication: viper.GetBool(legacyAuthFlag),
+						CreateCommunity:      viper.GetBool(createCommunityFlag),
+						OnUnknownIdentity:    onUnknownIdentity,
+						Compression: map[string]string{
+							services.IPPrimary: viper.GetString(compressionFlag),
+						},
 					},
-					IDChannel: viper.GetString(idChannelFlag),
-					Kicks:     viper.GetDuration(kicksFlag),
+					IDChannel:  viper.GetString(idChannelFlag),
+					Codec:      viper.GetString(idCodecFlag),
+					Kicks:      viper.GetDuration(kicksFlag),
+					ClaimAlone: viper.GetBool(claimAloneFlag),
+					OnConflict: onConflict,
 				},
 				Static: viper.GetBool(staticFlag),
 			},
@@ -116,7 +159,9 @@ var vpnIPCmd = &cobra.Command{
 		if err := adapter.Open(); err != nil {
 			return err
 		}
-		addInterruptHandler(cancel, adapter, nil)
+		addInterruptHandler(cancel, adapter, func() {
+			logCompressionStats(adapter.CompressionStats())
+		})
 
 		return adapter.Wait()
 	},
@@ -130,13 +175,22 @@ func init() {
 	vpnIPCmd.PersistentFlags().String(keyFlag, "", "Encryption key for community")
 	vpnIPCmd.PersistentFlags().StringSlice(iceFlag, []string{"stun:stun.l.google.com:19302"}, "Comma-separated list of STUN servers (in format stun:host:port) and TURN servers to use (in format username:credential@turn:host:port) (i.e. username:credential@turn:global.turn.twilio.com:3478?transport=tcp)")
 	vpnIPCmd.PersistentFlags().Bool(forceRelayFlag, false, "Force usage of TURN servers")
+	addKeyFlags(vpnIPCmd.PersistentFlags())
+	addIdentityFlags(vpnIPCmd.PersistentFlags())
+	addTLSClientFlags(vpnIPCmd.PersistentFlags())
+	addSignalerAuthFlags(vpnIPCmd.PersistentFlags())
+	addOIDCClientFlags(vpnIPCmd.PersistentFlags())
+	addInviteFlags(vpnIPCmd.PersistentFlags())
 	vpnIPCmd.PersistentFlags().String(devFlag, "", "Name to give to the TUN device (i.e. weron0) (default is auto-generated; only supported on Linux)")
 	vpnIPCmd.PersistentFlags().StringSlice(ipsFlag, []string{""}, "Comma-separated list of IP networks to claim an IP address from and and give to the TUN device (i.e. 2001:db8::1/32,192.0.2.1/24) (on Windows, only one IP network (either IPv4 or IPv6) is supported; on macOS, IPv4 networks are ignored)")
 	vpnIPCmd.PersistentFlags().Bool(staticFlag, false, "Try to claim the exact IPs specified in the --"+ipsFlag+" flag statically instead of selecting a random one from the specified network")
 	vpnIPCmd.PersistentFlags().Int(parallelFlag, runtime.NumCPU(), "Amount of threads to use to decode frames")
 	vpnIPCmd.PersistentFlags().String(idChannelFlag, services.IPID, "Channel to use to negotiate names")
-	vpnIPCmd.PersistentFlags().Duration(kicksFlag, time.Second*5, "Time to wait for kicks")
+	vpnIPCmd.PersistentFlags().String(idCodecFlag, v1.CodecCBOR, "Codec to use for name negotiation messages if the peer supports it ("+v1.CodecCBOR+" or "+v1.CodecJSON+" to keep them readable for debugging)")
+	vpnIPCmd.PersistentFlags().Duration(kicksFlag, time.Second*5, "Maximum time to wait for kicks; names are claimed earlier once all other clients have acknowledged the greeting")
+	vpnIPCmd.PersistentFlags().Bool(claimAloneFlag, true, "Claim names immediately if the signaler reports no other clients in the community")
 	vpnIPCmd.PersistentFlags().Int(maxRetriesFlag, 200, "Maximum amount of times to try and claim an IP address")
+	vpnIPCmd.PersistentFlags().String(compressionFlag, wrtcconn.CompressionNone, "Compression algorithm to use for outgoing packets (empty, "+wrtcconn.CompressionZstd+" or "+wrtcconn.CompressionSnappy+")")
 
 	viper.AutomaticEnv()
 
@@ -0,0 +1,14 @@
+-- +migrate Up
+alter table communities
+    add column max_clients integer not null default 0,
+    add column ephemeral_joins boolean not null default false,
+    add column expires_at timestamptz,
+    add column description text not null default '',
+    add column labels jsonb not null default '{}';
+-- +migrate Down
+alter table communities
+    drop column max_clients,
+    drop column ephemeral_joins,
+    drop column expires_at,
+    drop column description,
+    drop column labels;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +migrate Up
+alter table communities
+    add column previous_password text,
+    add column previous_password_expires_at timestamptz;
+-- +migrate Down
+alter table communities
+    drop column previous_password,
+    drop column previous_password_expires_at;
\ No newline at end of file
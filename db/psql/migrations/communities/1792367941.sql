@@ -0,0 +1,8 @@
+-- +migrate Up
+alter table communities
+    add column verifier jsonb,
+    add column previous_verifier jsonb;
+-- +migrate Down
+alter table communities
+    drop column verifier,
+    drop column previous_verifier;
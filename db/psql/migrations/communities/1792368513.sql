@@ -0,0 +1,11 @@
+-- +migrate Up
+create table audit_events (
+    id bigserial primary key,
+    timestamp timestamptz not null,
+    actor text not null,
+    action text not null,
+    target text not null,
+    source_ip text not null
+);
+-- +migrate Down
+drop table audit_events;
@@ -0,0 +1,6 @@
+-- +migrate Up
+alter table communities
+    add column owner text not null default '';
+-- +migrate Down
+alter table communities
+    drop column owner;
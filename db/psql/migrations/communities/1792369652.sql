@@ -0,0 +1,8 @@
+-- +migrate Up
+alter table communities
+    add column oidc_required boolean not null default false,
+    add column oidc_groups jsonb not null default '[]';
+-- +migrate Down
+alter table communities
+    drop column oidc_required,
+    drop column oidc_groups;
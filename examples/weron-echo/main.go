@@ -86,8 +86,9 @@ func main() {
 		strings.Split(*iceFlag, ","),
 		[]string{"weron/example/echo"},
 		&wrtcconn.AdapterConfig{
-			Timeout:    *timeoutFlag,
-			ForceRelay: *forceRelayFlag,
+			Timeout:         *timeoutFlag,
+			ForceRelay:      *forceRelayFlag,
+			CreateCommunity: true,
 			OnSignalerReconnect: func() {
 				log.Println("Reconnecting to signaler with address", *raddrFlag)
 			},
@@ -0,0 +1,116 @@
+package websocket
+
+import "github.com/pojntfx/weron/internal/encryption"
+
+const (
+	SubprotocolChallenge = "weron.challenge.v1" // Subprotocol with which clients join communities without sending the password in the URL
+
+	TypeChallenge = "challenge"
+	TypeProof     = "proof"
+	TypeVerifier  = "verifier"
+	TypePassword  = "password"
+	TypeAccepted  = "accepted"
+
+	CloseWrongPassword    = 4401 // Close code for clients which have sent a wrong password, or which tried to create a community while ephemeral communities are disabled
+	CloseCommunityFull    = 4409 // Close code for clients which tried to join a community which has reached its maximum amount of clients
+	CloseCommunityExpired = 4410 // Close code for clients which tried to join a community which has expired
+	CloseTooManyRequests  = 4429 // Close code for clients which have exceeded the quota for new ephemeral communities
+)
+
+// VerifierParameters are the public parameters of a verifier, which the client needs to prove the knowledge of the password
+type VerifierParameters struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+}
+
+// Challenge is sent by the signaler after the upgrade
+type Challenge struct {
+	*Message
+
+	Nonce     []byte               `json:"nonce"`
+	Verifiers []VerifierParameters `json:"verifiers"`        // Current and previous verifier; empty if the community has been created before verifiers were introduced, in which case the client sends the password instead
+	Create    bool                 `json:"create,omitempty"` // Whether the community doesn't exist yet, in which case the client sends a verifier for the new ephemeral community instead; if another client creates it first, the signaler sends another challenge instead of accepting the client
+}
+
+// Proof proves the knowledge of the password for each verifier of the challenge
+type Proof struct {
+	*Message
+
+	Proofs [][]byte `json:"proofs"`
+}
+
+// Verifier creates an ephemeral community
+type Verifier struct {
+	*Message
+
+	Verifier     encryption.Verifier `json:"verifier"`
+	PasswordHash string              `json:"passwordHash,omitempty"` // bcrypt hash of the password, which is stored alongside the verifier so that signalers which predate verifiers and share the database can still check passwords
+}
+
+// Password joins a community which has been created before verifiers were introduced; the signaler stores a verifier for it afterwards
+type Password struct {
+	*Message
+
+	Password string `json:"password"`
+}
+
+// Accepted is sent by the signaler once the client has joined the community
+type Accepted struct {
+	*Message
+
+	Clients   int    `json:"clients"`             // Amount of clients in the community (including the connecting client)
+	Routing   bool   `json:"routing"`             // Whether the signaler delivers routed frames only to their recipient
+	Verifier  int    `json:"verifier"`            // Index of the verifier for which the proof has been accepted
+	Signature []byte `json:"signature,omitempty"` // Proves that the signaler knows the verifier; only set if the client has sent a proof
+}
+
+func NewChallenge(nonce []byte, verifiers []VerifierParameters, create bool) *Challenge {
+	return &Challenge{
+		Message: &Message{
+			Type: TypeChallenge,
+		},
+		Nonce:     nonce,
+		Verifiers: verifiers,
+		Create:    create,
+	}
+}
+
+func NewProof(proofs [][]byte) *Proof {
+	return &Proof{
+		Message: &Message{
+			Type: TypeProof,
+		},
+		Proofs: proofs,
+	}
+}
+
+func NewVerifier(verifier encryption.Verifier, passwordHash string) *Verifier {
+	return &Verifier{
+		Message: &Message{
+			Type: TypeVerifier,
+		},
+		Verifier:     verifier,
+		PasswordHash: passwordHash,
+	}
+}
+
+func NewPassword(password string) *Password {
+	return &Password{
+		Message: &Message{
+			Type: TypePassword,
+		},
+		Password: password,
+	}
+}
+
+func NewAccepted(clients int, routing bool, verifier int, signature []byte) *Accepted {
+	return &Accepted{
+		Message: &Message{
+			Type: TypeAccepted,
+		},
+		Clients:   clients,
+		Routing:   routing,
+		Verifier:  verifier,
+		Signature: signature,
+	}
+}
@@ -0,0 +1,8 @@
+package websocket
+
+const (
+	HeaderClients = "X-Weron-Clients" // Header of the signaler's upgrade response for clients which don't use challenges with the amount of clients in the community (including the connecting client)
+	HeaderRouting = "X-Weron-Routing" // Header of the signaler's upgrade response for clients which don't use challenges which is set if the signaler delivers routed frames only to their recipient
+
+	HeaderChallenge = "X-Weron-Challenge" // Header of the signaler's responses to clients which request a challenge with the supported challenge subprotocol; it is also set if the client is rejected before the upgrade so that it can tell that the signaler supports challenges
+)
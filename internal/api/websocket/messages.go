@@ -7,15 +7,22 @@ type Message struct {
 type Introduction struct {
 	*Message
 
-	From string `json:"from"`
+	From      string `json:"from"`
+	PublicKey []byte `json:"publicKey,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Routable  bool   `json:"routable,omitempty"`
 }
 
 type Exchange struct {
 	*Message
 
-	From    string `json:"from"`
-	To      string `json:"to"`
-	Payload []byte `json:"payload"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Payload     []byte `json:"payload"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	PublicKey   []byte `json:"publicKey,omitempty"`
+	Signature   []byte `json:"signature,omitempty"`
+	Routable    bool   `json:"routable,omitempty"`
 }
 
 func NewIntroduction(from string) *Introduction {
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	QueryRecipient = "recipient" // Query parameter with which clients register their recipient token with the signaler
+
+	FrameRouted = byte(0xff) // Routed frames start with this byte, followed by the recipient token and the sealed envelope; it never collides with envelope versions
+
+	recipientTokenSize   = 16
+	recipientTokenPrefix = "weron/recipient/" // Prefix of hashed peer IDs to prevent tokens from being reused in other protocols
+)
+
+// GetRecipientToken returns the opaque token with which frames are routed to a peer; the signaler can't derive the peer ID from it
+func GetRecipientToken(community string, id string) string {
+	hash := sha256.Sum256([]byte(recipientTokenPrefix + community + "/" + id))
+
+	return hex.EncodeToString(hash[:recipientTokenSize])
+}
+
+// IsRecipientToken returns whether the token is well-formed
+func IsRecipientToken(token string) bool {
+	raw, err := hex.DecodeString(token)
+
+	return err == nil && len(raw) == recipientTokenSize
+}
+
+// NewRoutedFrame prefixes a sealed envelope with the token of its recipient
+func NewRoutedFrame(token string, envelope []byte) ([]byte, error) {
+	raw, err := hex.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{FrameRouted}, raw...), envelope...), nil
+}
+
+// ParseRoutedFrame returns the recipient token and the sealed envelope of a routed frame; ok is false if the frame is not routed
+func ParseRoutedFrame(frame []byte) (token string, envelope []byte, ok bool) {
+	if len(frame) < 1+recipientTokenSize || frame[0] != FrameRouted {
+		return "", nil, false
+	}
+
+	return hex.EncodeToString(frame[1 : 1+recipientTokenSize]), frame[1+recipientTokenSize:], true
+}
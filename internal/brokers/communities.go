@@ -1,22 +1,50 @@
 package brokers
 
-import "context"
+import (
+	"context"
+
+	v1 "github.com/pojntfx/weron/pkg/api/management/v1"
+)
 
 type Kick struct {
-	Community string `json:"community"`
+	Community string      `json:"community"`        // Community to kick all clients from
+	Client    *ClientKick `json:"client,omitempty"` // Single client to kick instead; Community is empty in this case so that signalers which can't kick single clients ignore the kick
+}
+
+type ClientKick struct {
+	Community string `json:"community"` // Community the client has joined
+	ID        string `json:"id"`        // ID of the client's connection
 }
 
 type Input struct {
 	Raddr       string `json:"raddr"`
 	MessageType int    `json:"messageType"`
 	P           []byte `json:"p"`
+	To          string `json:"to,omitempty"` // Recipient token of the only connection to deliver to; if empty, the input is delivered to all connections in the community
+}
+
+// ClientsRequest asks all signalers for the clients of a community which are connected to them
+type ClientsRequest struct {
+	ID        string `json:"id"`        // ID of the request, for which the responses are published
+	Community string `json:"community"` // Community to list the clients of
+}
+
+// ClientsResponse lists the clients of a community which are connected to one signaler
+type ClientsResponse struct {
+	Replica string      `json:"replica"` // Signaler which has responded
+	Clients []v1.Client `json:"clients"` // Clients which are connected to the signaler
 }
 
 type CommunitiesBroker interface {
 	Open(ctx context.Context, brokerURL string) error
 	SubscribeToKicks(ctx context.Context, errs chan error) (kicks chan Kick, close func() error)
-	SubscribeToInputs(ctx context.Context, errs chan error, community string) (kicks chan Input, close func() error)
+	SubscribeToInputs(ctx context.Context, errs chan error, community string, recipient string) (kicks chan Input, close func() error) // Inputs addressed to other recipients are not delivered
 	PublishInput(ctx context.Context, input Input, community string) error
 	PublishKick(ctx context.Context, kick Kick) error
+	SubscribeToClientsRequests(ctx context.Context, errs chan error) (requests chan ClientsRequest, close func() error)
+	SubscribeToClientsResponses(ctx context.Context, errs chan error, request string) (responses chan ClientsResponse, close func() error, err error) // The subscription is active once this has returned, so responses to requests which are published afterwards can't be missed
+	PublishClientsRequest(ctx context.Context, request ClientsRequest) (int, error)                                                                   // Returns the amount of signalers which have received the request
+	PublishClientsResponse(ctx context.Context, request string, response ClientsResponse) error
+	Ping(ctx context.Context) error
 	Close() error
 }
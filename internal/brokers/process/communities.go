@@ -3,6 +3,7 @@ package process
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 
 	"github.com/pojntfx/weron/internal/brokers"
 	"github.com/teivah/broadcast"
@@ -13,15 +14,31 @@ var (
 	ErrCouldNotUnmarshalInput = errors.New("could not unmarshal input")
 )
 
+type communityInput struct {
+	community string
+	input     brokers.Input
+}
+
+type clientsResponse struct {
+	request  string
+	response brokers.ClientsResponse
+}
+
 type CommunitiesBroker struct {
-	kicks  *broadcast.Relay[brokers.Kick]
-	inputs *broadcast.Relay[brokers.Input]
+	kicks            *broadcast.Relay[brokers.Kick]
+	inputs           *broadcast.Relay[communityInput]
+	clientsRequests  *broadcast.Relay[brokers.ClientsRequest]
+	clientsResponses *broadcast.Relay[clientsResponse]
+
+	clientsRequestsListeners atomic.Int64
 }
 
 func NewCommunitiesBroker() *CommunitiesBroker {
 	return &CommunitiesBroker{
-		kicks:  broadcast.NewRelay[brokers.Kick](),
-		inputs: broadcast.NewRelay[brokers.Input](),
+		kicks:            broadcast.NewRelay[brokers.Kick](),
+		inputs:           broadcast.NewRelay[communityInput](),
+		clientsRequests:  broadcast.NewRelay[brokers.ClientsRequest](),
+		clientsResponses: broadcast.NewRelay[clientsResponse](),
 	}
 }
 
@@ -53,7 +70,7 @@ func (c *CommunitiesBroker) SubscribeToKicks(ctx context.Context, errs chan erro
 	}
 }
 
-func (c *CommunitiesBroker) SubscribeToInputs(ctx context.Context, errs chan error, community string) (chan brokers.Input, func() error) {
+func (c *CommunitiesBroker) SubscribeToInputs(ctx context.Context, errs chan error, community string, recipient string) (chan brokers.Input, func() error) {
 	inputs := make(chan brokers.Input)
 
 	l := c.inputs.Listener(0)
@@ -64,8 +81,17 @@ func (c *CommunitiesBroker) SubscribeToInputs(ctx context.Context, errs chan err
 			select {
 			case <-ctx.Done():
 				return
-			case input := <-rawInputs:
-				inputs <- input
+			case input, ok := <-rawInputs:
+				if !ok {
+					// Listener closed
+					return
+				}
+
+				if input.community != community || (input.input.To != "" && input.input.To != recipient) {
+					continue
+				}
+
+				inputs <- input.input
 			}
 		}
 	}()
@@ -78,7 +104,10 @@ func (c *CommunitiesBroker) SubscribeToInputs(ctx context.Context, errs chan err
 }
 
 func (c *CommunitiesBroker) PublishInput(ctx context.Context, input brokers.Input, community string) error {
-	c.inputs.NotifyCtx(ctx, input)
+	c.inputs.NotifyCtx(ctx, communityInput{
+		community: community,
+		input:     input,
+	})
 
 	return nil
 }
@@ -89,9 +118,102 @@ func (c *CommunitiesBroker) PublishKick(ctx context.Context, kick brokers.Kick)
 	return nil
 }
 
+func (c *CommunitiesBroker) SubscribeToClientsRequests(ctx context.Context, errs chan error) (chan brokers.ClientsRequest, func() error) {
+	requests := make(chan brokers.ClientsRequest)
+
+	l := c.clientsRequests.Listener(0)
+	rawRequests := l.Ch()
+
+	c.clientsRequestsListeners.Add(1)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case request, ok := <-rawRequests:
+				if !ok {
+					// Listener closed
+					return
+				}
+
+				requests <- request
+			}
+		}
+	}()
+
+	return requests, func() error {
+		c.clientsRequestsListeners.Add(-1)
+
+		l.Close()
+
+		return nil
+	}
+}
+
+func (c *CommunitiesBroker) SubscribeToClientsResponses(ctx context.Context, errs chan error, request string) (chan brokers.ClientsResponse, func() error, error) {
+	responses := make(chan brokers.ClientsResponse)
+
+	l := c.clientsResponses.Listener(0)
+	rawResponses := l.Ch()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case response, ok := <-rawResponses:
+				if !ok {
+					// Listener closed
+					return
+				}
+
+				if response.request != request {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case responses <- response.response:
+				}
+			}
+		}
+	}()
+
+	return responses, func() error {
+		l.Close()
+
+		return nil
+	}, nil
+}
+
+func (c *CommunitiesBroker) PublishClientsRequest(ctx context.Context, request brokers.ClientsRequest) (int, error) {
+	receivers := int(c.clientsRequestsListeners.Load())
+
+	c.clientsRequests.NotifyCtx(ctx, request)
+
+	return receivers, nil
+}
+
+func (c *CommunitiesBroker) PublishClientsResponse(ctx context.Context, request string, response brokers.ClientsResponse) error {
+	c.clientsResponses.NotifyCtx(ctx, clientsResponse{
+		request:  request,
+		response: response,
+	})
+
+	return nil
+}
+
+func (c *CommunitiesBroker) Ping(ctx context.Context) error {
+	return nil
+}
+
 func (c *CommunitiesBroker) Close() error {
 	c.inputs.Close()
 	c.kicks.Close()
+	c.clientsRequests.Close()
+	c.clientsResponses.Close()
 
 	return nil
 }
@@ -0,0 +1,74 @@
+package process
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojntfx/weron/internal/brokers"
+)
+
+func TestSubscribeToInputs(t *testing.T) {
+	tests := []struct {
+		name      string
+		community string
+		to        string
+		want      map[string]bool // Whether each subscriber receives the input
+	}{
+		{"broadcast", "mycommunity", "", map[string]bool{"alice": true, "bob": true, "other": false}},
+		{"addressed", "mycommunity", "alice", map[string]bool{"alice": true, "bob": false, "other": false}},
+		{"broadcast to community which looks like a recipient topic", "mycommunity.recipients.alice", "", map[string]bool{"alice": false, "bob": false, "other": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewCommunitiesBroker()
+			t.Cleanup(func() {
+				_ = broker.Close()
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			subscribers := map[string]chan brokers.Input{}
+			for name, community := range map[string]string{
+				"alice": "mycommunity",
+				"bob":   "mycommunity",
+				"other": "mycommunity.recipients.alice",
+			} {
+				recipient := name
+				if name == "other" {
+					recipient = ""
+				}
+
+				inputs, closeInputs := broker.SubscribeToInputs(ctx, make(chan error, 1), community, recipient)
+				t.Cleanup(func() {
+					_ = closeInputs()
+				})
+
+				subscribers[name] = inputs
+			}
+
+			if err := broker.PublishInput(ctx, brokers.Input{Raddr: "127.0.0.1", P: []byte("hello"), To: tt.to}, tt.community); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, inputs := range subscribers {
+				select {
+				case input := <-inputs:
+					if !tt.want[name] {
+						t.Fatalf("%v received input %v, want none", name, input)
+					}
+
+					if string(input.P) != "hello" || input.To != tt.to {
+						t.Fatalf("%v received input %v, want the published one", name, input)
+					}
+				case <-time.After(time.Millisecond * 100):
+					if tt.want[name] {
+						t.Fatalf("%v did not receive the input", name)
+					}
+				}
+			}
+		})
+	}
+}
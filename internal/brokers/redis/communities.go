@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-redis/redis/v8"
 	jsoniter "github.com/json-iterator/go"
@@ -9,14 +10,30 @@ import (
 )
 
 const (
-	topicKick           = "kick"
-	topicMessagesPrefix = "messages."
+	topicKick            = "kick"
+	topicMessagesPrefix  = "messages."
+	topicRecipientPrefix = ".recipients."
+	topicClientsRequests = "clients.requests"
+	topicClientsPrefix   = "clients.responses."
 )
 
 var (
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	// Dots separate the parts of a topic, so they are escaped in communities and recipients
+	topicEscaper = strings.NewReplacer("%", "%25", ".", "%2E")
 )
 
+// getInputsTopic returns the topic for the inputs of a community; if recipient is set, it returns the topic for the inputs which are addressed to it
+func getInputsTopic(community string, recipient string) string {
+	topic := topicMessagesPrefix + topicEscaper.Replace(community)
+	if recipient != "" {
+		topic += topicRecipientPrefix + topicEscaper.Replace(recipient)
+	}
+
+	return topic
+}
+
 type CommunitiesBroker struct {
 	client *redis.Client
 }
@@ -70,10 +87,15 @@ func (c *CommunitiesBroker) SubscribeToKicks(ctx context.Context, errs chan erro
 	return kicks, kickPubsub.Close
 }
 
-func (c *CommunitiesBroker) SubscribeToInputs(ctx context.Context, errs chan error, community string) (chan brokers.Input, func() error) {
+func (c *CommunitiesBroker) SubscribeToInputs(ctx context.Context, errs chan error, community string, recipient string) (chan brokers.Input, func() error) {
 	inputs := make(chan brokers.Input)
 
-	inputsPubsub := c.client.Subscribe(ctx, topicMessagesPrefix+community)
+	topics := []string{getInputsTopic(community, "")}
+	if recipient != "" {
+		topics = append(topics, getInputsTopic(community, recipient))
+	}
+
+	inputsPubsub := c.client.Subscribe(ctx, topics...)
 	rawKicks := inputsPubsub.Channel()
 
 	go func() {
@@ -110,7 +132,8 @@ func (c *CommunitiesBroker) PublishInput(ctx context.Context, input brokers.Inpu
 		return err
 	}
 
-	return c.client.Publish(ctx, topicMessagesPrefix+community, data).Err()
+	// Addressed inputs are only published to the subscription of their recipient
+	return c.client.Publish(ctx, getInputsTopic(community, input.To), data).Err()
 }
 
 func (c *CommunitiesBroker) PublishKick(ctx context.Context, kick brokers.Kick) error {
@@ -122,6 +145,114 @@ func (c *CommunitiesBroker) PublishKick(ctx context.Context, kick brokers.Kick)
 	return c.client.Publish(ctx, topicKick, data).Err()
 }
 
+func (c *CommunitiesBroker) SubscribeToClientsRequests(ctx context.Context, errs chan error) (chan brokers.ClientsRequest, func() error) {
+	requests := make(chan brokers.ClientsRequest)
+
+	requestsPubsub := c.client.Subscribe(ctx, topicClientsRequests)
+	rawRequests := requestsPubsub.Channel()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rawRequest := <-rawRequests:
+				if rawRequest == nil {
+					close(requests)
+
+					// Channel closed
+					return
+				}
+
+				var request brokers.ClientsRequest
+				if err := json.Unmarshal([]byte(rawRequest.Payload), &request); err != nil {
+					errs <- err
+
+					return
+				}
+
+				requests <- request
+			}
+		}
+	}()
+
+	return requests, requestsPubsub.Close
+}
+
+func (c *CommunitiesBroker) SubscribeToClientsResponses(ctx context.Context, errs chan error, request string) (chan brokers.ClientsResponse, func() error, error) {
+	responses := make(chan brokers.ClientsResponse)
+
+	responsesPubsub := c.client.Subscribe(ctx, topicClientsPrefix+request)
+
+	// Subscribing is asynchronous, so responses which are published before the subscription is confirmed would be lost
+	if _, err := responsesPubsub.Receive(ctx); err != nil {
+		_ = responsesPubsub.Close()
+
+		return nil, nil, err
+	}
+
+	rawResponses := responsesPubsub.Channel()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rawResponse := <-rawResponses:
+				if rawResponse == nil {
+					close(responses)
+
+					// Channel closed
+					return
+				}
+
+				var response brokers.ClientsResponse
+				if err := json.Unmarshal([]byte(rawResponse.Payload), &response); err != nil {
+					errs <- err
+
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case responses <- response:
+				}
+			}
+		}
+	}()
+
+	return responses, responsesPubsub.Close, nil
+}
+
+func (c *CommunitiesBroker) PublishClientsRequest(ctx context.Context, request brokers.ClientsRequest) (int, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+
+	// Every signaler is subscribed to the requests, so the amount of receivers is the amount of signalers
+	receivers, err := c.client.Publish(ctx, topicClientsRequests, data).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(receivers), nil
+}
+
+func (c *CommunitiesBroker) PublishClientsResponse(ctx context.Context, request string, response brokers.ClientsResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	return c.client.Publish(ctx, topicClientsPrefix+request, data).Err()
+}
+
+func (c *CommunitiesBroker) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 func (c *CommunitiesBroker) Close() error {
 	return c.client.Close()
 }
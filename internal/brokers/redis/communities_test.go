@@ -0,0 +1,33 @@
+package redis
+
+import "testing"
+
+func TestGetInputsTopic(t *testing.T) {
+	tests := []struct {
+		name      string
+		community string
+		recipient string
+		want      string
+	}{
+		{"broadcast", "mycommunity", "", "messages.mycommunity"},
+		{"addressed", "mycommunity", "myrecipient", "messages.mycommunity.recipients.myrecipient"},
+		{"community which looks like a recipient topic", "mycommunity.recipients.myrecipient", "", "messages.mycommunity%2Erecipients%2Emyrecipient"},
+		{"escaped community", "mycommunity%2E", "", "messages.mycommunity%252E"},
+	}
+
+	topics := map[string]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getInputsTopic(tt.community, tt.recipient)
+			if got != tt.want {
+				t.Fatalf("getInputsTopic() = %v, want %v", got, tt.want)
+			}
+
+			// Inputs of different communities or recipients must never share a topic
+			if other, ok := topics[got]; ok {
+				t.Fatalf("getInputsTopic() = %v for both %v and %v", got, other, tt.name)
+			}
+			topics[got] = tt.name
+		})
+	}
+}
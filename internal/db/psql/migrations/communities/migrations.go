@@ -34,6 +34,60 @@ func db_psql_migrations_communities_1646780237_sql() ([]byte, error) {
 	)
 }
 
+var _db_psql_migrations_communities_1792366656_sql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x7d\x90\x41\x6a\xc3\x40\x0c\x45\xf7\x3e\x85\x76\x5e\x24\x86\xee\xb3\xcd\x15\xb2\x36\xb2\x47\x49\x15\x34\xd2\x30\x92\xa9\x49\xe9\xdd\x6b\x28\x94\x90\x31\xd1\x52\x5f\x4f\xf0\xdf\x30\xc0\x21\xf3\xad\x62\x10\x5c\x4a\x87\x12\x54\x21\x70\x12\x82\xd9\x72\x5e\x94\x83\xc9\x3b\xd8\x06\x53\xda\x76\xb2\x64\x85\x8c\xeb\x38\x0b\x93\x86\x03\x6b\xd0\x6d\x83\xd4\x02\x74\x11\x81\x44\x57\x5c\x24\xe0\xe3\xf8\x8a\x51\xf9\xa4\x4c\x15\x65\xbc\x1b\xab\xc3\x64\x26\x84\xda\xa2\x57\x14\xa7\x16\x5f\x0b\x57\xf2\x11\x03\x82\x33\x79\x60\x2e\xf1\x68\xce\x12\xf9\x5c\xb9\x04\x9b\x42\xd0\x1a\xed\xfb\xbe\x6f\x20\xc1\x89\xc4\xe1\xee\xa6\xd3\x0e\xf0\xfd\xd3\x9f\xba\xe1\xc9\xd5\xd9\xbe\xf4\xad\xad\x54\xad\xec\xe8\x3a\x36\xe1\x8b\x94\x9d\x83\xff\xda\x6d\xf6\xd4\xb5\x0d\xff\x3a\x9d\x7e\x01\x3f\x13\x55\xfb\xe3\x01\x00\x00")
+
+func db_psql_migrations_communities_1792366656_sql() ([]byte, error) {
+	return bindata_read(
+		_db_psql_migrations_communities_1792366656_sql,
+		"../../../db/psql/migrations/communities/1792366656.sql",
+	)
+}
+
+var _db_psql_migrations_communities_1792367226_sql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x85\x8e\x31\x0e\xc2\x30\x0c\x45\xf7\x9e\xc2\x3b\xcd\x09\xba\x72\x05\xe6\xc8\x10\x0b\x59\x8a\x13\xcb\x76\x68\xc5\xe9\xe9\xd8\xa5\xf4\x8f\x5f\x4f\x7a\x2f\x25\xb8\x09\xbf\x0d\x83\xe0\xa1\x13\xd6\x20\x83\xc0\x67\x25\x78\x75\x91\xd1\x38\x98\x7c\x82\x7d\x58\xca\xfe\xd5\x21\x0d\xd4\xe8\xc3\x7d\x78\x56\x74\x5f\xbb\x15\x08\xda\x62\xbe\xc4\x32\x6d\xca\x46\x9e\x31\x20\x58\xc8\x03\x45\xe3\xbb\x4c\xe9\x90\x71\xef\x6b\xfb\x1b\x52\xac\xeb\xa9\x62\xbe\x46\x0e\x15\xcb\x0f\x86\x2f\xf1\x90\x00\x01\x00\x00")
+
+func db_psql_migrations_communities_1792367226_sql() ([]byte, error) {
+	return bindata_read(
+		_db_psql_migrations_communities_1792367226_sql,
+		"../../../db/psql/migrations/communities/1792367226.sql",
+	)
+}
+
+var _db_psql_migrations_communities_1792367941_sql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x7d\x8d\x31\x0e\x80\x20\x10\x04\x7b\x5e\x71\xbd\xf2\x02\x5b\xbf\x60\x6d\x50\x4e\x73\x06\x38\x72\x80\x7e\x5f\x4a\x95\xc4\x2d\x77\x26\x19\xad\xa1\xf3\xb4\x8b\xc9\x08\x53\x54\xc6\x65\x14\xc8\x66\x71\x08\x2b\x7b\x5f\x02\x65\xc2\xa4\xa0\xce\x58\x5b\x3f\x57\x7c\x80\x13\x85\x36\xaa\xe6\x91\x38\x2c\xfd\x17\x47\xc1\x93\xb8\xa4\xf9\xed\x0d\x4a\x3f\x6a\x23\x5f\xe1\xb7\x67\x85\xe3\x37\xd8\x37\xa4\x69\x0d\xea\x06\x6f\xf9\xcb\x36\xd4\x00\x00\x00")
+
+func db_psql_migrations_communities_1792367941_sql() ([]byte, error) {
+	return bindata_read(
+		_db_psql_migrations_communities_1792367941_sql,
+		"../../../db/psql/migrations/communities/1792367941.sql",
+	)
+}
+
+var _db_psql_migrations_communities_1792368513_sql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x6d\xcf\x3b\x0e\xc2\x40\x0c\x04\xd0\x7e\x4f\xe1\x12\x04\x39\x41\x5a\xae\x40\x1d\x39\x1b\x2b\xb2\xd8\x9f\xbc\x13\x20\x9c\x9e\x5f\x01\x88\x9d\x6a\xa4\x37\xcd\x74\x1d\xed\xa2\xce\xc6\x10\x3a\x16\xe7\x4d\x9e\x0d\x3c\x06\x21\x5e\x26\xc5\x20\x67\x49\xa8\xb4\x71\xf4\x88\x4e\x34\xea\x5c\xc5\x94\x03\x15\xd3\xc8\xb6\xd2\x49\xd6\xfd\x4b\xa1\x51\x2a\x38\x96\x4f\xc3\x8d\x52\x06\xa5\x25\x84\xf7\x86\x3d\xb2\x11\xe4\x8a\x7f\xd0\x9c\x5a\x02\xb6\x59\xd0\x92\x9a\x17\xf3\x32\x68\xf9\x45\xb7\xed\x5d\xf7\x75\xec\x90\x2f\xc9\x4d\x96\x4b\xe3\x58\xef\xee\xf5\xd5\xec\x5a\x03\x01\x00\x00")
+
+func db_psql_migrations_communities_1792368513_sql() ([]byte, error) {
+	return bindata_read(
+		_db_psql_migrations_communities_1792368513_sql,
+		"../../../db/psql/migrations/communities/1792368513.sql",
+	)
+}
+
+var _db_psql_migrations_communities_1792369076_sql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x7d\x8c\x3b\x0a\x80\x30\x10\x05\xfb\x9c\xe2\x75\x16\x92\x13\xa4\xf5\x0a\x1e\x60\x35\xab\x04\x36\x1f\xe2\x06\x3d\xbe\xb1\xd3\xc6\x07\xd3\x3c\x86\xb1\x16\x63\x0c\x7b\x25\x65\xcc\xc5\x90\x28\x57\x28\x2d\xc2\x58\x73\x8c\x2d\x05\x0d\x7c\x18\xf4\x91\xf7\xfd\x93\x16\x13\xf2\x99\x1e\x8d\x2f\x45\xca\x9d\x26\x02\xcf\x1b\x35\x51\x0c\x83\x33\xf6\x55\x9d\xba\xfc\xdb\xf5\x35\x97\x4f\xd8\x99\x1b\x2e\xc9\x4e\x2a\x96\x00\x00\x00")
+
+func db_psql_migrations_communities_1792369076_sql() ([]byte, error) {
+	return bindata_read(
+		_db_psql_migrations_communities_1792369076_sql,
+		"../../../db/psql/migrations/communities/1792369076.sql",
+	)
+}
+
+var _db_psql_migrations_communities_1792369652_sql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x7d\xce\x31\x0e\xc2\x30\x0c\x05\xd0\x3d\xa7\xf0\xd6\x81\xe6\x04\x5d\xb9\x02\x13\x42\xc8\x69\xdc\x2a\xc8\xb1\x43\xe2\x88\xeb\x53\x89\x05\x89\x88\x3f\xfe\xaf\x2f\x3d\xef\xe1\x94\xd3\x5e\xd1\x08\x2e\xc5\x21\x1b\x55\x30\x0c\x4c\xb0\x6a\xce\x5d\x92\x25\x6a\x0e\x8e\x60\x8c\x47\xc7\x3d\x0b\x68\x8a\xeb\xbd\xd2\xb3\xa7\x4a\x11\x82\x2a\x13\x0a\x88\x1a\x48\x67\x86\x48\x1b\x76\x36\xd8\x90\x1b\xcd\xc3\xf3\x5e\xb5\x97\x06\x8f\xa6\x12\x7e\x8f\xd3\xf5\x36\x2d\xce\x7f\xd9\xce\xfa\x92\xbf\xba\x58\xb5\x0c\x79\xf3\x78\xfe\x00\x16\xf7\x06\xd2\x5e\xdf\x80\x01\x01\x00\x00")
+
+func db_psql_migrations_communities_1792369652_sql() ([]byte, error) {
+	return bindata_read(
+		_db_psql_migrations_communities_1792369652_sql,
+		"../../../db/psql/migrations/communities/1792369652.sql",
+	)
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -57,6 +111,12 @@ func AssetNames() []string {
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() ([]byte, error){
 	"../../../db/psql/migrations/communities/1646780237.sql": db_psql_migrations_communities_1646780237_sql,
+	"../../../db/psql/migrations/communities/1792366656.sql": db_psql_migrations_communities_1792366656_sql,
+	"../../../db/psql/migrations/communities/1792367226.sql": db_psql_migrations_communities_1792367226_sql,
+	"../../../db/psql/migrations/communities/1792367941.sql": db_psql_migrations_communities_1792367941_sql,
+	"../../../db/psql/migrations/communities/1792368513.sql": db_psql_migrations_communities_1792368513_sql,
+	"../../../db/psql/migrations/communities/1792369076.sql": db_psql_migrations_communities_1792369076_sql,
+	"../../../db/psql/migrations/communities/1792369652.sql": db_psql_migrations_communities_1792369652_sql,
 }
 // AssetDir returns the file names below a certain
 // directory embedded in the file by go-bindata.
@@ -107,6 +167,18 @@ var _bintree = &_bintree_t{nil, map[string]*_bintree_t{
 							"communities": &_bintree_t{nil, map[string]*_bintree_t{
 								"1646780237.sql": &_bintree_t{db_psql_migrations_communities_1646780237_sql, map[string]*_bintree_t{
 								}},
+								"1792366656.sql": &_bintree_t{db_psql_migrations_communities_1792366656_sql, map[string]*_bintree_t{
+								}},
+								"1792367226.sql": &_bintree_t{db_psql_migrations_communities_1792367226_sql, map[string]*_bintree_t{
+								}},
+								"1792367941.sql": &_bintree_t{db_psql_migrations_communities_1792367941_sql, map[string]*_bintree_t{
+								}},
+								"1792368513.sql": &_bintree_t{db_psql_migrations_communities_1792368513_sql, map[string]*_bintree_t{
+								}},
+								"1792369076.sql": &_bintree_t{db_psql_migrations_communities_1792369076_sql, map[string]*_bintree_t{
+								}},
+								"1792369652.sql": &_bintree_t{db_psql_migrations_communities_1792369652_sql, map[string]*_bintree_t{
+								}},
 							}},
 						}},
 					}},
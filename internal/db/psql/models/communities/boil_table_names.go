@@ -4,9 +4,11 @@
 package models
 
 var TableNames = struct {
+	AuditEvents    string
 	Communities    string
 	GorpMigrations string
 }{
+	AuditEvents:    "audit_events",
 	Communities:    "communities",
 	GorpMigrations: "gorp_migrations",
 }
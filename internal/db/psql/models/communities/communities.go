@@ -14,81 +14,112 @@ import (
 	"time"
 
 	"github.com/friendsofgo/errors"
+	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 	"github.com/volatiletech/sqlboiler/v4/queries/qmhelper"
+	"github.com/volatiletech/sqlboiler/v4/types"
 	"github.com/volatiletech/strmangle"
 )
 
 // Community is an object representing the database table.
 type Community struct {
-	ID         string `boil:"id" json:"id" toml:"id" yaml:"id"`
-	Password   string `boil:"password" json:"password" toml:"password" yaml:"password"`
-	Clients    int    `boil:"clients" json:"clients" toml:"clients" yaml:"clients"`
-	Persistent bool   `boil:"persistent" json:"persistent" toml:"persistent" yaml:"persistent"`
+	ID                        string      `boil:"id" json:"id" toml:"id" yaml:"id"`
+	Password                  string      `boil:"password" json:"password" toml:"password" yaml:"password"`
+	Clients                   int         `boil:"clients" json:"clients" toml:"clients" yaml:"clients"`
+	Persistent                bool        `boil:"persistent" json:"persistent" toml:"persistent" yaml:"persistent"`
+	MaxClients                int         `boil:"max_clients" json:"max_clients" toml:"max_clients" yaml:"max_clients"`
+	EphemeralJoins            bool        `boil:"ephemeral_joins" json:"ephemeral_joins" toml:"ephemeral_joins" yaml:"ephemeral_joins"`
+	ExpiresAt                 null.Time   `boil:"expires_at" json:"expires_at,omitempty" toml:"expires_at" yaml:"expires_at,omitempty"`
+	Description               string      `boil:"description" json:"description" toml:"description" yaml:"description"`
+	Labels                    types.JSON  `boil:"labels" json:"labels" toml:"labels" yaml:"labels"`
+	PreviousPassword          null.String `boil:"previous_password" json:"previous_password,omitempty" toml:"previous_password" yaml:"previous_password,omitempty"`
+	PreviousPasswordExpiresAt null.Time   `boil:"previous_password_expires_at" json:"previous_password_expires_at,omitempty" toml:"previous_password_expires_at" yaml:"previous_password_expires_at,omitempty"`
+	Verifier                  null.JSON   `boil:"verifier" json:"verifier,omitempty" toml:"verifier" yaml:"verifier,omitempty"`
+	PreviousVerifier          null.JSON   `boil:"previous_verifier" json:"previous_verifier,omitempty" toml:"previous_verifier" yaml:"previous_verifier,omitempty"`
+	Owner                     string      `boil:"owner" json:"owner" toml:"owner" yaml:"owner"`
+	OidcRequired              bool        `boil:"oidc_required" json:"oidc_required" toml:"oidc_required" yaml:"oidc_required"`
+	OidcGroups                types.JSON  `boil:"oidc_groups" json:"oidc_groups" toml:"oidc_groups" yaml:"oidc_groups"`
 
 	R *communityR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L communityL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var CommunityColumns = struct {
-	ID         string
-	Password   string
-	Clients    string
-	Persistent string
+	ID                        string
+	Password                  string
+	Clients                   string
+	Persistent                string
+	MaxClients                string
+	EphemeralJoins            string
+	ExpiresAt                 string
+	Description               string
+	Labels                    string
+	PreviousPassword          string
+	PreviousPasswordExpiresAt string
+	Verifier                  string
+	PreviousVerifier          string
+	Owner                     string
+	OidcRequired              string
+	OidcGroups                string
 }{
-	ID:         "id",
-	Password:   "password",
-	Clients:    "clients",
-	Persistent: "persistent",
+	ID:                        "id",
+	Password:                  "password",
+	Clients:                   "clients",
+	Persistent:                "persistent",
+	MaxClients:                "max_clients",
+	EphemeralJoins:            "ephemeral_joins",
+	ExpiresAt:                 "expires_at",
+	Description:               "description",
+	Labels:                    "labels",
+	PreviousPassword:          "previous_password",
+	PreviousPasswordExpiresAt: "previous_password_expires_at",
+	Verifier:                  "verifier",
+	PreviousVerifier:          "previous_verifier",
+	Owner:                     "owner",
+	OidcRequired:              "oidc_required",
+	OidcGroups:                "oidc_groups",
 }
 
 var CommunityTableColumns = struct {
-	ID         string
-	Password   string
-	Clients    string
-	Persistent string
+	ID                        string
+	Password                  string
+	Clients                   string
+	Persistent                string
+	MaxClients                string
+	EphemeralJoins            string
+	ExpiresAt                 string
+	Description               string
+	Labels                    string
+	PreviousPassword          string
+	PreviousPasswordExpiresAt string
+	Verifier                  string
+	PreviousVerifier          string
+	Owner                     string
+	OidcRequired              string
+	OidcGroups                string
 }{
-	ID:         "communities.id",
-	Password:   "communities.password",
-	Clients:    "communities.clients",
-	Persistent: "communities.persistent",
+	ID:                        "communities.id",
+	Password:                  "communities.password",
+	Clients:                   "communities.clients",
+	Persistent:                "communities.persistent",
+	MaxClients:                "communities.max_clients",
+	EphemeralJoins:            "communities.ephemeral_joins",
+	ExpiresAt:                 "communities.expires_at",
+	Description:               "communities.description",
+	Labels:                    "communities.labels",
+	PreviousPassword:          "communities.previous_password",
+	PreviousPasswordExpiresAt: "communities.previous_password_expires_at",
+	Verifier:                  "communities.verifier",
+	PreviousVerifier:          "communities.previous_verifier",
+	Owner:                     "communities.owner",
+	OidcRequired:              "communities.oidc_required",
+	OidcGroups:                "communities.oidc_groups",
 }
 
 // Generated where
 
-type whereHelperstring struct{ field string }
-
-func (w whereHelperstring) EQ(x string) qm.QueryMod      { return qmhelper.Where(w.field, qmhelper.EQ, x) }
-func (w whereHelperstring) NEQ(x string) qm.QueryMod     { return qmhelper.Where(w.field, qmhelper.NEQ, x) }
-func (w whereHelperstring) LT(x string) qm.QueryMod      { return qmhelper.Where(w.field, qmhelper.LT, x) }
-func (w whereHelperstring) LTE(x string) qm.QueryMod     { return qmhelper.Where(w.field, qmhelper.LTE, x) }
-func (w whereHelperstring) GT(x string) qm.QueryMod      { return qmhelper.Where(w.field, qmhelper.GT, x) }
-func (w whereHelperstring) GTE(x string) qm.QueryMod     { return qmhelper.Where(w.field, qmhelper.GTE, x) }
-func (w whereHelperstring) LIKE(x string) qm.QueryMod    { return qm.Where(w.field+" LIKE ?", x) }
-func (w whereHelperstring) NLIKE(x string) qm.QueryMod   { return qm.Where(w.field+" NOT LIKE ?", x) }
-func (w whereHelperstring) ILIKE(x string) qm.QueryMod   { return qm.Where(w.field+" ILIKE ?", x) }
-func (w whereHelperstring) NILIKE(x string) qm.QueryMod  { return qm.Where(w.field+" NOT ILIKE ?", x) }
-func (w whereHelperstring) SIMILAR(x string) qm.QueryMod { return qm.Where(w.field+" SIMILAR TO ?", x) }
-func (w whereHelperstring) NSIMILAR(x string) qm.QueryMod {
-	return qm.Where(w.field+" NOT SIMILAR TO ?", x)
-}
-func (w whereHelperstring) IN(slice []string) qm.QueryMod {
-	values := make([]interface{}, 0, len(slice))
-	for _, value := range slice {
-		values = append(values, value)
-	}
-	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
-}
-func (w whereHelperstring) NIN(slice []string) qm.QueryMod {
-	values := make([]interface{}, 0, len(slice))
-	for _, value := range slice {
-		values = append(values, value)
-	}
-	return qm.WhereNotIn(fmt.Sprintf("%s NOT IN ?", w.field), values...)
-}
-
 type whereHelperint struct{ field string }
 
 func (w whereHelperint) EQ(x int) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
@@ -121,16 +152,165 @@ func (w whereHelperbool) LTE(x bool) qm.QueryMod { return qmhelper.Where(w.field
 func (w whereHelperbool) GT(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
 func (w whereHelperbool) GTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
 
+type whereHelpernull_Time struct{ field string }
+
+func (w whereHelpernull_Time) EQ(x null.Time) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpernull_Time) NEQ(x null.Time) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpernull_Time) LT(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpernull_Time) LTE(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpernull_Time) GT(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpernull_Time) GTE(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+
+func (w whereHelpernull_Time) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpernull_Time) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+
+type whereHelpertypes_JSON struct{ field string }
+
+func (w whereHelpertypes_JSON) EQ(x types.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.EQ, x)
+}
+func (w whereHelpertypes_JSON) NEQ(x types.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.NEQ, x)
+}
+func (w whereHelpertypes_JSON) LT(x types.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpertypes_JSON) LTE(x types.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpertypes_JSON) GT(x types.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpertypes_JSON) GTE(x types.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+
+type whereHelpernull_String struct{ field string }
+
+func (w whereHelpernull_String) EQ(x null.String) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpernull_String) NEQ(x null.String) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpernull_String) LT(x null.String) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpernull_String) LTE(x null.String) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpernull_String) GT(x null.String) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpernull_String) GTE(x null.String) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+func (w whereHelpernull_String) LIKE(x null.String) qm.QueryMod {
+	return qm.Where(w.field+" LIKE ?", x)
+}
+func (w whereHelpernull_String) NLIKE(x null.String) qm.QueryMod {
+	return qm.Where(w.field+" NOT LIKE ?", x)
+}
+func (w whereHelpernull_String) ILIKE(x null.String) qm.QueryMod {
+	return qm.Where(w.field+" ILIKE ?", x)
+}
+func (w whereHelpernull_String) NILIKE(x null.String) qm.QueryMod {
+	return qm.Where(w.field+" NOT ILIKE ?", x)
+}
+func (w whereHelpernull_String) SIMILAR(x null.String) qm.QueryMod {
+	return qm.Where(w.field+" SIMILAR TO ?", x)
+}
+func (w whereHelpernull_String) NSIMILAR(x null.String) qm.QueryMod {
+	return qm.Where(w.field+" NOT SIMILAR TO ?", x)
+}
+func (w whereHelpernull_String) IN(slice []string) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
+}
+func (w whereHelpernull_String) NIN(slice []string) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereNotIn(fmt.Sprintf("%s NOT IN ?", w.field), values...)
+}
+
+func (w whereHelpernull_String) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpernull_String) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+
+type whereHelpernull_JSON struct{ field string }
+
+func (w whereHelpernull_JSON) EQ(x null.JSON) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpernull_JSON) NEQ(x null.JSON) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpernull_JSON) LT(x null.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpernull_JSON) LTE(x null.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpernull_JSON) GT(x null.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpernull_JSON) GTE(x null.JSON) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+
+func (w whereHelpernull_JSON) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpernull_JSON) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+
 var CommunityWhere = struct {
-	ID         whereHelperstring
-	Password   whereHelperstring
-	Clients    whereHelperint
-	Persistent whereHelperbool
+	ID                        whereHelperstring
+	Password                  whereHelperstring
+	Clients                   whereHelperint
+	Persistent                whereHelperbool
+	MaxClients                whereHelperint
+	EphemeralJoins            whereHelperbool
+	ExpiresAt                 whereHelpernull_Time
+	Description               whereHelperstring
+	Labels                    whereHelpertypes_JSON
+	PreviousPassword          whereHelpernull_String
+	PreviousPasswordExpiresAt whereHelpernull_Time
+	Verifier                  whereHelpernull_JSON
+	PreviousVerifier          whereHelpernull_JSON
+	Owner                     whereHelperstring
+	OidcRequired              whereHelperbool
+	OidcGroups                whereHelpertypes_JSON
 }{
-	ID:         whereHelperstring{field: "\"communities\".\"id\""},
-	Password:   whereHelperstring{field: "\"communities\".\"password\""},
-	Clients:    whereHelperint{field: "\"communities\".\"clients\""},
-	Persistent: whereHelperbool{field: "\"communities\".\"persistent\""},
+	ID:                        whereHelperstring{field: "\"communities\".\"id\""},
+	Password:                  whereHelperstring{field: "\"communities\".\"password\""},
+	Clients:                   whereHelperint{field: "\"communities\".\"clients\""},
+	Persistent:                whereHelperbool{field: "\"communities\".\"persistent\""},
+	MaxClients:                whereHelperint{field: "\"communities\".\"max_clients\""},
+	EphemeralJoins:            whereHelperbool{field: "\"communities\".\"ephemeral_joins\""},
+	ExpiresAt:                 whereHelpernull_Time{field: "\"communities\".\"expires_at\""},
+	Description:               whereHelperstring{field: "\"communities\".\"description\""},
+	Labels:                    whereHelpertypes_JSON{field: "\"communities\".\"labels\""},
+	PreviousPassword:          whereHelpernull_String{field: "\"communities\".\"previous_password\""},
+	PreviousPasswordExpiresAt: whereHelpernull_Time{field: "\"communities\".\"previous_password_expires_at\""},
+	Verifier:                  whereHelpernull_JSON{field: "\"communities\".\"verifier\""},
+	PreviousVerifier:          whereHelpernull_JSON{field: "\"communities\".\"previous_verifier\""},
+	Owner:                     whereHelperstring{field: "\"communities\".\"owner\""},
+	OidcRequired:              whereHelperbool{field: "\"communities\".\"oidc_required\""},
+	OidcGroups:                whereHelpertypes_JSON{field: "\"communities\".\"oidc_groups\""},
 }
 
 // CommunityRels is where relationship names are stored.
@@ -150,9 +330,9 @@ func (*communityR) NewStruct() *communityR {
 type communityL struct{}
 
 var (
-	communityAllColumns            = []string{"id", "password", "clients", "persistent"}
-	communityColumnsWithoutDefault = []string{"id", "password", "clients", "persistent"}
-	communityColumnsWithDefault    = []string{}
+	communityAllColumns            = []string{"id", "password", "clients", "persistent", "max_clients", "ephemeral_joins", "expires_at", "description", "labels", "previous_password", "previous_password_expires_at", "verifier", "previous_verifier", "owner", "oidc_required", "oidc_groups"}
+	communityColumnsWithoutDefault = []string{"id", "password", "clients", "persistent", "expires_at", "previous_password", "previous_password_expires_at", "verifier", "previous_verifier"}
+	communityColumnsWithDefault    = []string{"max_clients", "ephemeral_joins", "description", "labels", "owner", "oidc_required", "oidc_groups"}
 	communityPrimaryKeyColumns     = []string{"id"}
 	communityGeneratedColumns      = []string{}
 )
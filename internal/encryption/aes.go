@@ -4,14 +4,16 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
+	"errors"
 )
 
-// See https://bruinsslot.jp/post/golang-crypto/
+var (
+	ErrCiphertextTooShort = errors.New("ciphertext too short")
+)
 
-func Encrypt(data, password []byte) ([]byte, error) {
-	key := deriveKey(password)
+// See https://bruinsslot.jp/post/golang-crypto/
 
+func Encrypt(data, key, additionalData []byte) ([]byte, error) {
 	blockCipher, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -27,12 +29,10 @@ func Encrypt(data, password []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return gcm.Seal(nonce, nonce, data, nil), nil
+	return gcm.Seal(nonce, nonce, data, additionalData), nil
 }
 
-func Decrypt(data, password []byte) ([]byte, error) {
-	key := deriveKey(password)
-
+func Decrypt(data, key, additionalData []byte) ([]byte, error) {
 	blockCipher, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -43,21 +43,16 @@ func Decrypt(data, password []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrCiphertextTooShort
+	}
+
 	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
 
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
 
 	return plaintext, nil
 }
-
-func deriveKey(password []byte) []byte {
-	buf := make([]byte, 32) // Will use AES-256
-
-	h := sha256.Sum224(password)
-	copy(buf, h[:]) // Fill the rest of the hash with zeros (SHA-224 leads to a 28 byte long hash)
-
-	return buf
-}
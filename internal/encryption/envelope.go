@@ -0,0 +1,254 @@
+package encryption
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	EnvelopeVersionLegacy = byte(0) // Format of peers which predate envelopes (only the nonce and ciphertext); it uses the legacy SHA-224 key derivation and doesn't bind the community, message type or timestamp
+	EnvelopeVersion1      = byte(1) // Envelope which binds the community, message type and timestamp to the ciphertext; uses the legacy SHA-224 key derivation
+	EnvelopeVersion2      = byte(2) // Like EnvelopeVersion1, but uses Argon2id with a per-community salt for key derivation
+	EnvelopeVersion3      = byte(3) // Like EnvelopeVersion2, but includes the ID of the key so that keyrings can hold multiple keys
+
+	nonceSize = 12 // Size of the AES-GCM nonce at the start of the ciphertext
+)
+
+var (
+	ErrUnsupportedEnvelopeVersion = errors.New("unsupported envelope version") // The envelope has been sealed with an unknown version
+	ErrInvalidEnvelope            = errors.New("invalid envelope")             // The envelope is too short or malformed
+	ErrStaleEnvelope              = errors.New("stale envelope")               // The envelope's timestamp is outside of the replay window
+	ErrDuplicateEnvelope          = errors.New("duplicate envelope")           // The envelope has already been received
+)
+
+// Envelope is an opened signaling frame
+type Envelope struct {
+	Version   byte      // Version of the envelope format
+	KeyID     []byte    // ID of the key which sealed the envelope (nil for versions without key IDs)
+	Type      string    // Type of the sealed message
+	Timestamp time.Time // Time at which the envelope was sealed
+	Nonce     []byte    // Unique nonce of the envelope
+	Payload   []byte    // Decrypted message
+}
+
+func getKeyIDSize(version byte) int {
+	if version >= EnvelopeVersion3 {
+		return keyIDSize
+	}
+
+	return 0
+}
+
+func marshalHeader(version byte, keyID []byte, messageType string, timestamp time.Time) ([]byte, error) {
+	if len(messageType) > 255 || len(keyID) != getKeyIDSize(version) {
+		return nil, ErrInvalidEnvelope
+	}
+
+	header := make([]byte, 9, 10+len(keyID)+len(messageType))
+	header[0] = version
+	binary.BigEndian.PutUint64(header[1:9], uint64(timestamp.UnixNano()))
+	header = append(header, keyID...)
+	header = append(header, byte(len(messageType)))
+
+	return append(header, messageType...), nil
+}
+
+func getAdditionalData(header []byte, community string) []byte {
+	return append(append([]byte{}, header...), community...)
+}
+
+// Seal encrypts a message with the keyring's primary key and binds the community, message type and timestamp to it.
+// Envelopes with EnvelopeVersionLegacy don't have a header, so that peers which predate envelopes can open them.
+func Seal(data []byte, keyring *Keyring, version byte, messageType string, timestamp time.Time) ([]byte, error) {
+	key := keyring.primary()
+
+	derived, err := key.derive(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == EnvelopeVersionLegacy {
+		return Encrypt(data, derived, nil)
+	}
+
+	var keyID []byte
+	if getKeyIDSize(version) > 0 {
+		keyID, err = key.ID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header, err := marshalHeader(version, keyID, messageType, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := Encrypt(data, derived, getAdditionalData(header, key.Community()))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, ciphertext...), nil
+}
+
+// Open decrypts a message with the key derivation indicated by its version and verifies that it has been sealed for the community.
+// Envelopes with key IDs are opened with the matching key of the keyring, all other envelopes are opened with the first key that can decrypt them.
+// Frames which can't be opened as versioned envelopes are opened as EnvelopeVersionLegacy envelopes, which have neither a type nor a timestamp.
+func Open(frame []byte, keyring *Keyring) (*Envelope, error) {
+	envelope, err := openVersioned(frame, keyring)
+	if err == nil {
+		return envelope, nil
+	}
+
+	if legacy, lerr := openLegacy(frame, keyring); lerr == nil {
+		return legacy, nil
+	}
+
+	return nil, err
+}
+
+func openLegacy(frame []byte, keyring *Keyring) (*Envelope, error) {
+	if len(frame) < nonceSize {
+		return nil, ErrInvalidEnvelope
+	}
+
+	for _, key := range keyring.keys {
+		derived, err := key.derive(EnvelopeVersionLegacy)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := Decrypt(frame, derived, nil)
+		if err != nil {
+			continue
+		}
+
+		keyring.use(key)
+
+		return &Envelope{
+			Version: EnvelopeVersionLegacy,
+			Nonce:   append([]byte{}, frame[:nonceSize]...),
+			Payload: payload,
+		}, nil
+	}
+
+	return nil, ErrInvalidEnvelope
+}
+
+func openVersioned(frame []byte, keyring *Keyring) (*Envelope, error) {
+	if len(frame) < 1 {
+		return nil, ErrInvalidEnvelope
+	}
+
+	version := frame[0]
+	if version < EnvelopeVersion1 || version > EnvelopeVersion3 {
+		return nil, ErrUnsupportedEnvelopeVersion
+	}
+
+	typeLengthOffset := 9 + getKeyIDSize(version)
+	if len(frame) < typeLengthOffset+1 {
+		return nil, ErrInvalidEnvelope
+	}
+
+	headerLength := typeLengthOffset + 1 + int(frame[typeLengthOffset])
+	if len(frame) < headerLength+nonceSize {
+		return nil, ErrInvalidEnvelope
+	}
+
+	header, ciphertext := frame[:headerLength], frame[headerLength:]
+
+	var keyID []byte
+	if typeLengthOffset > 9 {
+		keyID = append([]byte{}, header[9:typeLengthOffset]...)
+	}
+
+	keys, err := keyring.candidates(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		derived, err := key.derive(version)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := Decrypt(ciphertext, derived, getAdditionalData(header, keyring.Community()))
+		if err != nil {
+			continue
+		}
+
+		keyring.use(key)
+
+		return &Envelope{
+			Version:   version,
+			KeyID:     keyID,
+			Type:      string(header[typeLengthOffset+1:]),
+			Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(header[1:9]))),
+			Nonce:     append([]byte{}, ciphertext[:nonceSize]...),
+			Payload:   payload,
+		}, nil
+	}
+
+	return nil, ErrInvalidEnvelope
+}
+
+// ReplayFilter rejects envelopes which are stale or have already been received
+type ReplayFilter struct {
+	window time.Duration
+
+	lock sync.Mutex
+	seen map[int64]map[string]struct{} // Nonces of the received envelopes, bucketed by their timestamp in multiples of the window
+}
+
+// NewReplayFilter creates the replay filter; the window must be positive
+func NewReplayFilter(window time.Duration) *ReplayFilter {
+	return &ReplayFilter{
+		window: window,
+
+		seen: map[int64]map[string]struct{}{},
+	}
+}
+
+func (f *ReplayFilter) getBucket(t time.Time) int64 {
+	return t.UnixNano() / int64(f.window)
+}
+
+// Check returns an error if the envelope is outside of the replay window or has already been received
+func (f *ReplayFilter) Check(envelope *Envelope) error {
+	now := time.Now()
+
+	if envelope.Timestamp.Before(now.Add(-f.window)) || envelope.Timestamp.After(now.Add(f.window)) {
+		return ErrStaleEnvelope
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Forget the buckets which are entirely outside of the replay window, since their envelopes would be stale; there are at most four buckets
+	oldest := f.getBucket(now.Add(-f.window))
+	for bucket := range f.seen {
+		if bucket < oldest {
+			delete(f.seen, bucket)
+		}
+	}
+
+	// Since the timestamp is authenticated, a replayed envelope always falls into the same bucket as the original
+	bucket := f.getBucket(envelope.Timestamp)
+	nonces, ok := f.seen[bucket]
+	if !ok {
+		nonces = map[string]struct{}{}
+		f.seen[bucket] = nonces
+	}
+
+	if _, ok := nonces[string(envelope.Nonce)]; ok {
+		return ErrDuplicateEnvelope
+	}
+
+	nonces[string(envelope.Nonce)] = struct{}{}
+
+	return nil
+}
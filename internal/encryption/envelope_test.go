@@ -0,0 +1,259 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSealOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		version byte
+	}{
+		{"version 1", EnvelopeVersion1},
+		{"version 2", EnvelopeVersion2},
+		{"version 3", EnvelopeVersion3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := NewKeyring("mycommunity", []byte("mykey"))
+			timestamp := time.Unix(0, time.Now().UnixNano())
+
+			frame, err := Seal([]byte("hello"), keyring, tt.version, "introduction", timestamp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			envelope, err := Open(frame, keyring)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if envelope.Version != tt.version || envelope.Type != "introduction" || !envelope.Timestamp.Equal(timestamp) || !bytes.Equal(envelope.Payload, []byte("hello")) {
+				t.Fatalf("Open() = %+v, want version %v, type introduction, timestamp %v and payload hello", envelope, tt.version, timestamp)
+			}
+
+			if (envelope.KeyID != nil) != (tt.version >= EnvelopeVersion3) {
+				t.Fatalf("Open() key ID = %v for version %v", envelope.KeyID, tt.version)
+			}
+		})
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	seal := func(t *testing.T, version byte, community, key string) []byte {
+		frame, err := Seal([]byte("hello"), NewKeyring(community, []byte(key)), version, "offer", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return frame
+	}
+
+	tests := []struct {
+		name  string
+		frame func(t *testing.T) []byte
+		want  error
+	}{
+		{
+			"empty frame",
+			func(t *testing.T) []byte { return []byte{} },
+			ErrInvalidEnvelope,
+		},
+		{
+			"unknown version",
+			func(t *testing.T) []byte {
+				frame := seal(t, EnvelopeVersion2, "mycommunity", "mykey")
+				frame[0] = 0xff
+
+				return frame
+			},
+			ErrUnsupportedEnvelopeVersion,
+		},
+		{
+			"truncated header",
+			func(t *testing.T) []byte { return seal(t, EnvelopeVersion2, "mycommunity", "mykey")[:5] },
+			ErrInvalidEnvelope,
+		},
+		{
+			"other community",
+			func(t *testing.T) []byte { return seal(t, EnvelopeVersion2, "othercommunity", "mykey") },
+			ErrInvalidEnvelope,
+		},
+		{
+			"other key",
+			func(t *testing.T) []byte { return seal(t, EnvelopeVersion2, "mycommunity", "otherkey") },
+			ErrInvalidEnvelope,
+		},
+		{
+			"unknown key ID",
+			func(t *testing.T) []byte { return seal(t, EnvelopeVersion3, "mycommunity", "otherkey") },
+			ErrUnknownKey,
+		},
+		{
+			"tampered message type",
+			func(t *testing.T) []byte {
+				frame := seal(t, EnvelopeVersion2, "mycommunity", "mykey")
+				frame[10] = 'x'
+
+				return frame
+			},
+			ErrInvalidEnvelope,
+		},
+		{
+			"tampered timestamp",
+			func(t *testing.T) []byte {
+				frame := seal(t, EnvelopeVersion1, "mycommunity", "mykey")
+				frame[8] ^= 0xff
+
+				return frame
+			},
+			ErrInvalidEnvelope,
+		},
+		{
+			"downgraded version",
+			func(t *testing.T) []byte {
+				frame := seal(t, EnvelopeVersion2, "mycommunity", "mykey")
+				frame[0] = EnvelopeVersion1
+
+				return frame
+			},
+			ErrInvalidEnvelope,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.frame(t), NewKeyring("mycommunity", []byte("mykey"))); !errors.Is(err, tt.want) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayFilter(t *testing.T) {
+	window := time.Minute
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		envelopes []*Envelope
+		want      []error
+	}{
+		{
+			"fresh envelopes",
+			[]*Envelope{{Timestamp: now, Nonce: []byte("a")}, {Timestamp: now, Nonce: []byte("b")}},
+			[]error{nil, nil},
+		},
+		{
+			"duplicate envelope",
+			[]*Envelope{{Timestamp: now, Nonce: []byte("a")}, {Timestamp: now, Nonce: []byte("a")}},
+			[]error{nil, ErrDuplicateEnvelope},
+		},
+		{
+			"duplicate envelope from the future",
+			[]*Envelope{{Timestamp: now.Add(window / 2), Nonce: []byte("a")}, {Timestamp: now.Add(window / 2), Nonce: []byte("a")}},
+			[]error{nil, ErrDuplicateEnvelope},
+		},
+		{
+			"duplicate envelope at the edge of the window",
+			[]*Envelope{{Timestamp: now.Add(-window + time.Second), Nonce: []byte("a")}, {Timestamp: now, Nonce: []byte("b")}, {Timestamp: now.Add(-window + time.Second), Nonce: []byte("a")}},
+			[]error{nil, nil, ErrDuplicateEnvelope},
+		},
+		{
+			"stale envelope",
+			[]*Envelope{{Timestamp: now.Add(-window * 2), Nonce: []byte("a")}},
+			[]error{ErrStaleEnvelope},
+		},
+		{
+			"envelope too far in the future",
+			[]*Envelope{{Timestamp: now.Add(window * 2), Nonce: []byte("a")}},
+			[]error{ErrStaleEnvelope},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewReplayFilter(window)
+
+			for i, envelope := range tt.envelopes {
+				if err := filter.Check(envelope); !errors.Is(err, tt.want[i]) {
+					t.Fatalf("Check() of envelope %v error = %v, want %v", i, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReplayFilterForgetsStaleBuckets(t *testing.T) {
+	filter := NewReplayFilter(time.Minute)
+
+	// Fill a bucket which is entirely outside of the replay window
+	filter.seen[filter.getBucket(time.Now().Add(-time.Minute*3))] = map[string]struct{}{"a": {}}
+
+	if err := filter.Check(&Envelope{Timestamp: time.Now(), Nonce: []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filter.seen) != 1 {
+		t.Fatalf("filter has %v buckets, want 1", len(filter.seen))
+	}
+}
+
+func TestLegacyEnvelope(t *testing.T) {
+	keyring := NewKeyring("mycommunity", []byte("mykey"))
+
+	// Peers which predate envelopes seal frames as nonce and ciphertext only
+	baseline, err := Encrypt([]byte("hello"), deriveLegacyKey([]byte("mykey")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := Seal([]byte("hello"), keyring, EnvelopeVersionLegacy, "offer", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		frame   []byte
+		keyring *Keyring
+		wantErr bool
+	}{
+		{"baseline frame", baseline, keyring, false},
+		{"legacy frame", sealed, keyring, false},
+		{"legacy frame with additional key", sealed, NewKeyring("mycommunity", []byte("newkey"), []byte("mykey")), false},
+		{"legacy frame with other key", sealed, NewKeyring("mycommunity", []byte("otherkey")), true},
+		{"truncated legacy frame", sealed[:nonceSize-1], keyring, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := Open(tt.frame, tt.keyring)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if envelope.Version != EnvelopeVersionLegacy || envelope.Type != "" || !envelope.Timestamp.IsZero() || !bytes.Equal(envelope.Payload, []byte("hello")) {
+				t.Fatalf("Open() = %+v, want legacy envelope with payload hello", envelope)
+			}
+		})
+	}
+
+	// Peers which predate envelopes must be able to decrypt legacy frames
+	payload, err := Decrypt(sealed, deriveLegacyKey([]byte("mykey")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(payload, []byte("hello")) {
+		t.Fatalf("Decrypt() = %s, want hello", payload)
+	}
+}
@@ -0,0 +1,80 @@
+package encryption
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltPrefix = "weron/community/" // Prefix to hash with the community ID to get the salt
+
+	argon2Time    = 1         // Argon2id passes
+	argon2Memory  = 64 * 1024 // Argon2id memory in KiB
+	argon2Threads = 4         // Argon2id parallelism
+	keyLength     = 32        // Will use AES-256
+)
+
+// Key is a community key which caches the AES keys derived from it
+type Key struct {
+	password  []byte
+	community string
+
+	lock    sync.Mutex
+	derived map[byte][]byte
+}
+
+// NewKey creates the key
+func NewKey(password []byte, community string) *Key {
+	return &Key{
+		password:  password,
+		community: community,
+
+		derived: map[byte][]byte{},
+	}
+}
+
+// Community returns the community which the key is bound to
+func (k *Key) Community() string {
+	return k.community
+}
+
+func (k *Key) derive(version byte) ([]byte, error) {
+	// Envelopes with key IDs use the same key derivation as EnvelopeVersion2
+	if version == EnvelopeVersion3 {
+		version = EnvelopeVersion2
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if key, ok := k.derived[version]; ok {
+		return key, nil
+	}
+
+	var key []byte
+	switch version {
+	case EnvelopeVersionLegacy, EnvelopeVersion1:
+		key = deriveLegacyKey(k.password)
+	case EnvelopeVersion2:
+		salt := sha256.Sum256([]byte(saltPrefix + k.community))
+
+		key = argon2.IDKey(k.password, salt[:16], argon2Time, argon2Memory, argon2Threads, keyLength)
+	default:
+		return nil, ErrUnsupportedEnvelopeVersion
+	}
+
+	k.derived[version] = key
+
+	return key, nil
+}
+
+func deriveLegacyKey(password []byte) []byte {
+	buf := make([]byte, keyLength)
+
+	h := sha256.Sum224(password)
+	copy(buf, h[:]) // Fill the rest of the hash with zeros (SHA-224 leads to a 28 byte long hash)
+
+	return buf
+}
@@ -0,0 +1,155 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	keyIDPrefix = "weron/key-id/" // Prefix to hash with the derived key to get the key ID
+	keyIDSize   = 4               // Size of the key ID in the envelope header
+
+	generatedKeySize = 32 // Size of generated keys in bytes
+)
+
+var (
+	ErrUnknownKey = errors.New("envelope has been sealed with an unknown key") // The key ID of the envelope is not in the keyring
+)
+
+// KeyUsage describes when a key of a keyring has last been used to open an envelope
+type KeyUsage struct {
+	ID       string    // ID of the key
+	Primary  bool      // Whether the key is used for sealing envelopes
+	LastUsed time.Time // Time at which an envelope has last been opened with the key (zero if it hasn't been used yet)
+}
+
+// Keyring seals envelopes with a primary key and opens envelopes with any of its keys
+type Keyring struct {
+	keys []*Key // The first key is the primary key
+
+	lock     sync.Mutex
+	ids      map[string]*Key
+	lastUsed map[*Key]time.Time
+}
+
+// NewKeyring creates the keyring; the primary key is used for sealing, all keys are used for opening
+func NewKeyring(community string, primary []byte, keys ...[]byte) *Keyring {
+	k := &Keyring{
+		keys: []*Key{NewKey(primary, community)},
+
+		lastUsed: map[*Key]time.Time{},
+	}
+
+	for _, key := range keys {
+		k.keys = append(k.keys, NewKey(key, community))
+	}
+
+	return k
+}
+
+// GenerateKey creates a random key which can be added to keyrings
+func GenerateKey() (string, error) {
+	key := make([]byte, generatedKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// Community returns the community which the keys are bound to
+func (k *Keyring) Community() string {
+	return k.keys[0].Community()
+}
+
+// ID returns the ID of the key, which is derived from the key so that peers don't need to agree on names for keys
+func (k *Key) ID() ([]byte, error) {
+	derived, err := k.derive(EnvelopeVersion2)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(append([]byte(keyIDPrefix), derived...))
+
+	return hash[:keyIDSize], nil
+}
+
+func (k *Keyring) primary() *Key {
+	return k.keys[0]
+}
+
+// candidates returns the keys which could have sealed an envelope; envelopes with key IDs only have one candidate
+func (k *Keyring) candidates(id []byte) ([]*Key, error) {
+	if id == nil {
+		return k.keys, nil
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.ids == nil {
+		ids := map[string]*Key{}
+		for _, key := range k.keys {
+			keyID, err := key.ID()
+			if err != nil {
+				return nil, err
+			}
+
+			ids[string(keyID)] = key
+		}
+
+		k.ids = ids
+	}
+
+	key, ok := k.ids[string(id)]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return []*Key{key}, nil
+}
+
+func (k *Keyring) use(key *Key) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.lastUsed[key] = time.Now()
+}
+
+// Usage returns when each key has last been used to open an envelope
+func (k *Keyring) Usage() ([]KeyUsage, error) {
+	usage := []KeyUsage{}
+	for i, key := range k.keys {
+		id, err := key.ID()
+		if err != nil {
+			return nil, err
+		}
+
+		k.lock.Lock()
+		lastUsed := k.lastUsed[key]
+		k.lock.Unlock()
+
+		usage = append(usage, KeyUsage{
+			ID:       hex.EncodeToString(id),
+			Primary:  i == 0,
+			LastUsed: lastUsed,
+		})
+	}
+
+	return usage, nil
+}
+
+// GetKeyID returns the hex-encoded ID of a key in a community
+func GetKeyID(key []byte, community string) (string, error) {
+	id, err := NewKey(key, community).ID()
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		sealer  *Keyring
+		opener  *Keyring
+		version byte
+		want    error
+		wantKey int
+	}{
+		{"primary key", NewKeyring("mycommunity", []byte("oldkey")), NewKeyring("mycommunity", []byte("oldkey"), []byte("newkey")), EnvelopeVersion3, nil, 0},
+		{"additional key", NewKeyring("mycommunity", []byte("newkey")), NewKeyring("mycommunity", []byte("oldkey"), []byte("newkey")), EnvelopeVersion3, nil, 1},
+		{"additional key without key ID", NewKeyring("mycommunity", []byte("newkey")), NewKeyring("mycommunity", []byte("oldkey"), []byte("newkey")), EnvelopeVersion2, nil, 1},
+		{"removed key", NewKeyring("mycommunity", []byte("oldkey")), NewKeyring("mycommunity", []byte("newkey")), EnvelopeVersion3, ErrUnknownKey, -1},
+		{"removed key without key ID", NewKeyring("mycommunity", []byte("oldkey")), NewKeyring("mycommunity", []byte("newkey")), EnvelopeVersion2, ErrInvalidEnvelope, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := Seal([]byte("hello"), tt.sealer, tt.version, "offer", time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			envelope, err := Open(frame, tt.opener)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.want)
+			}
+
+			usage, uerr := tt.opener.Usage()
+			if uerr != nil {
+				t.Fatal(uerr)
+			}
+
+			for i, u := range usage {
+				if u.Primary != (i == 0) {
+					t.Fatalf("key %v primary = %v", i, u.Primary)
+				}
+
+				if used := !u.LastUsed.IsZero(); used != (i == tt.wantKey) {
+					t.Fatalf("key %v used = %v, want key %v to be used", i, used, tt.wantKey)
+				}
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !bytes.Equal(envelope.Payload, []byte("hello")) {
+				t.Fatalf("Open() payload = %s, want hello", envelope.Payload)
+			}
+		})
+	}
+}
+
+func TestGetKeyID(t *testing.T) {
+	id, err := GetKeyID([]byte("mykey"), "mycommunity")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if raw, err := hex.DecodeString(id); err != nil || len(raw) != keyIDSize {
+		t.Fatalf("GetKeyID() = %v, want %v hex-encoded bytes", id, keyIDSize)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		community string
+		wantEqual bool
+	}{
+		{"same key and community", "mykey", "mycommunity", true},
+		{"other key", "otherkey", "mycommunity", false},
+		{"other community", "mykey", "othercommunity", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other, err := GetKeyID([]byte(tt.key), tt.community)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (other == id) != tt.wantEqual {
+				t.Fatalf("GetKeyID() = %v, want equal to %v: %v", other, id, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	a, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a == b || len(a) < generatedKeySize {
+		t.Fatalf("GenerateKey() = %v and %v, want distinct keys of at least %v characters", a, b, generatedKeySize)
+	}
+}
@@ -0,0 +1,149 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+)
+
+const (
+	VerifierIterations = 4096 // PBKDF2 iterations for new verifiers
+	VerifierSaltSize   = 16   // Size of the salt of new verifiers
+
+	verifierKeySize   = sha256.Size
+	verifierClientKey = "Client Key"
+	verifierServerKey = "Server Key"
+	authMessagePrefix = "weron/challenge/v1,"
+	minimumIterations = 1
+	maximumIterations = 1 << 20
+	maximumSaltSize   = 1024
+)
+
+var (
+	ErrInvalidVerifierParameters = errors.New("invalid verifier parameters")
+)
+
+// Verifier allows checking proofs of knowledge of a password without storing the password or an equivalent of it (see RFC 5802)
+type Verifier struct {
+	Salt       []byte `json:"salt"`       // Salt of the salted password
+	Iterations int    `json:"iterations"` // PBKDF2 iterations of the salted password
+	StoredKey  []byte `json:"storedKey"`  // Hash of the client key
+	ServerKey  []byte `json:"serverKey"`  // Key to prove the knowledge of the verifier to clients with
+}
+
+// NewVerifier creates a verifier with a new salt
+func NewVerifier(password string) (*Verifier, error) {
+	salt := make([]byte, VerifierSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return NewVerifierWithSalt(password, salt, VerifierIterations)
+}
+
+// NewVerifierWithSalt creates a verifier with the given salt and iterations
+func NewVerifierWithSalt(password string, salt []byte, iterations int) (*Verifier, error) {
+	clientKey, serverKey, err := getVerifierKeys(password, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	storedKey := sha256.Sum256(clientKey)
+
+	return &Verifier{
+		Salt:       salt,
+		Iterations: iterations,
+		StoredKey:  storedKey[:],
+		ServerKey:  serverKey,
+	}, nil
+}
+
+func getVerifierKeys(password string, salt []byte, iterations int) ([]byte, []byte, error) {
+	// Salts and iterations can be chosen by the other side, so they have to be bounded
+	if iterations < minimumIterations || iterations > maximumIterations || len(salt) > maximumSaltSize {
+		return nil, nil, ErrInvalidVerifierParameters
+	}
+
+	saltedPassword, err := pbkdf2.Key(sha256.New, password, salt, iterations, verifierKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return getHMAC(saltedPassword, []byte(verifierClientKey)), getHMAC(saltedPassword, []byte(verifierServerKey)), nil
+}
+
+func getHMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+// GetAuthMessage returns the message which is signed to join a community with a challenge
+func GetAuthMessage(community string, nonce []byte) []byte {
+	return append([]byte(authMessagePrefix+community+","), nonce...)
+}
+
+// GetProof proves the knowledge of a password for a verifier with the given salt and iterations; it also returns the server key so that the server signature can be checked
+func GetProof(password string, salt []byte, iterations int, authMessage []byte) ([]byte, []byte, error) {
+	clientKey, serverKey, err := getVerifierKeys(password, salt, iterations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storedKey := sha256.Sum256(clientKey)
+	clientSignature := getHMAC(storedKey[:], authMessage)
+
+	proof := make([]byte, len(clientKey))
+	subtle.XORBytes(proof, clientKey, clientSignature)
+
+	return proof, serverKey, nil
+}
+
+// GetServerSignature proves the knowledge of a verifier to the client
+func GetServerSignature(serverKey []byte, authMessage []byte) []byte {
+	return getHMAC(serverKey, authMessage)
+}
+
+// VerifyServerSignature checks whether the server knows the verifier of the password
+func VerifyServerSignature(serverKey []byte, authMessage []byte, signature []byte) bool {
+	return hmac.Equal(GetServerSignature(serverKey, authMessage), signature)
+}
+
+// VerifyProof checks whether a proof has been created with the password of the verifier
+func (v *Verifier) VerifyProof(authMessage []byte, proof []byte) bool {
+	if len(proof) != verifierKeySize || len(v.StoredKey) != verifierKeySize {
+		return false
+	}
+
+	clientSignature := getHMAC(v.StoredKey, authMessage)
+
+	clientKey := make([]byte, len(proof))
+	subtle.XORBytes(clientKey, proof, clientSignature)
+
+	storedKey := sha256.Sum256(clientKey)
+
+	return hmac.Equal(storedKey[:], v.StoredKey)
+}
+
+// IsValid checks whether the verifier can be used to verify proofs, i.e. if it has been sent by a client
+func (v *Verifier) IsValid() bool {
+	return v.Iterations >= minimumIterations &&
+		v.Iterations <= maximumIterations &&
+		len(v.Salt) <= maximumSaltSize &&
+		len(v.StoredKey) == verifierKeySize &&
+		len(v.ServerKey) == verifierKeySize
+}
+
+// VerifyPassword checks whether the verifier has been created from the password
+func (v *Verifier) VerifyPassword(password string) bool {
+	candidate, err := NewVerifierWithSalt(password, v.Salt, v.Iterations)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(candidate.StoredKey, v.StoredKey)
+}
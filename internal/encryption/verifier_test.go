@@ -0,0 +1,136 @@
+package encryption
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVerifyProof(t *testing.T) {
+	verifier, err := NewVerifier("mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authMessage := GetAuthMessage("mycommunity", []byte("nonce"))
+
+	tests := []struct {
+		name        string
+		password    string
+		authMessage []byte
+		proof       func(proof []byte) []byte
+		want        bool
+	}{
+		{"valid proof", "mypassword", authMessage, nil, true},
+		{"wrong password", "otherpassword", authMessage, nil, false},
+		{"proof for other community", "mypassword", GetAuthMessage("othercommunity", []byte("nonce")), nil, false},
+		{"proof for other nonce", "mypassword", GetAuthMessage("mycommunity", []byte("othernonce")), nil, false},
+		{"truncated proof", "mypassword", authMessage, func(proof []byte) []byte { return proof[1:] }, false},
+		{"missing proof", "mypassword", authMessage, func(proof []byte) []byte { return nil }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, serverKey, err := GetProof(tt.password, verifier.Salt, verifier.Iterations, tt.authMessage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.proof != nil {
+				proof = tt.proof(proof)
+			}
+
+			if got := verifier.VerifyProof(authMessage, proof); got != tt.want {
+				t.Fatalf("VerifyProof() = %v, want %v", got, tt.want)
+			}
+
+			// Clients can only check the signaler's signature if they know the password
+			if got := VerifyServerSignature(serverKey, authMessage, GetServerSignature(verifier.ServerKey, authMessage)); got != (tt.password == "mypassword") {
+				t.Fatalf("VerifyServerSignature() = %v for password %v", got, tt.password)
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	verifier, err := NewVerifier("mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewVerifier("mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(verifier.StoredKey, other.StoredKey) {
+		t.Fatal("NewVerifier() created the same stored key for two salts")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"same password", "mypassword", true},
+		{"other password", "otherpassword", false},
+		{"empty password", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifier.VerifyPassword(tt.password); got != tt.want {
+				t.Fatalf("VerifyPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifierIsValid(t *testing.T) {
+	verifier, err := NewVerifier("mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier func(v Verifier) Verifier
+		want     bool
+	}{
+		{"new verifier", func(v Verifier) Verifier { return v }, true},
+		{"no iterations", func(v Verifier) Verifier { v.Iterations = 0; return v }, false},
+		{"too many iterations", func(v Verifier) Verifier { v.Iterations = maximumIterations + 1; return v }, false},
+		{"oversized salt", func(v Verifier) Verifier { v.Salt = make([]byte, maximumSaltSize+1); return v }, false},
+		{"truncated stored key", func(v Verifier) Verifier { v.StoredKey = v.StoredKey[1:]; return v }, false},
+		{"missing server key", func(v Verifier) Verifier { v.ServerKey = nil; return v }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.verifier(*verifier)
+			if got := v.IsValid(); got != tt.want {
+				t.Fatalf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProofRejectsParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		salt       []byte
+		iterations int
+	}{
+		{"no iterations", []byte("salt"), 0},
+		{"too many iterations", []byte("salt"), maximumIterations + 1},
+		{"oversized salt", make([]byte, maximumSaltSize+1), VerifierIterations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Signalers choose the parameters, so they must not be able to make clients spend unbounded resources
+			if _, _, err := GetProof("mypassword", tt.salt, tt.iterations, GetAuthMessage("mycommunity", []byte("nonce"))); err != ErrInvalidVerifierParameters {
+				t.Fatalf("GetProof() error = %v, want %v", err, ErrInvalidVerifierParameters)
+			}
+		})
+	}
+}
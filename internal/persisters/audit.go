@@ -0,0 +1,20 @@
+package persisters
+
+import "time"
+
+const (
+	AuditActionCreateCommunity = "community.create"          // A persistent community has been created
+	AuditActionDeleteCommunity = "community.delete"          // A community has been deleted and its clients have been kicked
+	AuditActionUpdatePassword  = "community.password.update" // The password of a community has been changed
+	AuditActionKickClient      = "client.kick"               // A client has been kicked from a community
+)
+
+// AuditEvent records a management operation
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Actor     string    `json:"actor"`    // Basic auth user or OIDC subject which has done the operation
+	Action    string    `json:"action"`   // One of the AuditAction constants
+	Target    string    `json:"target"`   // Path of the resource on which the operation has been done, i.e. communities/{id}
+	SourceIP  string    `json:"sourceIP"` // IP from which the operation has been requested
+}
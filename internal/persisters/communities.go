@@ -3,16 +3,42 @@ package persisters
 import (
 	"context"
 	"errors"
+	"time"
+
+	"github.com/pojntfx/weron/internal/encryption"
 )
 
 var (
 	ErrEphemeralCommunitiesDisabled = errors.New("creation of ephemeral communites is disabled")
+	ErrCommunityFull                = errors.New("community has reached its maximum amount of clients")
+	ErrCommunityExpired             = errors.New("community has expired")
+	ErrCommunityExists              = errors.New("community already exists")
+	ErrMissingVerifier              = errors.New("community has been created before verifiers were introduced, so its password can only be rotated with a grace period once a client has joined it")
 )
 
+// Policy configures a persistent community
+type Policy struct {
+	MaxClients     int               `json:"maxClients"`          // Maximum amount of clients in the community (0 disables the limit)
+	EphemeralJoins bool              `json:"ephemeralJoins"`      // Whether clients may still join the community ephemerally once it has expired, that is only while it has clients left
+	ExpiresAt      *time.Time        `json:"expiresAt,omitempty"` // Time after which the community expires (optional)
+	Description    string            `json:"description"`         // Free-form description of the community
+	Labels         map[string]string `json:"labels"`              // Free-form labels of the community
+	OIDCRequired   bool              `json:"oidcRequired"`        // Whether clients have to present a valid OIDC ID token to join the community
+	OIDCGroups     []string          `json:"oidcGroups"`          // Groups of which clients have to be in at least one to join the community if OIDC is required (if empty, all clients with a valid ID token can join)
+}
+
+// IsExpired returns whether the community has expired at the given time
+func (p Policy) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
 type Community struct {
 	ID         string `json:"id"`
 	Clients    int    `json:"clients"`
 	Persistent bool   `json:"persistent"`
+	Owner      string `json:"owner,omitempty"` // OIDC subject which has created the community; only set if the signaler enforces community ownership
+
+	Policy
 }
 
 type CommunitiesPersister interface {
@@ -22,7 +48,18 @@ type CommunitiesPersister interface {
 		community string,
 		password string,
 		upsert bool,
-	) error
+	) (int, error) // Returns the amount of clients in the community, including the added client
+	AddClientsToVerifiedCommunity(
+		ctx context.Context,
+		community string,
+		verifier encryption.Verifier,
+		passwordHash string,
+		upsert bool,
+	) (int, error) // Like AddClientsToCommunity, but for clients which have proven the knowledge of the password for the verifier; ephemeral communities are created with the verifier and the bcrypt hash of the password; returns authn.ErrWrongPassword if the password has been changed or another client has created the community with another verifier in the meantime, and ErrCommunityExists if another client is creating it concurrently
+	GetCommunityVerifiers(
+		ctx context.Context,
+		community string,
+	) ([]encryption.Verifier, error) // Returns sql.ErrNoRows if the community doesn't exist; the current verifier comes first, communities which have been created before verifiers were introduced have none
 	RemoveClientFromCommunity(
 		ctx context.Context,
 		community string,
@@ -33,13 +70,37 @@ type CommunitiesPersister interface {
 	GetCommunities(
 		ctx context.Context,
 	) ([]Community, error)
+	GetCommunity(
+		ctx context.Context,
+		community string,
+	) (*Community, error) // Returns sql.ErrNoRows if the community doesn't exist
 	CreatePersistentCommunity(
 		ctx context.Context,
 		community string,
 		password string,
+		policy Policy,
+		owner string,
 	) (*Community, error)
+	UpdateCommunityPassword(
+		ctx context.Context,
+		community string,
+		password string,
+		grace time.Duration,
+	) error // Returns sql.ErrNoRows if the community doesn't exist; the previous password keeps working for the grace period, which requires the community to have a verifier (ErrMissingVerifier otherwise)
 	DeleteCommunity(
 		ctx context.Context,
 		community string,
 	) error
+	AddAuditEvent(
+		ctx context.Context,
+		event AuditEvent,
+	) error // The ID of the event is set by the persister
+	GetAuditEvents(
+		ctx context.Context,
+		offset int,
+		limit int,
+	) ([]AuditEvent, int, error) // Returns a page of the audit events, newest first, and the total amount of audit events
+	Ping(
+		ctx context.Context,
+	) error
 }
@@ -2,32 +2,69 @@ package memory
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
-	"errors"
 	"sync"
+	"time"
 
 	"github.com/pojntfx/go-auth-utils/pkg/authn"
+	"github.com/pojntfx/weron/internal/encryption"
 	"github.com/pojntfx/weron/internal/persisters"
-	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MaxAuditEvents = 10000 // Amount of audit events which are kept; older events are overwritten
 )
 
 var (
-	ErrUniqueConstraintViolation = errors.New("unique constraint violation")
+	ErrUniqueConstraintViolation = persisters.ErrCommunityExists
 )
 
 type Community struct {
 	*persisters.Community
-	password string
+	verifier                  encryption.Verifier
+	previousVerifier          *encryption.Verifier
+	previousVerifierExpiresAt time.Time
+}
+
+// getVerifiers returns the current verifier and the previous one if its grace period is not over yet
+func (c *Community) getVerifiers() []encryption.Verifier {
+	verifiers := []encryption.Verifier{c.verifier}
+	if c.previousVerifier != nil && time.Now().Before(c.previousVerifierExpiresAt) {
+		verifiers = append(verifiers, *c.previousVerifier)
+	}
+
+	return verifiers
+}
+
+// join adds a client to the community if its policy allows it
+func (c *Community) join() (int, error) {
+	// Expired communities stay persistent, but they can't be joined again once the last client has left
+	if c.IsExpired(time.Now()) && (!c.EphemeralJoins || c.Clients <= 0) {
+		return 0, persisters.ErrCommunityExpired
+	}
+
+	if c.MaxClients > 0 && c.Clients >= c.MaxClients {
+		return 0, persisters.ErrCommunityFull
+	}
+
+	c.Clients += 1
+
+	return c.Clients, nil
 }
 
 type CommunitiesPersister struct {
 	lock        sync.Mutex
 	communities []*Community
+
+	auditEvents      []persisters.AuditEvent // Ring buffer; the event with ID i is at index (i - 1) % MaxAuditEvents
+	lastAuditEventID int64
 }
 
 func NewCommunitiesPersister() *CommunitiesPersister {
 	return &CommunitiesPersister{
 		communities: []*Community{},
+		auditEvents: []persisters.AuditEvent{},
 	}
 }
 
@@ -40,15 +77,68 @@ func (p *CommunitiesPersister) AddClientsToCommunity(
 	community string,
 	password string,
 	upsert bool,
-) error {
+) (int, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+	var c *Community
+	for _, candidate := range p.communities {
+		if candidate.ID == community {
+			c = candidate
+
+			break
+		}
 	}
 
+	if c == nil {
+		if !upsert {
+			return 0, persisters.ErrEphemeralCommunitiesDisabled
+		}
+
+		verifier, err := encryption.NewVerifier(password)
+		if err != nil {
+			return 0, err
+		}
+
+		p.communities = append(p.communities, &Community{
+			verifier: *verifier,
+			Community: &persisters.Community{
+				ID:         community,
+				Clients:    1,
+				Persistent: false,
+			},
+		})
+
+		return 1, nil
+	}
+
+	// The previous password keeps working until its grace period is over
+	valid := false
+	for _, verifier := range c.getVerifiers() {
+		if verifier.VerifyPassword(password) {
+			valid = true
+
+			break
+		}
+	}
+
+	if !valid {
+		return 0, authn.ErrWrongPassword
+	}
+
+	return c.join()
+}
+
+func (p *CommunitiesPersister) AddClientsToVerifiedCommunity(
+	ctx context.Context,
+	community string,
+	verifier encryption.Verifier,
+	passwordHash string,
+	upsert bool,
+) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	var c *Community
 	for _, candidate := range p.communities {
 		if candidate.ID == community {
@@ -59,8 +149,12 @@ func (p *CommunitiesPersister) AddClientsToCommunity(
 	}
 
 	if c == nil {
+		if !upsert {
+			return 0, persisters.ErrEphemeralCommunitiesDisabled
+		}
+
 		p.communities = append(p.communities, &Community{
-			password: string(hashedPassword),
+			verifier: verifier,
 			Community: &persisters.Community{
 				ID:         community,
 				Clients:    1,
@@ -68,16 +162,40 @@ func (p *CommunitiesPersister) AddClientsToCommunity(
 			},
 		})
 
-		return nil
+		return 1, nil
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(c.password), []byte(password)) != nil {
-		return authn.ErrWrongPassword
+	// The password could have been changed since the client has proven its knowledge, or another client could have created the community in the meantime
+	valid := false
+	for _, candidate := range c.getVerifiers() {
+		if subtle.ConstantTimeCompare(candidate.StoredKey, verifier.StoredKey) == 1 {
+			valid = true
+
+			break
+		}
 	}
 
-	c.Clients += 1
+	if !valid {
+		return 0, authn.ErrWrongPassword
+	}
 
-	return nil
+	return c.join()
+}
+
+func (p *CommunitiesPersister) GetCommunityVerifiers(
+	ctx context.Context,
+	community string,
+) ([]encryption.Verifier, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, candidate := range p.communities {
+		if candidate.ID == community {
+			return candidate.getVerifiers(), nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
 }
 
 func (p *CommunitiesPersister) RemoveClientFromCommunity(
@@ -157,21 +275,47 @@ func (p *CommunitiesPersister) GetCommunities(
 			ID:         community.ID,
 			Clients:    community.Clients,
 			Persistent: community.Persistent,
+			Owner:      community.Owner,
+			Policy:     community.Policy,
 		})
 	}
 
 	return cc, nil
 }
 
+func (p *CommunitiesPersister) GetCommunity(
+	ctx context.Context,
+	community string,
+) (*persisters.Community, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, candidate := range p.communities {
+		if candidate.ID == community {
+			return &persisters.Community{
+				ID:         candidate.ID,
+				Clients:    candidate.Clients,
+				Persistent: candidate.Persistent,
+				Owner:      candidate.Owner,
+				Policy:     candidate.Policy,
+			}, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
 func (p *CommunitiesPersister) CreatePersistentCommunity(
 	ctx context.Context,
 	community string,
 	password string,
+	policy persisters.Policy,
+	owner string,
 ) (*persisters.Community, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	verifier, err := encryption.NewVerifier(password)
 	if err != nil {
 		return nil, err
 	}
@@ -190,11 +334,13 @@ func (p *CommunitiesPersister) CreatePersistentCommunity(
 	}
 
 	c = &Community{
-		password: string(hashedPassword),
+		verifier: *verifier,
 		Community: &persisters.Community{
 			ID:         community,
 			Clients:    0,
 			Persistent: true,
+			Owner:      owner,
+			Policy:     policy,
 		},
 	}
 
@@ -204,11 +350,60 @@ func (p *CommunitiesPersister) CreatePersistentCommunity(
 		ID:         c.ID,
 		Clients:    c.Clients,
 		Persistent: c.Persistent,
+		Owner:      c.Owner,
+		Policy:     c.Policy,
 	}
 
 	return cc, nil
 }
 
+func (p *CommunitiesPersister) UpdateCommunityPassword(
+	ctx context.Context,
+	community string,
+	password string,
+	grace time.Duration,
+) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	verifier, err := encryption.NewVerifier(password)
+	if err != nil {
+		return err
+	}
+
+	var c *Community
+	for _, candidate := range p.communities {
+		if candidate.ID == community {
+			c = candidate
+
+			break
+		}
+	}
+
+	if c == nil {
+		return sql.ErrNoRows
+	}
+
+	if grace > 0 {
+		// Clients which support challenges can only prove the knowledge of the previous password with its verifier
+		if !c.verifier.IsValid() {
+			return persisters.ErrMissingVerifier
+		}
+
+		previousVerifier := c.verifier
+
+		c.previousVerifier = &previousVerifier
+		c.previousVerifierExpiresAt = time.Now().Add(grace)
+	} else {
+		c.previousVerifier = nil
+		c.previousVerifierExpiresAt = time.Time{}
+	}
+
+	c.verifier = *verifier
+
+	return nil
+}
+
 func (p *CommunitiesPersister) DeleteCommunity(
 	ctx context.Context,
 	community string,
@@ -236,3 +431,46 @@ func (p *CommunitiesPersister) DeleteCommunity(
 
 	return nil
 }
+
+func (p *CommunitiesPersister) AddAuditEvent(
+	ctx context.Context,
+	event persisters.AuditEvent,
+) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.lastAuditEventID++
+	event.ID = p.lastAuditEventID
+
+	if len(p.auditEvents) < MaxAuditEvents {
+		p.auditEvents = append(p.auditEvents, event)
+
+		return nil
+	}
+
+	p.auditEvents[(event.ID-1)%MaxAuditEvents] = event
+
+	return nil
+}
+
+func (p *CommunitiesPersister) GetAuditEvents(
+	ctx context.Context,
+	offset int,
+	limit int,
+) ([]persisters.AuditEvent, int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	events := []persisters.AuditEvent{}
+	for i := offset; i < offset+limit && i < len(p.auditEvents); i++ {
+		events = append(events, p.auditEvents[(p.lastAuditEventID-int64(i)-1)%MaxAuditEvents])
+	}
+
+	return events, len(p.auditEvents), nil
+}
+
+func (p *CommunitiesPersister) Ping(
+	ctx context.Context,
+) error {
+	return nil
+}
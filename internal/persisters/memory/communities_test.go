@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pojntfx/weron/internal/encryption"
+	"github.com/pojntfx/weron/internal/persisters"
+)
+
+func TestJoin(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		clients int
+		policy  persisters.Policy
+		want    error
+	}{
+		{"no policy", 0, persisters.Policy{}, nil},
+		{"below max clients", 1, persisters.Policy{MaxClients: 2}, nil},
+		{"at max clients", 2, persisters.Policy{MaxClients: 2}, persisters.ErrCommunityFull},
+		{"not expired yet", 0, persisters.Policy{ExpiresAt: &later}, nil},
+		{"expired", 1, persisters.Policy{ExpiresAt: &expired}, persisters.ErrCommunityExpired},
+		{"expired with ephemeral joins", 1, persisters.Policy{ExpiresAt: &expired, EphemeralJoins: true}, nil},
+		{"expired and empty with ephemeral joins", 0, persisters.Policy{ExpiresAt: &expired, EphemeralJoins: true}, persisters.ErrCommunityExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Community{
+				Community: &persisters.Community{
+					ID:         "mycommunity",
+					Clients:    tt.clients,
+					Persistent: true,
+					Policy:     tt.policy,
+				},
+			}
+
+			clients, err := c.join()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("join() error = %v, want %v", err, tt.want)
+			}
+
+			if err == nil && (clients != tt.clients+1 || c.Clients != tt.clients+1) {
+				t.Fatalf("join() = %v, want %v clients", clients, tt.clients+1)
+			}
+
+			if !c.Persistent {
+				t.Fatal("join() made the community ephemeral")
+			}
+		})
+	}
+}
+
+func TestUpdateCommunityPassword(t *testing.T) {
+	oldVerifier, err := encryption.NewVerifier("oldpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		withVerifier  bool // Whether the community has a verifier
+		grace         time.Duration
+		want          error
+		wantVerifiers int
+		wantPrevious  bool // Whether the previous password keeps working
+	}{
+		{"grace period", true, time.Hour, nil, 2, true},
+		{"no grace period", true, 0, nil, 1, false},
+		{"grace period without verifier", false, time.Hour, persisters.ErrMissingVerifier, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Community{
+				Community: &persisters.Community{
+					ID:         "mycommunity",
+					Persistent: true,
+				},
+			}
+
+			if tt.withVerifier {
+				c.verifier = *oldVerifier
+			}
+
+			p := NewCommunitiesPersister()
+			p.communities = append(p.communities, c)
+
+			if err := p.UpdateCommunityPassword(context.Background(), "mycommunity", "newpassword", tt.grace); !errors.Is(err, tt.want) {
+				t.Fatalf("UpdateCommunityPassword() error = %v, want %v", err, tt.want)
+			}
+
+			verifiers, err := p.GetCommunityVerifiers(context.Background(), "mycommunity")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(verifiers) != tt.wantVerifiers {
+				t.Fatalf("GetCommunityVerifiers() = %v verifiers, want %v", len(verifiers), tt.wantVerifiers)
+			}
+
+			_, err = p.AddClientsToCommunity(context.Background(), "mycommunity", "oldpassword", false)
+			if got := err == nil; got != tt.wantPrevious {
+				t.Fatalf("AddClientsToCommunity() with previous password error = %v, want success %v", err, tt.wantPrevious)
+			}
+		})
+	}
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		added     int
+		offset    int
+		limit     int
+		wantIDs   []int64
+		wantTotal int
+	}{
+		{"no events", 0, 0, 10, []int64{}, 0},
+		{"newest first", 5, 0, 3, []int64{5, 4, 3}, 5},
+		{"offset", 5, 3, 10, []int64{2, 1}, 5},
+		{"offset past the end", 5, 10, 10, []int64{}, 5},
+		{"full buffer", MaxAuditEvents, 0, 2, []int64{MaxAuditEvents, MaxAuditEvents - 1}, MaxAuditEvents},
+		{"wrapped buffer", MaxAuditEvents + 3, 0, 4, []int64{MaxAuditEvents + 3, MaxAuditEvents + 2, MaxAuditEvents + 1, MaxAuditEvents}, MaxAuditEvents},
+		{"oldest events of wrapped buffer", MaxAuditEvents + 3, MaxAuditEvents - 2, 10, []int64{5, 4}, MaxAuditEvents},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewCommunitiesPersister()
+			for i := 0; i < tt.added; i++ {
+				if err := p.AddAuditEvent(context.Background(), persisters.AuditEvent{Action: persisters.AuditActionCreateCommunity}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			events, total, err := p.GetAuditEvents(context.Background(), tt.offset, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if total != tt.wantTotal {
+				t.Fatalf("GetAuditEvents() total = %v, want %v", total, tt.wantTotal)
+			}
+
+			if len(events) != len(tt.wantIDs) {
+				t.Fatalf("GetAuditEvents() = %v events, want %v", len(events), len(tt.wantIDs))
+			}
+
+			for i, id := range tt.wantIDs {
+				if events[i].ID != id {
+					t.Fatalf("GetAuditEvents() event %v ID = %v, want %v", i, events[i].ID, id)
+				}
+			}
+		})
+	}
+}
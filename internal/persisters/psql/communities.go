@@ -2,22 +2,32 @@ package psql
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
+	"errors"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pojntfx/go-auth-utils/pkg/authn"
 	"github.com/pojntfx/weron/internal/db/psql/migrations/communities"
 	models "github.com/pojntfx/weron/internal/db/psql/models/communities"
+	"github.com/pojntfx/weron/internal/encryption"
 	"github.com/pojntfx/weron/internal/persisters"
 	migrate "github.com/rubenv/sql-migrate"
+	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"github.com/volatiletech/sqlboiler/v4/types"
 	"golang.org/x/crypto/bcrypt"
 )
 
 //go:generate sqlboiler psql -o ../../../internal/db/psql/models/communities -c ../../../configs/sqlboiler/communities.yaml
 //go:generate go-bindata -pkg communities -o ../../../internal/db/psql/migrations/communities/migrations.go ../../../db/psql/migrations/communities
 
+const (
+	uniqueViolation = pq.ErrorCode("23505")
+)
+
 type CommunitiesPersister struct {
 	db *sql.DB
 }
@@ -55,21 +65,12 @@ func (p *CommunitiesPersister) AddClientsToCommunity(
 	community string,
 	password string,
 	upsert bool,
-) error {
+) (int, error) {
 	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 	})
 	if err != nil {
-		return err
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		if err := tx.Rollback(); err != nil {
-			return err
-		}
-
-		return err
+		return 0, err
 	}
 
 	c, err := models.FindCommunity(ctx, tx, community)
@@ -77,56 +78,155 @@ func (p *CommunitiesPersister) AddClientsToCommunity(
 		if err == sql.ErrNoRows {
 			if !upsert {
 				if err := tx.Rollback(); err != nil {
-					return err
+					return 0, err
 				}
 
-				return persisters.ErrEphemeralCommunitiesDisabled
+				return 0, persisters.ErrEphemeralCommunitiesDisabled
 			}
 
-			c = &models.Community{
-				ID:         community,
-				Password:   string(hashedPassword),
-				Clients:    1,
-				Persistent: false,
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				if err := tx.Rollback(); err != nil {
+					return 0, err
+				}
+
+				return 0, err
 			}
 
-			if err := c.Insert(ctx, tx, boil.Infer()); err != nil {
+			verifier, err := encryption.NewVerifier(password)
+			if err != nil {
 				if err := tx.Rollback(); err != nil {
-					return err
+					return 0, err
 				}
 
-				return err
+				return 0, err
 			}
 
-			return tx.Commit()
+			return createEphemeralCommunity(ctx, tx, community, *verifier, string(hashedPassword))
 		} else {
 			if err := tx.Rollback(); err != nil {
-				return err
+				return 0, err
 			}
 
-			return err
+			return 0, err
 		}
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(c.Password), []byte(password)) != nil {
+	valid, upgrade, err := verifyPassword(c, password)
+	if err != nil {
 		if err := tx.Rollback(); err != nil {
-			return err
+			return 0, err
 		}
 
-		return authn.ErrWrongPassword
+		return 0, err
 	}
 
-	c.Clients += 1
+	if !valid {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
 
-	if _, err := c.Update(ctx, tx, boil.Infer()); err != nil {
+		return 0, authn.ErrWrongPassword
+	}
+
+	// Communities which have been created before verifiers were introduced get one on the first join so that their clients can use challenges
+	if upgrade {
+		verifier, err := encryption.NewVerifier(password)
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				return 0, err
+			}
+
+			return 0, err
+		}
+
+		if err := c.Verifier.Marshal(verifier); err != nil {
+			if err := tx.Rollback(); err != nil {
+				return 0, err
+			}
+
+			return 0, err
+		}
+	}
+
+	return joinCommunity(ctx, tx, c)
+}
+
+func (p *CommunitiesPersister) AddClientsToVerifiedCommunity(
+	ctx context.Context,
+	community string,
+	verifier encryption.Verifier,
+	passwordHash string,
+	upsert bool,
+) (int, error) {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	c, err := models.FindCommunity(ctx, tx, community)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			if !upsert {
+				if err := tx.Rollback(); err != nil {
+					return 0, err
+				}
+
+				return 0, persisters.ErrEphemeralCommunitiesDisabled
+			}
+
+			return createEphemeralCommunity(ctx, tx, community, verifier, passwordHash)
+		} else {
+			if err := tx.Rollback(); err != nil {
+				return 0, err
+			}
+
+			return 0, err
+		}
+	}
+
+	verifiers, err := getVerifiers(c)
+	if err != nil {
 		if err := tx.Rollback(); err != nil {
-			return err
+			return 0, err
 		}
 
-		return err
+		return 0, err
 	}
 
-	return tx.Commit()
+	// The password could have been changed since the client has proven its knowledge, or another client could have created the community in the meantime
+	valid := false
+	for _, candidate := range verifiers {
+		if subtle.ConstantTimeCompare(candidate.StoredKey, verifier.StoredKey) == 1 {
+			valid = true
+
+			break
+		}
+	}
+
+	if !valid {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+
+		return 0, authn.ErrWrongPassword
+	}
+
+	return joinCommunity(ctx, tx, c)
+}
+
+func (p *CommunitiesPersister) GetCommunityVerifiers(
+	ctx context.Context,
+	community string,
+) ([]encryption.Verifier, error) {
+	c, err := models.FindCommunity(ctx, p.db, community)
+	if err != nil {
+		return nil, err
+	}
+
+	return getVerifiers(c)
 }
 
 func (p *CommunitiesPersister) RemoveClientFromCommunity(
@@ -222,34 +322,85 @@ func (p *CommunitiesPersister) GetCommunities(
 
 	cc := []persisters.Community{}
 	for _, community := range c {
+		policy, err := getPolicy(community)
+		if err != nil {
+			return nil, err
+		}
+
 		cc = append(cc, persisters.Community{
 			ID:         community.ID,
 			Clients:    community.Clients,
 			Persistent: community.Persistent,
+			Owner:      community.Owner,
+			Policy:     policy,
 		})
 	}
 
 	return cc, nil
 }
 
+func (p *CommunitiesPersister) GetCommunity(
+	ctx context.Context,
+	community string,
+) (*persisters.Community, error) {
+	c, err := models.FindCommunity(ctx, p.db, community)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := getPolicy(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &persisters.Community{
+		ID:         c.ID,
+		Clients:    c.Clients,
+		Persistent: c.Persistent,
+		Owner:      c.Owner,
+		Policy:     policy,
+	}, nil
+}
+
 func (p *CommunitiesPersister) CreatePersistentCommunity(
 	ctx context.Context,
 	community string,
 	password string,
+	policy persisters.Policy,
+	owner string,
 ) (*persisters.Community, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 
+	verifier, err := encryption.NewVerifier(password)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &models.Community{
 		ID:         community,
 		Password:   string(hashedPassword),
 		Clients:    0,
 		Persistent: true,
+		Owner:      owner,
+	}
+
+	if err := c.Verifier.Marshal(verifier); err != nil {
+		return nil, err
+	}
+
+	if err := setPolicy(c, policy); err != nil {
+		return nil, err
 	}
 
 	if err := c.Insert(ctx, p.db, boil.Infer()); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, persisters.ErrCommunityExists
+		}
+
 		return nil, err
 	}
 
@@ -257,11 +408,64 @@ func (p *CommunitiesPersister) CreatePersistentCommunity(
 		ID:         c.ID,
 		Clients:    c.Clients,
 		Persistent: c.Persistent,
+		Owner:      c.Owner,
+		Policy:     policy,
 	}
 
 	return cc, nil
 }
 
+func (p *CommunitiesPersister) UpdateCommunityPassword(
+	ctx context.Context,
+	community string,
+	password string,
+	grace time.Duration,
+) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := encryption.NewVerifier(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return err
+	}
+
+	c, err := models.FindCommunity(ctx, tx, community)
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+
+		return err
+	}
+
+	if err := rotatePassword(c, string(hashedPassword), verifier, grace); err != nil {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+
+		return err
+	}
+
+	if _, err := c.Update(ctx, tx, boil.Infer()); err != nil {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (p *CommunitiesPersister) DeleteCommunity(
 	ctx context.Context,
 	community string,
@@ -279,3 +483,260 @@ func (p *CommunitiesPersister) DeleteCommunity(
 
 	return nil
 }
+
+func (p *CommunitiesPersister) AddAuditEvent(
+	ctx context.Context,
+	event persisters.AuditEvent,
+) error {
+	e := &models.AuditEvent{
+		Timestamp: event.Timestamp,
+		Actor:     event.Actor,
+		Action:    event.Action,
+		Target:    event.Target,
+		SourceIP:  event.SourceIP,
+	}
+
+	return e.Insert(ctx, p.db, boil.Infer())
+}
+
+func (p *CommunitiesPersister) GetAuditEvents(
+	ctx context.Context,
+	offset int,
+	limit int,
+) ([]persisters.AuditEvent, int, error) {
+	total, err := models.AuditEvents().Count(ctx, p.db)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	e, err := models.AuditEvents(
+		qm.OrderBy(models.AuditEventColumns.ID+" desc"),
+		qm.Offset(offset),
+		qm.Limit(limit),
+	).All(ctx, p.db)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []persisters.AuditEvent{}
+	for _, event := range e {
+		events = append(events, persisters.AuditEvent{
+			ID:        event.ID,
+			Timestamp: event.Timestamp,
+			Actor:     event.Actor,
+			Action:    event.Action,
+			Target:    event.Target,
+			SourceIP:  event.SourceIP,
+		})
+	}
+
+	return events, int(total), nil
+}
+
+func (p *CommunitiesPersister) Ping(
+	ctx context.Context,
+) error {
+	return p.db.PingContext(ctx)
+}
+
+func getPolicy(c *models.Community) (persisters.Policy, error) {
+	policy := persisters.Policy{
+		MaxClients:     c.MaxClients,
+		EphemeralJoins: c.EphemeralJoins,
+		Description:    c.Description,
+		Labels:         map[string]string{},
+		OIDCRequired:   c.OidcRequired,
+		OIDCGroups:     []string{},
+	}
+
+	if c.ExpiresAt.Valid {
+		expiresAt := c.ExpiresAt.Time
+		policy.ExpiresAt = &expiresAt
+	}
+
+	if len(c.Labels) > 0 {
+		if err := c.Labels.Unmarshal(&policy.Labels); err != nil {
+			return persisters.Policy{}, err
+		}
+	}
+
+	if len(c.OidcGroups) > 0 {
+		if err := c.OidcGroups.Unmarshal(&policy.OIDCGroups); err != nil {
+			return persisters.Policy{}, err
+		}
+	}
+
+	return policy, nil
+}
+
+func setPolicy(c *models.Community, policy persisters.Policy) error {
+	c.MaxClients = policy.MaxClients
+	c.EphemeralJoins = policy.EphemeralJoins
+	c.ExpiresAt = null.TimeFromPtr(policy.ExpiresAt)
+	c.Description = policy.Description
+	c.OidcRequired = policy.OIDCRequired
+
+	labels := policy.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	c.Labels = types.JSON{}
+
+	if err := c.Labels.Marshal(labels); err != nil {
+		return err
+	}
+
+	groups := policy.OIDCGroups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	c.OidcGroups = types.JSON{}
+
+	return c.OidcGroups.Marshal(groups)
+}
+
+// createEphemeralCommunity creates an ephemeral community with the joining client in it; the bcrypt hash of the password is stored alongside the verifier so that signalers which predate verifiers and share the database can still join clients to it
+func createEphemeralCommunity(ctx context.Context, tx *sql.Tx, community string, verifier encryption.Verifier, passwordHash string) (int, error) {
+	c := &models.Community{
+		ID:         community,
+		Password:   passwordHash,
+		Clients:    1,
+		Persistent: false,
+	}
+
+	if err := c.Verifier.Marshal(verifier); err != nil {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+
+		return 0, err
+	}
+
+	if err := c.Insert(ctx, tx, boil.Infer()); err != nil {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+
+		// Another client has created the community concurrently
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return 0, persisters.ErrCommunityExists
+		}
+
+		return 0, err
+	}
+
+	return 1, tx.Commit()
+}
+
+// joinCommunity adds a client to a community if its policy allows it
+func joinCommunity(ctx context.Context, tx *sql.Tx, c *models.Community) (int, error) {
+	// Expired communities stay persistent, but they can't be joined again once the last client has left
+	if c.ExpiresAt.Valid && !time.Now().Before(c.ExpiresAt.Time) && (!c.EphemeralJoins || c.Clients <= 0) {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+
+		return 0, persisters.ErrCommunityExpired
+	}
+
+	if c.MaxClients > 0 && c.Clients >= c.MaxClients {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+
+		return 0, persisters.ErrCommunityFull
+	}
+
+	c.Clients += 1
+
+	if _, err := c.Update(ctx, tx, boil.Infer()); err != nil {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+
+		return 0, err
+	}
+
+	return c.Clients, tx.Commit()
+}
+
+// rotatePassword sets the new password of a community and keeps the current one for the grace period
+func rotatePassword(c *models.Community, hashedPassword string, verifier *encryption.Verifier, grace time.Duration) error {
+	if grace > 0 {
+		// Clients which support challenges can only prove the knowledge of the previous password with its verifier
+		if !c.Verifier.Valid {
+			return persisters.ErrMissingVerifier
+		}
+
+		c.PreviousPassword = null.StringFrom(c.Password)
+		c.PreviousVerifier = c.Verifier
+		c.PreviousPasswordExpiresAt = null.TimeFrom(time.Now().Add(grace))
+	} else {
+		c.PreviousPassword = null.String{}
+		c.PreviousVerifier = null.JSON{}
+		c.PreviousPasswordExpiresAt = null.Time{}
+	}
+
+	c.Password = hashedPassword
+
+	return c.Verifier.Marshal(verifier)
+}
+
+// getVerifiers returns the current verifier and the previous one if its grace period is not over yet
+func getVerifiers(c *models.Community) ([]encryption.Verifier, error) {
+	verifiers := []encryption.Verifier{}
+	if c.Verifier.Valid {
+		verifier := encryption.Verifier{}
+		if err := c.Verifier.Unmarshal(&verifier); err != nil {
+			return nil, err
+		}
+
+		verifiers = append(verifiers, verifier)
+	}
+
+	if c.PreviousVerifier.Valid && c.PreviousPasswordExpiresAt.Valid && time.Now().Before(c.PreviousPasswordExpiresAt.Time) {
+		verifier := encryption.Verifier{}
+		if err := c.PreviousVerifier.Unmarshal(&verifier); err != nil {
+			return nil, err
+		}
+
+		verifiers = append(verifiers, verifier)
+	}
+
+	return verifiers, nil
+}
+
+// verifyPassword checks a password against the verifiers of a community, falling back to the password hashes for communities which have been created before verifiers were introduced; it also returns whether the community should get a verifier
+func verifyPassword(c *models.Community, password string) (bool, bool, error) {
+	if c.Verifier.Valid {
+		verifier := encryption.Verifier{}
+		if err := c.Verifier.Unmarshal(&verifier); err != nil {
+			return false, false, err
+		}
+
+		if verifier.VerifyPassword(password) {
+			return true, false, nil
+		}
+	} else if bcrypt.CompareHashAndPassword([]byte(c.Password), []byte(password)) == nil {
+		return true, true, nil
+	}
+
+	// The previous password keeps working until its grace period is over
+	if !c.PreviousPasswordExpiresAt.Valid || !time.Now().Before(c.PreviousPasswordExpiresAt.Time) {
+		return false, false, nil
+	}
+
+	if c.PreviousVerifier.Valid {
+		verifier := encryption.Verifier{}
+		if err := c.PreviousVerifier.Unmarshal(&verifier); err != nil {
+			return false, false, err
+		}
+
+		return verifier.VerifyPassword(password), false, nil
+	}
+
+	return c.PreviousPassword.Valid && bcrypt.CompareHashAndPassword([]byte(c.PreviousPassword.String), []byte(password)) == nil, false, nil
+}
@@ -0,0 +1,86 @@
+package psql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	models "github.com/pojntfx/weron/internal/db/psql/models/communities"
+	"github.com/pojntfx/weron/internal/encryption"
+	"github.com/pojntfx/weron/internal/persisters"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRotatePassword(t *testing.T) {
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("oldpassword"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte("newpassword"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldVerifier, err := encryption.NewVerifier("oldpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newVerifier, err := encryption.NewVerifier("newpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		withVerifier  bool // Whether the community has been created after verifiers were introduced
+		grace         time.Duration
+		want          error
+		wantVerifiers int
+		wantPrevious  bool // Whether the previous password keeps working
+	}{
+		{"grace period", true, time.Hour, nil, 2, true},
+		{"no grace period", true, 0, nil, 1, false},
+		{"grace period without verifier", false, time.Hour, persisters.ErrMissingVerifier, 0, true},
+		{"no grace period without verifier", false, 0, nil, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &models.Community{
+				ID:       "mycommunity",
+				Password: string(oldHash),
+			}
+
+			if tt.withVerifier {
+				if err := c.Verifier.Marshal(oldVerifier); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := rotatePassword(c, string(newHash), newVerifier, tt.grace); !errors.Is(err, tt.want) {
+				t.Fatalf("rotatePassword() error = %v, want %v", err, tt.want)
+			}
+
+			verifiers, err := getVerifiers(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(verifiers) != tt.wantVerifiers {
+				t.Fatalf("getVerifiers() = %v verifiers, want %v", len(verifiers), tt.wantVerifiers)
+			}
+
+			// Clients which use the previous password must be able to prove it with a verifier, otherwise the password must not have been changed
+			valid, _, err := verifyPassword(c, "oldpassword")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if valid != tt.wantPrevious {
+				t.Fatalf("verifyPassword() for previous password = %v, want %v", valid, tt.wantPrevious)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/pojntfx/weron/internal/persisters"
+)
+
+const (
+	PathPrefix      = "/api/v1"                    // Prefix of all paths of the management API
+	PathCommunities = PathPrefix + "/communities"  // Path of the communities; single communities are at PathCommunities/{id}
+	PathPassword    = "password"                   // Path of the password of a community, relative to PathCommunities/{id}
+	PathClients     = "clients"                    // Path of the clients of a community, relative to PathCommunities/{id}; single clients are at PathClients/{client}
+	PathOpenAPI     = PathPrefix + "/openapi.json" // Path of the OpenAPI document of the management API
+	PathAudit       = PathPrefix + "/audit"        // Path of the audit log of management operations
+
+	QueryLimit      = "limit"      // Maximum amount of communities or audit events to list
+	QueryOffset     = "offset"     // Amount of communities or audit events to skip when listing
+	QueryPersistent = "persistent" // Only list persistent ("true") or ephemeral ("false") communities
+	QueryLabel      = "label"      // Only list communities with a label (in format key=value); can be repeated to require multiple labels
+
+	DefaultLimit = 100  // Default amount of communities or audit events per page
+	MaxLimit     = 1000 // Maximum amount of communities or audit events per page
+)
+
+// CreateCommunity is the request body to create a persistent community
+type CreateCommunity struct {
+	ID       string `json:"id"`       // ID of the community
+	Password string `json:"password"` // Password for the community
+
+	persisters.Policy
+}
+
+// UpdatePassword is the request body to change the password of a community
+type UpdatePassword struct {
+	Password    string `json:"password"`    // New password for the community
+	GracePeriod int64  `json:"gracePeriod"` // Seconds during which the previous password keeps working (0 revokes it immediately)
+}
+
+// Communities is a page of communities, sorted by ID
+type Communities struct {
+	Communities []persisters.Community `json:"communities"` // Communities on this page
+	Total       int                    `json:"total"`       // Amount of communities which match the filters
+	Offset      int                    `json:"offset"`      // Amount of communities which have been skipped
+	Limit       int                    `json:"limit"`       // Maximum amount of communities on this page
+}
+
+// Client is a client which is connected to a signaler
+type Client struct {
+	ID             string    `json:"id"`             // ID of the client's connection
+	RemoteIP       string    `json:"remoteIP"`       // IP from which the client has connected
+	UserAgent      string    `json:"userAgent"`      // User agent with which the client has connected
+	ConnectedSince time.Time `json:"connectedSince"` // Time at which the client has connected
+	BytesReceived  int64     `json:"bytesReceived"`  // Amount of bytes which the signaler has received from the client
+	BytesSent      int64     `json:"bytesSent"`      // Amount of bytes which the signaler has relayed to the client
+	Replica        string    `json:"replica"`        // Signaler to which the client is connected
+}
+
+// Clients are the clients of a community which are connected to the signalers which share the broker, sorted by the time at which they have connected
+type Clients struct {
+	Clients  []Client `json:"clients"`  // Connected clients
+	Replicas []string `json:"replicas"` // Signalers which have listed their clients
+	Complete bool     `json:"complete"` // Whether all signalers which share the broker have listed their clients in time; if not, clients of the missing signalers are not listed
+}
+
+// AuditEvents is a page of the audit log, newest first
+type AuditEvents struct {
+	Events []persisters.AuditEvent `json:"events"` // Audit events on this page
+	Total  int                     `json:"total"`  // Amount of audit events which are kept by the signaler
+	Offset int                     `json:"offset"` // Amount of audit events which have been skipped
+	Limit  int                     `json:"limit"`  // Maximum amount of audit events on this page
+}
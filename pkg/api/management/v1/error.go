@@ -0,0 +1,18 @@
+package v1
+
+// Error is the response body of all failed requests to the management API
+type Error struct {
+	Status  int    `json:"status"`  // HTTP status code
+	Message string `json:"message"` // Human-readable description of the error
+}
+
+func NewError(status int, message string) *Error {
+	return &Error{
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
@@ -0,0 +1,10 @@
+package v1
+
+import (
+	_ "embed"
+)
+
+// OpenAPI is the OpenAPI document of the management API
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	CodecJSON = "json" // JSON encoding; supported by all peers and readable for debugging
+	CodecCBOR = "cbor" // Compact binary encoding (RFC 8949) with integer keys as defined by the `cbor` struct tags
+)
+
+var (
+	ErrUnsupportedCodec   = errors.New("unsupported codec")            // The codec is not known
+	ErrUnknownMessageType = errors.New("unknown message type")         // The frame's type discriminator is not known
+	ErrEmptyFrame         = errors.New("could not decode empty frame") // The frame doesn't contain a message
+)
+
+// Encode encodes a message into a frame using a codec
+func Encode(codec string, message interface{}) ([]byte, error) {
+	switch codec {
+	case CodecJSON:
+		return json.Marshal(message)
+	case CodecCBOR:
+		return cbor.Marshal(message)
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+}
+
+// Decode decodes a frame which has been encoded with any codec into a typed message (i.e. *Greeting) and returns the codec it has been encoded with
+func Decode(frame []byte) (interface{}, string, error) {
+	if len(frame) <= 0 {
+		return nil, "", ErrEmptyFrame
+	}
+
+	// JSON messages are objects, while CBOR messages are maps, which never start with '{' or whitespace
+	codec := CodecCBOR
+	unmarshal := cbor.Unmarshal
+	if trimmed := bytes.TrimLeft(frame, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		codec = CodecJSON
+		unmarshal = json.Unmarshal
+	}
+
+	var message Message
+	if err := unmarshal(frame, &message); err != nil {
+		return nil, codec, err
+	}
+
+	var typed interface{}
+	switch message.Type {
+	case TypeGreeting:
+		typed = &Greeting{}
+	case TypeKick:
+		typed = &Kick{}
+	case TypeBackoff:
+		typed = &Backoff{}
+	case TypeClaimed:
+		typed = &Claimed{}
+	case TypeAcknowledgement:
+		typed = &Acknowledgement{}
+	default:
+		return nil, codec, ErrUnknownMessageType
+	}
+
+	if err := unmarshal(frame, typed); err != nil {
+		return nil, codec, err
+	}
+
+	return typed, codec, nil
+}
@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	claimed := NewClaimed("alice")
+	claimed.PublicKey = "cHVibGljS2V5"
+	claimed.Signature = "c2lnbmF0dXJl"
+
+	messages := []struct {
+		name    string
+		message interface{}
+	}{
+		{"greeting", NewGreeting(map[string]struct{}{"alice": {}, "bob": {}}, 42, []string{CodecCBOR, CodecJSON})},
+		{"greeting without codecs", NewGreeting(map[string]struct{}{"alice": {}}, 42, nil)},
+		{"kick", NewKick("alice")},
+		{"backoff", NewBackoff()},
+		{"claimed", NewClaimed("alice")},
+		{"signed claimed", claimed},
+		{"acknowledgement", NewAcknowledgement()},
+	}
+
+	for _, codec := range []string{CodecJSON, CodecCBOR} {
+		for _, tt := range messages {
+			t.Run(codec+"/"+tt.name, func(t *testing.T) {
+				frame, err := Encode(codec, tt.message)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, gotCodec, err := Decode(frame)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+
+				if gotCodec != codec {
+					t.Fatalf("Decode() codec = %v, want %v", gotCodec, codec)
+				}
+
+				if !reflect.DeepEqual(got, tt.message) {
+					t.Fatalf("Decode() = %+v, want %+v", got, tt.message)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeRejectsFrames(t *testing.T) {
+	unknownJSON, err := Encode(CodecJSON, Message{Type: "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownCBOR, err := Encode(CodecCBOR, Message{Type: "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  error
+	}{
+		{"empty frame", []byte{}, ErrEmptyFrame},
+		{"unknown JSON message", unknownJSON, ErrUnknownMessageType},
+		{"unknown CBOR message", unknownCBOR, ErrUnknownMessageType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Decode(tt.frame); err != tt.want {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeRejectsUnknownCodec(t *testing.T) {
+	if _, err := Encode("xml", NewBackoff()); err != ErrUnsupportedCodec {
+		t.Fatalf("Encode() error = %v, want %v", err, ErrUnsupportedCodec)
+	}
+}
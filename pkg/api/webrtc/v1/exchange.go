@@ -3,24 +3,26 @@ package v1
 // Greeting is a claim for a set of IDs
 type Greeting struct {
 	Message
-	IDs       map[string]struct{} `json:"ids"`       // IDs to claim one of
-	Timestamp int64               `json:"timestamp"` // Timestamp to resolve conflicts
+	IDs    map[string]struct{} `json:"ids" cbor:"1,keyasint"`                        // IDs to claim one of
+	Rank   int64               `json:"timestamp" cbor:"2,keyasint"`                  // Rank to resolve conflicts with; lower ranks win (named "timestamp" on the wire for compatibility with peers which use wall clock timestamps)
+	Codecs []string            `json:"codecs,omitempty" cbor:"3,keyasint,omitempty"` // Codecs the peer can decode; peers which don't send this only support JSON
 }
 
-func NewGreeting(id map[string]struct{}, timestamp int64) *Greeting {
+func NewGreeting(id map[string]struct{}, rank int64, codecs []string) *Greeting {
 	return &Greeting{
 		Message: Message{
 			Type: TypeGreeting,
 		},
-		IDs:       id,
-		Timestamp: timestamp,
+		IDs:    id,
+		Rank:   rank,
+		Codecs: codecs,
 	}
 }
 
 // Kick notifies peers that an ID has already been claimed
 type Kick struct {
 	Message
-	ID string `json:"id"` // ID which has already been claimed
+	ID string `json:"id" cbor:"1,keyasint"` // ID which has already been claimed
 }
 
 func NewKick(id string) *Kick {
@@ -48,7 +50,9 @@ func NewBackoff() *Backoff {
 // Claimed notifies a peer that an ID has already been claimed
 type Claimed struct {
 	Message
-	ID string `json:"id"` // ID which has already been claimed
+	ID        string `json:"id" cbor:"1,keyasint"`                            // ID which has already been claimed
+	PublicKey string `json:"publicKey,omitempty" cbor:"2,keyasint,omitempty"` // Base64-encoded identity of the peer which has claimed the ID
+	Signature string `json:"signature,omitempty" cbor:"3,keyasint,omitempty"` // Base64-encoded signature of the claim
 }
 
 func NewClaimed(id string) *Claimed {
@@ -59,3 +63,16 @@ func NewClaimed(id string) *Claimed {
 		ID: id,
 	}
 }
+
+// Acknowledgement notifies a peer that its greeting has been processed, i.e. that all kicks for it have been sent
+type Acknowledgement struct {
+	Message
+}
+
+func NewAcknowledgement() *Acknowledgement {
+	return &Acknowledgement{
+		Message: Message{
+			Type: TypeAcknowledgement,
+		},
+	}
+}
@@ -2,5 +2,5 @@ package v1
 
 // Message is a generic message container
 type Message struct {
-	Type string `json:"type"` // Message type to unmarshal to
+	Type string `json:"type" cbor:"0,keyasint"` // Message type to unmarshal to
 }
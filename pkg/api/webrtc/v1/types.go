@@ -5,4 +5,6 @@ const (
 	TypeKick     = "kick"     // Kick notifies peers that an ID has already been claimed
 	TypeBackoff  = "backoff"  // Backoff asks a peer to back off from claiming IDs
 	TypeClaimed  = "claimed"  // Claimed notifies a peer that an ID has already been claimed
+
+	TypeAcknowledgement = "acknowledgement" // Acknowledgement notifies a peer that its greeting has been processed
 )
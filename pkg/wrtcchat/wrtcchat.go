@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/pojntfx/weron/pkg/wrtcconn"
 	"github.com/rs/zerolog/log"
@@ -24,6 +25,8 @@ type AdapterConfig struct {
 	OnPeerConnect      func(peerID string, channelID string) // Handler to be called when the adapter has connected to a peer
 	OnPeerDisconnected func(peerID string, channelID string) // Handler to be called when the adapter has disconnected from a peer
 	OnMessage          func(Message)                         // Handler to be called when the adapter has received a message
+	OnRosterChange     func(names []string)                  // Handler to be called when the names of the connected peers have changed
+	OnPeerRename       func(oldID string, newID string)      // Handler to be called when a connected peer has renamed itself
 	Channels           []string                              // Channels to join
 }
 
@@ -40,6 +43,9 @@ type Adapter struct {
 
 	ids   chan string
 	input *broadcast.Relay[[]byte]
+
+	peerIDs     map[*wrtcconn.Peer]string // Current IDs of the connected peers, which can change if they rename themselves
+	peerIDsLock sync.Mutex
 }
 
 // NewAdapter creates the adapter
@@ -67,6 +73,8 @@ func NewAdapter(
 
 		ids:   make(chan string),
 		input: broadcast.NewRelay[[]byte](),
+
+		peerIDs: map[*wrtcconn.Peer]string{},
 	}
 }
 
@@ -74,6 +82,24 @@ func NewAdapter(
 func (a *Adapter) Open() error {
 	log.Trace().Msg("Opening adapter")
 
+	if a.config.NamedAdapterConfig == nil {
+		a.config.NamedAdapterConfig = &wrtcconn.NamedAdapterConfig{}
+	}
+
+	a.config.NamedAdapterConfig.OnRename = func(oldID, newID string) {
+		a.peerIDsLock.Lock()
+		for peer, peerID := range a.peerIDs {
+			if peerID == oldID {
+				a.peerIDs[peer] = newID
+			}
+		}
+		a.peerIDsLock.Unlock()
+
+		if a.config.OnPeerRename != nil {
+			a.config.OnPeerRename(oldID, newID)
+		}
+	}
+
 	a.adapter = wrtcconn.NewNamedAdapter(
 		a.signaler,
 		a.key,
@@ -101,6 +127,11 @@ func (a *Adapter) Close() error {
 	return a.adapter.Close()
 }
 
+// CompressionStats returns the compression statistics for each compressed channel
+func (a *Adapter) CompressionStats() map[string]wrtcconn.CompressionStats {
+	return a.adapter.CompressionStats()
+}
+
 // Wait starts the transmission loop
 func (a *Adapter) Wait() error {
 	for {
@@ -119,23 +150,41 @@ func (a *Adapter) Wait() error {
 			if a.config.OnSignalerConnect != nil {
 				a.config.OnSignalerConnect(id)
 			}
+		case <-a.adapter.Changes():
+			names := a.adapter.Names()
+
+			log.Debug().Strs("names", names).Msg("Roster changed")
+
+			if a.config.OnRosterChange != nil {
+				a.config.OnRosterChange(names)
+			}
 		case peer := <-a.adapter.Accept():
 			log.Debug().Str("channelID", peer.ChannelID).Str("peerID", peer.PeerID).Msg("Connected to peer")
 
 			l := a.input.Listener(0)
 
+			a.peerIDsLock.Lock()
+			a.peerIDs[peer] = peer.PeerID
+			a.peerIDsLock.Unlock()
+
 			if a.config.OnPeerConnect != nil {
 				a.config.OnPeerConnect(peer.PeerID, peer.ChannelID)
 			}
 
 			go func() {
 				defer func() {
-					log.Debug().Str("channelID", peer.ChannelID).Str("peerID", peer.PeerID).Msg("Disconnected from peer")
+					peerID := a.getPeerID(peer)
+
+					log.Debug().Str("channelID", peer.ChannelID).Str("peerID", peerID).Msg("Disconnected from peer")
 
 					if a.config.OnPeerDisconnected != nil {
-						a.config.OnPeerDisconnected(peer.PeerID, peer.ChannelID)
+						a.config.OnPeerDisconnected(peerID, peer.ChannelID)
 					}
 
+					a.peerIDsLock.Lock()
+					delete(a.peerIDs, peer)
+					a.peerIDsLock.Unlock()
+
 					l.Close()
 				}()
 
@@ -147,7 +196,7 @@ func (a *Adapter) Wait() error {
 
 					a.config.OnMessage(
 						Message{
-							PeerID:    peer.PeerID,
+							PeerID:    a.getPeerID(peer),
 							ChannelID: peer.ChannelID,
 							Body:      body,
 						},
@@ -172,6 +221,27 @@ func (a *Adapter) Wait() error {
 	}
 }
 
+func (a *Adapter) getPeerID(peer *wrtcconn.Peer) string {
+	a.peerIDsLock.Lock()
+	defer a.peerIDsLock.Unlock()
+
+	if peerID, ok := a.peerIDs[peer]; ok {
+		return peerID
+	}
+
+	return peer.PeerID
+}
+
+// Rename claims one of the candidates as the new name without reconnecting to the peers
+func (a *Adapter) Rename(candidates []string) error {
+	return a.adapter.Rename(candidates)
+}
+
+// Roster returns the names of the connected peers
+func (a *Adapter) Roster() []string {
+	return a.adapter.Names()
+}
+
 // SendMessage sends a message to all peers
 func (a *Adapter) SendMessage(body []byte) {
 	log.Trace().Bytes("body", body).Msg("Sending message")
@@ -2,11 +2,14 @@ package wrtcconn
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
 	"errors"
 	"io"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +20,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	defaultReplayWindow = time.Minute * 5
+)
+
 var (
 	ErrInvalidTURNServerAddr   = errors.New("invalid TURN server address")                            // The specified TURN server address is invalid
 	ErrMissingTURNCredentials  = errors.New("missing TURN server credentials")                        // The specified TURN server is missing credentials
@@ -24,10 +31,12 @@ var (
 )
 
 type peer struct {
-	conn       *webrtc.PeerConnection
-	candidates chan webrtc.ICECandidateInit
-	channels   map[string]*webrtc.DataChannel
-	iid        string
+	conn        *webrtc.PeerConnection
+	candidates  chan webrtc.ICECandidateInit
+	channels    map[string]*webrtc.DataChannel
+	iid         string
+	publicKey   ed25519.PublicKey
+	fingerprint string
 }
 
 // Peer is a connected remote adapter
@@ -35,14 +44,30 @@ type Peer struct {
 	PeerID    string             // ID of the peer
 	ChannelID string             // Channel on which the peer is connected to
 	Conn      io.ReadWriteCloser // Underlying connection to send/receive on
+	PublicKey ed25519.PublicKey  // Verified identity of the peer (nil if the peer has not presented an identity)
 }
 
 // AdapterConfig configures the adapter
 type AdapterConfig struct {
-	Timeout             time.Duration // Time to wait before retrying to connect to the signaler
-	ID                  string        // ID to claim without conflict resolution (default is UUID)
-	ForceRelay          bool          // Whether to block P2P connections
-	OnSignalerReconnect func()        // Handler to be called when the adapter has reconnected to the signaler
+	Timeout              time.Duration                                   // Time to wait before retrying to connect to the signaler
+	ID                   string                                          // ID to claim without conflict resolution (default is UUID)
+	ForceRelay           bool                                            // Whether to block P2P connections
+	OnSignalerReconnect  func()                                          // Handler to be called when the adapter has reconnected to the signaler
+	Compression          map[string]string                               // Compression algorithm to negotiate per channel ID (see CompressionZstd and CompressionSnappy)
+	ReplayWindow         time.Duration                                   // Maximum age of signaling messages before they are rejected as stale (default is 5 minutes)
+	LegacyKeyDerivation  bool                                            // Whether to send signaling messages in the format of peers which predate envelopes and accept it from them; this disables replay protection for those messages
+	Identity             ed25519.PrivateKey                              // Identity key to sign signaling messages with (optional)
+	KnownPeers           KnownPeers                                      // Store of trusted peer identities (optional)
+	RequireIdentity      bool                                            // Whether to reject peers which don't present an identity
+	OnUnknownIdentity    func(peerID string, key ed25519.PublicKey) bool // Handler to be called when a peer presents an unknown identity; returning false rejects it (default is to trust it on first use)
+	Certificate          *webrtc.Certificate                             // DTLS certificate to use for all peer connections; its fingerprint is signed with the identity key (default is a certificate generated when opening the adapter)
+	Keys                 []string                                        // Additional keys to decrypt signaling messages with, i.e. while rotating the community key (the adapter's key is used for encryption)
+	OnKeyUnused          func(keyID string)                              // Handler to be called once an additional key hasn't been used to decrypt signaling messages for KeyUnusedTimeout
+	KeyUnusedTimeout     time.Duration                                   // Time after which an additional key is considered to be unused (default is 1 hour)
+	TLSConfig            *tls.Config                                     // TLS configuration to connect to the signaler with, i.e. to trust a private CA or to present a client certificate (optional)
+	IDToken              func() (string, error)                          // Returns the OIDC ID token to join communities which require OIDC with; it is called before every connection to the signaler, so it can refresh the token (optional)
+	LegacyAuthentication bool                                            // Whether to send the password to signalers which don't support challenges, or which ask for it because the community has been created before verifiers were introduced; the password can end up in the logs of the signaler and of proxies in front of it
+	CreateCommunity      bool                                            // Whether to create the community as an ephemeral community if it doesn't exist yet; if not, signalers which ask the adapter to create it are rejected
 }
 
 // NamedAdapter provides a connection service without name conflict prevention
@@ -59,9 +84,25 @@ type Adapter struct {
 	doneSync sync.Mutex
 	lines    chan []byte
 
-	peers chan *Peer
+	peers      chan *Peer
+	reconnects chan struct{}
+	clients    atomic.Int64
+
+	api         *webrtc.API
+	certificate *webrtc.Certificate
+	fingerprint string
+	knownPeers  KnownPeers
 
-	api *webrtc.API
+	keyring     *encryption.Keyring
+	keyringLock sync.Mutex
+
+	routing    bool
+	routes     map[string]struct{}
+	routesLock sync.Mutex
+
+	codecs              codecs
+	compressionLock     sync.Mutex
+	compressionCounters map[string]*compressionCounters
 }
 
 // NewAdapter creates the adapter
@@ -91,9 +132,13 @@ func NewAdapter(
 		config:   config,
 		ctx:      ictx,
 
-		cancel: cancel,
-		peers:  make(chan *Peer),
-		lines:  make(chan []byte),
+		cancel:     cancel,
+		peers:      make(chan *Peer),
+		lines:      make(chan []byte),
+		reconnects: make(chan struct{}, 1),
+		routes:     map[string]struct{}{},
+
+		compressionCounters: map[string]*compressionCounters{},
 	}
 }
 
@@ -108,8 +153,56 @@ func (a *Adapter) sendLine(line []byte) {
 	a.lines <- line
 }
 
+func (a *Adapter) wrapConn(channelID string, protocol string, conn io.ReadWriteCloser) (io.ReadWriteCloser, error) {
+	compression, err := parseCompressionProtocol(protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	if compression == CompressionNone {
+		return conn, nil
+	}
+
+	cd, err := a.codecs.get(compression)
+	if err != nil {
+		return nil, err
+	}
+
+	a.compressionLock.Lock()
+	counters, ok := a.compressionCounters[channelID]
+	if !ok {
+		counters = &compressionCounters{}
+		a.compressionCounters[channelID] = counters
+	}
+	a.compressionLock.Unlock()
+
+	return newCompressedConn(conn, cd, counters), nil
+}
+
 // Open connects the adapter to the signaler
 func (a *Adapter) Open() (chan string, error) {
+	for _, compression := range a.config.Compression {
+		if err := validateCompression(compression); err != nil {
+			return nil, err
+		}
+	}
+
+	a.certificate = a.config.Certificate
+	if a.certificate == nil {
+		certificate, err := GenerateCertificate()
+		if err != nil {
+			return nil, err
+		}
+
+		a.certificate = certificate
+	}
+
+	fingerprint, err := getCertificateFingerprint(a.certificate)
+	if err != nil {
+		return nil, err
+	}
+	a.fingerprint = fingerprint
+
 	settingEngine := webrtc.SettingEngine{}
 	settingEngine.DetachDataChannels()
 	a.api = webrtc.NewAPI(webrtc.WithSettingEngine(settingEngine))
@@ -123,6 +216,11 @@ func (a *Adapter) Open() (chan string, error) {
 
 	community := u.Query().Get("community")
 
+	a.knownPeers = newScopedKnownPeers(a.config.KnownPeers, u, community)
+
+	// The password must not end up in logs
+	lu := getRedactedURL(u)
+
 	iceServers := []webrtc.ICEServer{}
 
 	containsTURN := false
@@ -164,6 +262,26 @@ func (a *Adapter) Open() (chan string, error) {
 		return ids, ErrMissingForcedTURNServer
 	}
 
+	replayWindow := a.config.ReplayWindow
+	if replayWindow <= 0 {
+		replayWindow = defaultReplayWindow
+	}
+
+	replays := encryption.NewReplayFilter(replayWindow)
+
+	key := a.newKeyring(community)
+
+	a.keyringLock.Lock()
+	a.keyring = key
+	a.keyringLock.Unlock()
+
+	go a.watchKeyUsage(time.Now())
+
+	version := encryption.EnvelopeVersion3
+	if a.config.LegacyKeyDerivation {
+		version = encryption.EnvelopeVersionLegacy
+	}
+
 	go func() {
 		for {
 			if a.done {
@@ -176,10 +294,10 @@ func (a *Adapter) Open() (chan string, error) {
 			func() {
 				defer func() {
 					if err := recover(); err != nil {
-						log.Debug().Str("address", u.String()).Err(err.(error)).Msg("Closed connection to signaler (wrong username or password?)")
+						log.Debug().Str("address", lu.String()).Err(err.(error)).Msg("Closed connection to signaler (wrong username or password?)")
 					}
 
-					log.Debug().Str("address", u.String()).Dur("timeout", a.config.Timeout).Msg("Reconnecting to signaler")
+					log.Debug().Str("address", lu.String()).Dur("timeout", a.config.Timeout).Msg("Reconnecting to signaler")
 
 					if a.config.OnSignalerReconnect != nil {
 						a.config.OnSignalerReconnect()
@@ -191,13 +309,30 @@ func (a *Adapter) Open() (chan string, error) {
 				ctx, cancel := context.WithTimeout(a.ctx, a.config.Timeout)
 				defer cancel()
 
-				conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+				id := a.config.ID
+				if strings.TrimSpace(id) == "" {
+					id = uuid.New().String()
+				}
+
+				// Register our recipient token so that the signaler only delivers the exchanges which are addressed to us
+				ru := *u
+				q := ru.Query()
+				q.Set(websocketapi.QueryRecipient, websocketapi.GetRecipientToken(community, id))
+				ru.RawQuery = q.Encode()
+
+				dialer := *websocket.DefaultDialer
+				dialer.TLSClientConfig = a.config.TLSConfig
+
+				conn, clients, routing, err := a.dialSignaler(ctx, dialer, &ru, community)
 				if err != nil {
 					panic(err)
 				}
 
+				a.resetRoutes(routing)
+				a.clients.Store(int64(clients))
+
 				defer func() {
-					log.Debug().Str("address", u.String()).Msg("Disconnected from signaler")
+					log.Debug().Str("address", lu.String()).Msg("Disconnected from signaler")
 
 					if err := conn.Close(); err != nil {
 						panic(err)
@@ -228,7 +363,7 @@ func (a *Adapter) Open() (chan string, error) {
 					return conn.SetReadDeadline(time.Now().Add(a.config.Timeout))
 				})
 
-				log.Debug().Str("address", u.String()).Msg("Connected to signaler")
+				log.Debug().Str("address", lu.String()).Msg("Connected to signaler")
 
 				inputs := make(chan []byte)
 				errs := make(chan error)
@@ -250,15 +385,10 @@ func (a *Adapter) Open() (chan string, error) {
 					}
 				}()
 
-				id := a.config.ID
-				if strings.TrimSpace(id) == "" {
-					id = uuid.New().String()
-				}
-
 				ids <- id
 
 				go func() {
-					p, err := json.Marshal(websocketapi.NewIntroduction(id))
+					p, err := json.Marshal(a.signIntroduction(community, websocketapi.NewIntroduction(id)))
 					if err != nil {
 						errs <- err
 
@@ -267,7 +397,7 @@ func (a *Adapter) Open() (chan string, error) {
 
 					a.sendLine(p)
 
-					log.Debug().Str("address", u.String()).Str("id", id).Msg("Introduced to signaler")
+					log.Debug().Str("address", lu.String()).Str("id", id).Msg("Introduced to signaler")
 				}()
 
 				pings := time.NewTicker(a.config.Timeout / 2)
@@ -277,8 +407,16 @@ func (a *Adapter) Open() (chan string, error) {
 					select {
 					case err := <-errs:
 						panic(err)
+					case <-a.reconnects:
+						log.Debug().
+							Str("address", conn.RemoteAddr().String()).
+							Str("community", community).
+							Str("id", id).
+							Msg("Reconnect requested, disconnecting from signaler")
+
+						return
 					case input := <-inputs:
-						input, err = encryption.Decrypt(input, []byte(a.key))
+						envelope, err := encryption.Open(input, key)
 						if err != nil {
 							log.Debug().
 								Str("address", conn.RemoteAddr().String()).
@@ -289,6 +427,34 @@ func (a *Adapter) Open() (chan string, error) {
 							continue
 						}
 
+						legacy := envelope.Version == encryption.EnvelopeVersionLegacy
+						if legacy && !a.config.LegacyKeyDerivation {
+							log.Debug().
+								Str("address", conn.RemoteAddr().String()).
+								Str("community", community).
+								Str("id", id).
+								Msg("Discarding message from signaler without an envelope since legacy key derivation is disabled, continuing")
+
+							continue
+						}
+
+						// Messages without an envelope have neither a timestamp nor a type to check
+						if !legacy {
+							if err := replays.Check(envelope); err != nil {
+								log.Debug().
+									Err(err).
+									Str("address", conn.RemoteAddr().String()).
+									Str("community", community).
+									Str("id", id).
+									Time("timestamp", envelope.Timestamp).
+									Msg("Discarding stale or duplicate message from signaler, continuing")
+
+								continue
+							}
+						}
+
+						input = envelope.Payload
+
 						log.Trace().
 							Str("address", conn.RemoteAddr().String()).
 							Int("len", len(input)).
@@ -305,6 +471,18 @@ func (a *Adapter) Open() (chan string, error) {
 							continue
 						}
 
+						if !legacy && message.Type != envelope.Type {
+							log.Debug().
+								Str("address", conn.RemoteAddr().String()).
+								Str("community", community).
+								Str("id", id).
+								Str("type", message.Type).
+								Str("envelopeType", envelope.Type).
+								Msg("Discarding message from signaler because its type does not match its envelope, continuing")
+
+							continue
+						}
+
 						switch message.Type {
 						case websocketapi.TypeIntroduction:
 							var introduction websocketapi.Introduction
@@ -317,6 +495,21 @@ func (a *Adapter) Open() (chan string, error) {
 								continue
 							}
 
+							publicKey, err := a.verifyIntroduction(community, &introduction)
+							if err != nil {
+								log.Debug().
+									Err(err).
+									Str("address", conn.RemoteAddr().String()).
+									Str("community", community).
+									Str("id", id).
+									Str("peerID", introduction.From).
+									Msg("Could not verify identity of introduction from signaler, continuing")
+
+								continue
+							}
+
+							a.setRoutable(introduction.From, introduction.Routable)
+
 							log.Debug().
 								Str("address", conn.RemoteAddr().String()).
 								Str("community", community).
@@ -332,6 +525,7 @@ func (a *Adapter) Open() (chan string, error) {
 							c, err := a.api.NewPeerConnection(webrtc.Configuration{
 								ICEServers:         iceServers,
 								ICETransportPolicy: transportPolicy,
+								Certificates:       []webrtc.Certificate{*a.certificate},
 							})
 							if err != nil {
 								panic(err)
@@ -358,14 +552,15 @@ func (a *Adapter) Open() (chan string, error) {
 										return
 									}
 
+									// The peer is gone, so closing its channels can fail if they have already been reset
 									for _, channel := range c.channels {
 										if err := channel.Close(); err != nil {
-											panic(err)
+											log.Debug().Err(err).Str("peerID", introduction.From).Str("label", channel.Label()).Msg("Could not close channel, continuing")
 										}
 									}
 
 									if err := c.conn.Close(); err != nil {
-										panic(err)
+										log.Debug().Err(err).Str("peerID", introduction.From).Msg("Could not close connection to peer, continuing")
 									}
 
 									close(c.candidates)
@@ -382,7 +577,7 @@ func (a *Adapter) Open() (chan string, error) {
 										Str("community", community).
 										Str("id", id).Msg("Created ICE candidate")
 
-									p, err := json.Marshal(websocketapi.NewCandidate(id, introduction.From, []byte(i.ToJSON().Candidate)))
+									p, err := json.Marshal(a.signExchange(community, websocketapi.NewCandidate(id, introduction.From, []byte(i.ToJSON().Candidate))))
 									if err != nil {
 										panic(err)
 									}
@@ -406,7 +601,14 @@ func (a *Adapter) Open() (chan string, error) {
 									continue
 								}
 
-								dc, err := c.CreateDataChannel(channelID, nil)
+								var init *webrtc.DataChannelInit
+								if protocol := getCompressionProtocol(a.config.Compression[channelID]); protocol != "" {
+									init = &webrtc.DataChannelInit{
+										Protocol: &protocol,
+									}
+								}
+
+								dc, err := c.CreateDataChannel(channelID, init)
 								if err != nil {
 									panic(err)
 								}
@@ -423,16 +625,53 @@ func (a *Adapter) Open() (chan string, error) {
 										Str("peer", introduction.From).
 										Msg("Connected to channel")
 
+									peerLock.Lock()
+									fingerprint := ""
+									if p, ok := peers[introduction.From]; ok {
+										fingerprint = p.fingerprint
+									}
+									peerLock.Unlock()
+
+									if err := verifyCertificate(c, a.knownPeers, introduction.From, publicKey, fingerprint, a.config.RequireIdentity); err != nil {
+										log.Debug().
+											Err(err).
+											Str("label", dc.Label()).
+											Str("peer", introduction.From).
+											Msg("Could not verify certificate of peer, closing")
+
+										if err := dc.Close(); err != nil {
+											panic(err)
+										}
+
+										return
+									}
+
 									c, err := dc.Detach()
 									if err != nil {
 										panic(err)
 									}
 
+									rwc, err := a.wrapConn(dc.Label(), dc.Protocol(), c)
+									if err != nil {
+										log.Debug().
+											Err(err).
+											Str("label", dc.Label()).
+											Str("protocol", dc.Protocol()).
+											Str("peer", introduction.From).
+											Msg("Could not negotiate compression for channel, closing")
+
+										if err := dc.Close(); err != nil {
+											panic(err)
+										}
+
+										return
+									}
+
 									for _, channel := range a.channels {
 										if dc.Label() == channel {
 											peerLock.Lock()
 											peers[introduction.From].channels[dc.Label()] = dc
-											a.peers <- &Peer{introduction.From, dc.Label(), c}
+											a.peers <- &Peer{introduction.From, dc.Label(), rwc, publicKey}
 											peerLock.Unlock()
 
 											break
@@ -487,14 +726,14 @@ func (a *Adapter) Open() (chan string, error) {
 										panic(err)
 									}
 
-									p, err := json.Marshal(websocketapi.NewOffer(id, introduction.From, oj))
+									p, err := json.Marshal(a.signExchange(community, websocketapi.NewOffer(id, introduction.From, oj)))
 									if err != nil {
 										panic(err)
 									}
 
 									pr := &peer{c, make(chan webrtc.ICECandidateInit), map[string]*webrtc.DataChannel{
 										dc.Label(): dc,
-									}, iid}
+									}, iid, publicKey, ""}
 
 									peerLock.Lock()
 									old, ok := peers[introduction.From]
@@ -550,6 +789,21 @@ func (a *Adapter) Open() (chan string, error) {
 								continue
 							}
 
+							publicKey, err := a.verifyExchange(community, &offer, nil, true)
+							if err != nil {
+								log.Debug().
+									Err(err).
+									Str("address", conn.RemoteAddr().String()).
+									Str("community", community).
+									Str("id", id).
+									Str("peerID", offer.From).
+									Msg("Could not verify identity of offer from signaler, continuing")
+
+								continue
+							}
+
+							a.setRoutable(offer.From, offer.Routable)
+
 							log.Debug().
 								Str("address", conn.RemoteAddr().String()).
 								Str("community", community).
@@ -565,6 +819,7 @@ func (a *Adapter) Open() (chan string, error) {
 							c, err := a.api.NewPeerConnection(webrtc.Configuration{
 								ICEServers:         iceServers,
 								ICETransportPolicy: transportPolicy,
+								Certificates:       []webrtc.Certificate{*a.certificate},
 							})
 							if err != nil {
 								panic(err)
@@ -591,11 +846,7 @@ func (a *Adapter) Open() (chan string, error) {
 									}
 
 									if err := c.conn.Close(); err != nil {
-										panic(err)
-									}
-
-									if err := c.conn.Close(); err != nil {
-										panic(err)
+										log.Debug().Err(err).Str("peerID", offer.From).Msg("Could not close connection to peer, continuing")
 									}
 
 									close(c.candidates)
@@ -612,7 +863,7 @@ func (a *Adapter) Open() (chan string, error) {
 										Str("community", community).
 										Str("id", id).Msg("Created ICE candidate")
 
-									p, err := json.Marshal(websocketapi.NewCandidate(id, offer.From, []byte(i.ToJSON().Candidate)))
+									p, err := json.Marshal(a.signExchange(community, websocketapi.NewCandidate(id, offer.From, []byte(i.ToJSON().Candidate))))
 									if err != nil {
 										panic(err)
 									}
@@ -637,16 +888,46 @@ func (a *Adapter) Open() (chan string, error) {
 										Str("peer", offer.From).
 										Msg("Connected to channel")
 
+									if err := verifyCertificate(c, a.knownPeers, offer.From, publicKey, offer.Fingerprint, a.config.RequireIdentity); err != nil {
+										log.Debug().
+											Err(err).
+											Str("label", dc.Label()).
+											Str("peer", offer.From).
+											Msg("Could not verify certificate of peer, closing")
+
+										if err := dc.Close(); err != nil {
+											panic(err)
+										}
+
+										return
+									}
+
 									c, err := dc.Detach()
 									if err != nil {
 										panic(err)
 									}
 
+									rwc, err := a.wrapConn(dc.Label(), dc.Protocol(), c)
+									if err != nil {
+										log.Debug().
+											Err(err).
+											Str("label", dc.Label()).
+											Str("protocol", dc.Protocol()).
+											Str("peer", offer.From).
+											Msg("Could not negotiate compression for channel, closing")
+
+										if err := dc.Close(); err != nil {
+											panic(err)
+										}
+
+										return
+									}
+
 									for _, channel := range a.channels {
 										if dc.Label() == channel {
 											peerLock.Lock()
 											peers[offer.From].channels[dc.Label()] = dc
-											a.peers <- &Peer{offer.From, dc.Label(), c}
+											a.peers <- &Peer{offer.From, dc.Label(), rwc, publicKey}
 											peerLock.Unlock()
 
 											break
@@ -708,7 +989,7 @@ func (a *Adapter) Open() (chan string, error) {
 								panic(err)
 							}
 
-							p, err := json.Marshal(websocketapi.NewAnswer(id, offer.From, aj))
+							p, err := json.Marshal(a.signExchange(community, websocketapi.NewAnswer(id, offer.From, aj)))
 							if err != nil {
 								panic(err)
 							}
@@ -716,7 +997,7 @@ func (a *Adapter) Open() (chan string, error) {
 							peerLock.Lock()
 
 							candidates := make(chan webrtc.ICECandidateInit)
-							peers[offer.From] = &peer{c, candidates, map[string]*webrtc.DataChannel{}, iid}
+							peers[offer.From] = &peer{c, candidates, map[string]*webrtc.DataChannel{}, iid, publicKey, ""}
 
 							peerLock.Unlock()
 
@@ -783,6 +1064,20 @@ func (a *Adapter) Open() (chan string, error) {
 								continue
 							}
 
+							if _, err := a.verifyExchange(community, &candidate, c.publicKey, false); err != nil {
+								log.Debug().
+									Err(err).
+									Str("address", conn.RemoteAddr().String()).
+									Str("community", community).
+									Str("id", id).
+									Str("peerID", candidate.From).
+									Msg("Could not verify identity of candidate from signaler, continuing")
+
+								peerLock.Unlock()
+
+								continue
+							}
+
 							go func() {
 								defer func() {
 									if err := recover(); err != nil {
@@ -833,6 +1128,22 @@ func (a *Adapter) Open() (chan string, error) {
 								continue
 							}
 
+							if _, err := a.verifyExchange(community, &answer, c.publicKey, false); err != nil {
+								log.Debug().
+									Err(err).
+									Str("address", conn.RemoteAddr().String()).
+									Str("community", community).
+									Str("id", id).
+									Str("peerID", answer.From).
+									Msg("Could not verify identity of answer from signaler, continuing")
+
+								continue
+							}
+
+							peerLock.Lock()
+							c.fingerprint = answer.Fingerprint
+							peerLock.Unlock()
+
 							var sdp webrtc.SessionDescription
 							if err := json.Unmarshal(answer.Payload, &sdp); err != nil {
 								log.Debug().
@@ -881,7 +1192,18 @@ func (a *Adapter) Open() (chan string, error) {
 							continue
 						}
 					case line := <-a.lines:
-						line, err = encryption.Encrypt(line, []byte(a.key))
+						// Introductions don't have a recipient, so they are always broadcast
+						var message websocketapi.Exchange
+						if err := json.Unmarshal(line, &message); err != nil {
+							panic(err)
+						}
+
+						line, err = encryption.Seal(line, key, version, message.Type, time.Now())
+						if err != nil {
+							panic(err)
+						}
+
+						line, err = a.route(community, message.To, line)
 						if err != nil {
 							panic(err)
 						}
@@ -940,3 +1262,24 @@ func (a *Adapter) Close() error {
 func (a *Adapter) Accept() chan *Peer {
 	return a.peers
 }
+
+// reconnect disconnects from the signaler and all peers, after which the adapter reconnects with a new ID
+func (a *Adapter) reconnect() {
+	select {
+	case a.reconnects <- struct{}{}:
+	default:
+	}
+}
+
+// CompressionStats returns the compression statistics for each compressed channel
+func (a *Adapter) CompressionStats() map[string]CompressionStats {
+	a.compressionLock.Lock()
+	defer a.compressionLock.Unlock()
+
+	stats := map[string]CompressionStats{}
+	for channelID, counters := range a.compressionCounters {
+		stats[channelID] = counters.stats()
+	}
+
+	return stats
+}
@@ -2,7 +2,10 @@ package wrtcconn
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -10,13 +13,15 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 
-	"github.com/mitchellh/mapstructure"
 	v1 "github.com/pojntfx/weron/pkg/api/webrtc/v1"
 	"github.com/pojntfx/weron/pkg/services"
 )
 
 var (
-	ErrAllNamesClaimed = errors.New("all available names have been claimed") // All specified usernames have already been claimed by other peers
+	ErrAllNamesClaimed = errors.New("all available names have been claimed")  // All specified usernames have already been claimed by other peers
+	ErrMissingNames    = errors.New("no names provided")                      // No names to claim one of have been specified
+	ErrNameNotClaimed  = errors.New("no name has been claimed yet")           // Renaming requires a claimed name, i.e. because a name is being claimed or renamed already
+	ErrRenameAborted   = errors.New("rename has been aborted by a reconnect") // The adapter has reconnected to the signaler while renaming, so it claims one of its original names again
 
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
 )
@@ -26,8 +31,50 @@ type NamedAdapterConfig struct {
 	*AdapterConfig
 	IDChannel   string                                             // Channel to use for ID negotiation
 	Names       []string                                           // Names to try and claim one of
-	Kicks       time.Duration                                      // Time to wait for kicks before claiming names
+	Kicks       time.Duration                                      // Maximum time to wait for kicks before claiming names; names are claimed earlier once all other clients in the community have acknowledged the greeting
+	ClaimAlone  bool                                               // Whether to claim names immediately if the signaler reports no other clients in the community
 	IsIDClaimed func(theirs map[string]struct{}, ours string) bool // Handler to be called when asked to compare own ID with an incoming greeting
+	OnConflict  func(id string)                                    // Handler to be called when another peer has claimed the same ID (i.e. after a network partition has healed) and won the tie-break; the adapter re-claims an ID afterwards
+	OnRename    func(oldID string, newID string)                   // Handler to be called when a connected peer has renamed itself; its connections stay open and are not accepted again
+	Codec       string                                             // Codec to use for ID negotiation messages if the peer supports it (v1.CodecCBOR by default; v1.CodecJSON keeps messages readable for debugging)
+}
+
+type rename struct {
+	candidates []string
+	result     chan error
+}
+
+// acknowledgements tracks the peers which have acknowledged our greeting; names are claimed before the kicks timeout once all expected peers have done so
+type acknowledgements struct {
+	expected int // Amount of peers which are expected to acknowledge the greeting; -1 if it is unknown, in which case the timeout is always waited for
+	peers    map[string]struct{}
+}
+
+func newAcknowledgements(expected int) *acknowledgements {
+	if expected < 0 {
+		expected = -1
+	}
+
+	return &acknowledgements{
+		expected: expected,
+		peers:    map[string]struct{}{},
+	}
+}
+
+// reset forgets all acknowledgements, i.e. because they don't cover the next greeting
+func (a *acknowledgements) reset() {
+	a.peers = map[string]struct{}{}
+}
+
+// add records the acknowledgement of a peer and returns whether all expected peers have acknowledged the greeting
+func (a *acknowledgements) add(peerID string) bool {
+	if a.expected <= 0 {
+		return false
+	}
+
+	a.peers[peerID] = struct{}{}
+
+	return len(a.peers) >= a.expected
 }
 
 // NamedAdapter provides a connection service with name conflict prevention
@@ -41,10 +88,21 @@ type NamedAdapter struct {
 
 	cancel        context.CancelFunc
 	adapter       *Adapter
+	knownPeers    KnownPeers
 	ids           chan string
 	names         chan string
 	errs          chan error
 	acceptedPeers chan *Peer
+	directory     *directory
+	renames       chan rename
+}
+
+// getRank returns the rank of an underlying ID, which resolves conflicts in favor of the lower rank.
+// Since it is derived from the ID instead of a wall clock, all peers agree on it regardless of clock skew.
+func getRank(id string) int64 {
+	hash := sha256.Sum256([]byte(id))
+
+	return int64(binary.BigEndian.Uint64(hash[:8]) >> 1)
 }
 
 // NewNamedAdapter creates the adapter
@@ -66,6 +124,10 @@ func NewNamedAdapter(
 		config.IDChannel = services.IDGeneral
 	}
 
+	if config.Codec == "" {
+		config.Codec = v1.CodecCBOR
+	}
+
 	if config.IsIDClaimed == nil {
 		config.IsIDClaimed = func(ids map[string]struct{}, id string) bool {
 			_, ok := ids[id]
@@ -87,11 +149,26 @@ func NewNamedAdapter(
 		names:         make(chan string),
 		errs:          make(chan error),
 		acceptedPeers: make(chan *Peer),
+		directory:     newDirectory(),
+		renames:       make(chan rename),
 	}
 }
 
 // Open connects the adapter to the signaler
 func (a *NamedAdapter) Open() (chan string, error) {
+	if a.config.Codec != v1.CodecJSON && a.config.Codec != v1.CodecCBOR {
+		return nil, v1.ErrUnsupportedCodec
+	}
+
+	u, err := url.Parse(a.signaler)
+	if err != nil {
+		return nil, err
+	}
+
+	community := u.Query().Get("community")
+
+	a.knownPeers = newScopedKnownPeers(a.config.KnownPeers, u, community)
+
 	ready := time.NewTimer(a.config.Timeout + a.config.Kicks)
 
 	a.config.AdapterConfig.OnSignalerReconnect = func() {
@@ -99,16 +176,20 @@ func (a *NamedAdapter) Open() (chan string, error) {
 		ready.Reset(a.config.Timeout + a.config.Kicks)
 	}
 
+	// Identities are trusted for the claimed names instead of the ephemeral underlying IDs
+	adapterConfig := *a.config.AdapterConfig
+	adapterConfig.KnownPeers = nil
+	adapterConfig.OnUnknownIdentity = nil
+
 	a.adapter = NewAdapter(
 		a.signaler,
 		a.key,
 		strings.Split(strings.Join(a.ice, ","), ","),
 		append([]string{a.config.IDChannel}, a.channels...),
-		a.config.AdapterConfig,
+		&adapterConfig,
 		a.ctx,
 	)
 
-	var err error
 	a.ids, err = a.adapter.Open()
 	if err != nil {
 		return nil, err
@@ -117,7 +198,16 @@ func (a *NamedAdapter) Open() (chan string, error) {
 	var candidatesLock sync.Mutex
 	candidates := map[string]struct{}{}
 	id := ""
-	timestamp := time.Now().UnixNano()
+	rank := int64(0)
+
+	acks := newAcknowledgements(-1)
+
+	names := a.config.Names
+
+	// State of a running rename; the previous ID is defended until the new ID has been claimed, and kept if all candidates have been claimed
+	previousID := ""
+	previousNames := []string{}
+	var renamed chan error
 
 	peers := map[string]map[string]*Peer{}
 	var peersLock sync.Mutex
@@ -133,27 +223,80 @@ func (a *NamedAdapter) Open() (chan string, error) {
 				return
 			case sid := <-a.ids:
 				candidatesLock.Lock()
+				// Reconnecting releases the previous ID, so a running rename can't fall back to it and the original names are claimed again
+				if renamed != nil {
+					names = previousNames
+
+					renamed <- ErrRenameAborted
+					renamed = nil
+				}
+				previousID = ""
+
 				candidates = map[string]struct{}{}
-				for _, username := range a.config.Names {
+				for _, username := range names {
 					candidates[username] = struct{}{}
 				}
 				id = ""
+				rank = getRank(sid)
+				// The signaler's client count includes us; it is unknown if the signaler doesn't report it
+				acks = newAcknowledgements(int(a.adapter.clients.Load()) - 1)
+				expected := acks.expected
 				candidatesLock.Unlock()
 
-				log.Debug().Str("id", sid).Msg("Claimed ID")
+				log.Debug().Str("id", sid).Int("expected", expected).Msg("Claimed ID")
 
 				ready.Stop()
-				ready.Reset(a.config.Kicks)
+				if expected == 0 && a.config.ClaimAlone {
+					log.Debug().Str("id", sid).Msg("No other clients in community, claiming immediately")
+
+					ready.Reset(0)
+				} else {
+					ready.Reset(a.config.Kicks)
+				}
 			case <-ready.C:
 				candidatesLock.Lock()
+				// The timer might have been reset by an acknowledgement while it was firing
+				if id != "" {
+					candidatesLock.Unlock()
+
+					continue
+				}
+
+				acks = newAcknowledgements(-1)
 				for username := range candidates {
 					id = username
 
 					break
 				}
 				candidates = map[string]struct{}{}
+
+				previous := previousID
+				previousID = ""
 				candidatesLock.Unlock()
 
+				if id == "" && renamed != nil {
+					names = previousNames
+
+					renamed <- ErrAllNamesClaimed
+					renamed = nil
+
+					// The previous ID has been defended while renaming, so it is still ours
+					if previous != "" {
+						log.Debug().Str("id", previous).Msg("All names to rename to have been claimed, keeping previous ID")
+
+						id = previous
+						namedPeersCond.Broadcast()
+
+						continue
+					}
+
+					log.Debug().Msg("All names to rename to have been claimed and previous ID has been lost, re-claiming")
+
+					a.adapter.reconnect()
+
+					continue
+				}
+
 				if id == "" {
 					a.errs <- ErrAllNamesClaimed
 
@@ -164,29 +307,89 @@ func (a *NamedAdapter) Open() (chan string, error) {
 				namedPeersCond.Broadcast()
 
 				peersLock.Lock()
-				for _, peer := range peers {
+				for _, p := range peers {
+					peer, ok := p[a.config.IDChannel]
+					if !ok {
+						continue
+					}
+
 					log.Debug().Str("id", id).Msg("Sending claimed")
 
-					d, err := json.Marshal(v1.NewClaimed(id))
-					if err != nil {
+					if err := peer.Conn.(*messageConn).encode(a.newClaimed(community, id)); err != nil {
 						log.Debug().
-							Str("id", id).
-							Err(err).
-							Msg("Could not marshal claimed")
+							Str("channelID", peer.ChannelID).
+							Str("peerID", peer.PeerID).
+							Msg("Could not write to peer, stopping")
 
 						continue
 					}
+				}
+				peersLock.Unlock()
+
+				if renamed != nil {
+					renamed <- nil
+					renamed = nil
+				}
+			case r := <-a.renames:
+				if id == "" || renamed != nil {
+					r.result <- ErrNameNotClaimed
 
-					if _, err := peer[a.config.IDChannel].Conn.Write(d); err != nil {
+					continue
+				}
+
+				log.Debug().Str("id", id).Strs("candidates", r.candidates).Msg("Renaming")
+
+				candidatesLock.Lock()
+				candidates = map[string]struct{}{}
+				for _, username := range r.candidates {
+					candidates[username] = struct{}{}
+				}
+				previousID = id
+				previousNames = names
+				names = r.candidates
+				renamed = r.result
+				id = ""
+				expected := 0
+				peersLock.Lock()
+				for _, p := range peers {
+					if _, ok := p[a.config.IDChannel]; ok {
+						expected++
+					}
+				}
+				peersLock.Unlock()
+				acks = newAcknowledgements(expected)
+				candidatesLock.Unlock()
+
+				// Run the greeting protocol again on the existing ID channels
+				peersLock.Lock()
+				for _, p := range peers {
+					peer, ok := p[a.config.IDChannel]
+					if !ok {
+						continue
+					}
+
+					log.Debug().
+						Str("channelID", peer.ChannelID).
+						Str("peerID", peer.PeerID).
+						Msg("Sending greeting")
+
+					if err := peer.Conn.(*messageConn).encode(v1.NewGreeting(candidates, rank, a.getCodecs())); err != nil {
 						log.Debug().
-							Str("channelID", peer[a.config.IDChannel].ChannelID).
-							Str("peerID", peer[a.config.IDChannel].PeerID).
-							Msg("Could not write to peer, stopping")
+							Str("channelID", peer.ChannelID).
+							Str("peerID", peer.PeerID).
+							Msg("Could not write to peer, continuing")
 
 						continue
 					}
 				}
 				peersLock.Unlock()
+
+				ready.Stop()
+				if expected == 0 && a.config.ClaimAlone {
+					ready.Reset(0)
+				} else {
+					ready.Reset(a.config.Kicks)
+				}
 			case peer := <-namedPeers:
 				go func() {
 					if id == "" {
@@ -200,6 +403,10 @@ func (a *NamedAdapter) Open() (chan string, error) {
 			case peer := <-a.adapter.Accept():
 				rid := peer.PeerID
 
+				if peer.ChannelID == a.config.IDChannel {
+					peer.Conn = newMessageConn(peer.Conn, a.getCodecs())
+				}
+
 				peersLock.Lock()
 				for candidate, p := range peers {
 					for _, c := range p {
@@ -215,18 +422,21 @@ func (a *NamedAdapter) Open() (chan string, error) {
 				}
 				peers[rid][peer.ChannelID] = peer
 				if rid != peer.PeerID && peer.ChannelID != a.config.IDChannel {
-					namedPeers <- &Peer{
+					// Named peers are received in this loop, so they must be sent asynchronously
+					go func(p *Peer) {
+						namedPeers <- p
+					}(&Peer{
 						PeerID:    rid,
 						ChannelID: peer.ChannelID,
 						Conn:      peer.Conn,
-					}
+						PublicKey: peer.PublicKey,
+					})
 				}
 				peersLock.Unlock()
 
 				if peer.ChannelID == a.config.IDChannel {
 					go func() {
-						e := json.NewEncoder(peer.Conn)
-						d := json.NewDecoder(peer.Conn)
+						conn := peer.Conn.(*messageConn)
 
 						defer func() {
 							if err := recover(); err != nil {
@@ -242,10 +452,12 @@ func (a *NamedAdapter) Open() (chan string, error) {
 									Str("channelID", peer.ChannelID).
 									Str("peerID", rid).
 									Msg("Disconnected from peer")
+
+								a.directory.remove(rid, peer.PeerID)
 							}
 
 							peersLock.Lock()
-							if _, ok := peers[rid]; !ok {
+							if _, ok := peers[rid]; ok {
 								delete(peers[rid], peer.ChannelID)
 
 								if len(peers[rid]) <= 0 {
@@ -260,11 +472,11 @@ func (a *NamedAdapter) Open() (chan string, error) {
 								Str("channelID", peer.ChannelID).
 								Str("peerID", rid).
 								Int("candidates", len(candidates)).
-								Int64("timestamp", timestamp).
+								Int64("rank", rank).
 								Msg("Sending greeting")
 
 							if id == "" {
-								if err := e.Encode(v1.NewGreeting(candidates, timestamp)); err != nil {
+								if err := conn.encode(v1.NewGreeting(candidates, rank, a.getCodecs())); err != nil {
 									log.Debug().
 										Err(err).
 										Str("channelID", peer.ChannelID).
@@ -274,7 +486,7 @@ func (a *NamedAdapter) Open() (chan string, error) {
 									return
 								}
 							} else {
-								if err := e.Encode(v1.NewGreeting(map[string]struct{}{id: {}}, timestamp)); err != nil {
+								if err := conn.encode(v1.NewGreeting(map[string]struct{}{id: {}}, rank, a.getCodecs())); err != nil {
 									log.Debug().
 										Err(err).
 										Str("channelID", peer.ChannelID).
@@ -290,7 +502,7 @@ func (a *NamedAdapter) Open() (chan string, error) {
 									Str("id", id).
 									Msg("Sending claimed")
 
-								if err := e.Encode(v1.NewClaimed(id)); err != nil {
+								if err := conn.encode(a.newClaimed(community, id)); err != nil {
 									log.Debug().
 										Err(err).
 										Str("channelID", peer.ChannelID).
@@ -306,8 +518,8 @@ func (a *NamedAdapter) Open() (chan string, error) {
 
 					l:
 						for {
-							var j interface{}
-							if err := d.Decode(&j); err != nil {
+							frame, err := conn.read()
+							if err != nil {
 								log.Debug().
 									Err(err).
 									Str("channelID", peer.ChannelID).
@@ -317,8 +529,8 @@ func (a *NamedAdapter) Open() (chan string, error) {
 								return
 							}
 
-							var message v1.Message
-							if err := mapstructure.Decode(j, &message); err != nil {
+							message, codec, err := v1.Decode(frame)
+							if err != nil {
 								log.Debug().
 									Err(err).
 									Str("channelID", peer.ChannelID).
@@ -328,34 +540,29 @@ func (a *NamedAdapter) Open() (chan string, error) {
 								continue
 							}
 
-							switch message.Type {
-							case v1.TypeGreeting:
-								var gng v1.Greeting
-								if err := mapstructure.Decode(j, &gng); err != nil {
-									log.Debug().
-										Err(err).
-										Str("channelID", peer.ChannelID).
-										Str("peerID", rid).
-										Msg("Could not decode greeting from peer, stopping")
+							switch message := message.(type) {
+							case *v1.Greeting:
+								gng := message
 
-									continue
-								}
+								// Peers which announce a codec in their greeting can decode it from now on
+								conn.negotiate(gng.Codecs)
 
 								log.Debug().
 									Err(err).
 									Str("channelID", peer.ChannelID).
 									Str("peerID", rid).
+									Str("codec", codec).
 									Msg("Received greeting")
 
 								for gngID := range gng.IDs {
-									if _, ok := candidates[gngID]; id == "" && ok && timestamp < gng.Timestamp {
+									if _, ok := candidates[gngID]; id == "" && ok && rank < gng.Rank {
 										log.Debug().
 											Str("channelID", peer.ChannelID).
 											Str("peerID", rid).
 											Str("id", gngID).
 											Msg("Sending backoff")
 
-										if err := e.Encode(v1.NewBackoff()); err != nil {
+										if err := conn.encode(v1.NewBackoff()); err != nil {
 											log.Debug().
 												Err(err).
 												Str("channelID", peer.ChannelID).
@@ -369,35 +576,62 @@ func (a *NamedAdapter) Open() (chan string, error) {
 									}
 								}
 
-								if a.config.IsIDClaimed(gng.IDs, id) {
+								// The previous ID is still ours while renaming, so it is defended until the new ID has been claimed
+								candidatesLock.Lock()
+								defended := []string{id, previousID}
+								candidatesLock.Unlock()
+
+								for _, held := range defended {
+									if held == "" || !a.config.IsIDClaimed(gng.IDs, held) {
+										continue
+									}
+
 									log.Debug().
 										Str("channelID", peer.ChannelID).
 										Str("peerID", rid).
-										Str("id", id).
+										Str("id", held).
 										Msg("Sending kick")
 
-									if err := e.Encode(v1.NewKick(id)); err != nil {
+									if err := conn.encode(v1.NewKick(held)); err != nil {
 										log.Debug().
 											Err(err).
 											Str("channelID", peer.ChannelID).
 											Str("peerID", rid).
-											Str("id", id).
+											Str("id", held).
 											Msg("Could not send backoff to peer, stopping")
 
 										return
 									}
 								}
-							case v1.TypeKick:
-								var kck v1.Kick
-								if err := mapstructure.Decode(j, &kck); err != nil {
+
+								// Kicks have been sent before, so the peer can stop waiting for us
+								if err := conn.encode(v1.NewAcknowledgement()); err != nil {
 									log.Debug().
 										Err(err).
 										Str("channelID", peer.ChannelID).
 										Str("peerID", rid).
-										Msg("Could not decode kick from peer, stopping")
+										Msg("Could not write acknowledgement to peer, stopping")
 
-									continue
+									return
+								}
+							case *v1.Acknowledgement:
+								log.Debug().
+									Str("channelID", peer.ChannelID).
+									Str("peerID", rid).
+									Str("codec", codec).
+									Msg("Received acknowledgement")
+
+								candidatesLock.Lock()
+								if id == "" && acks.add(peer.PeerID) {
+									log.Debug().
+										Int("acks", len(acks.peers)).
+										Msg("All peers have acknowledged greeting, claiming early")
+
+									ready.Reset(0)
 								}
+								candidatesLock.Unlock()
+							case *v1.Kick:
+								kck := message
 
 								log.Debug().
 									Err(err).
@@ -409,31 +643,32 @@ func (a *NamedAdapter) Open() (chan string, error) {
 								candidatesLock.Lock()
 								delete(candidates, kck.ID)
 								candidatesLock.Unlock()
-							case v1.TypeBackoff:
+							case *v1.Backoff:
 								log.Debug().
 									Err(err).
 									Str("channelID", peer.ChannelID).
 									Str("peerID", rid).
 									Msg("Received backoff")
 
+								// Peers which have already claimed an ID keep it; conflicts with them are resolved once the other peer has claimed
+								if id != "" {
+									continue
+								}
+
 								ready.Stop()
 
+								// Acknowledgements of the previous greeting don't cover the next one
+								candidatesLock.Lock()
+								acks.reset()
+								candidatesLock.Unlock()
+
 								time.Sleep(a.config.Kicks)
 
 								greet()
 
 								ready.Reset(a.config.Kicks)
-							case v1.TypeClaimed:
-								var clm v1.Claimed
-								if err := mapstructure.Decode(j, &clm); err != nil {
-									log.Debug().
-										Err(err).
-										Str("channelID", peer.ChannelID).
-										Str("peerID", rid).
-										Msg("Could not decode claimed from peer, stopping")
-
-									continue
-								}
+							case *v1.Claimed:
+								clm := message
 
 								log.Debug().
 									Err(err).
@@ -442,8 +677,116 @@ func (a *NamedAdapter) Open() (chan string, error) {
 									Str("id", clm.ID).
 									Msg("Received kick")
 
+								if err := a.verifyClaimed(community, clm, peer.PublicKey); err != nil {
+									log.Debug().
+										Err(err).
+										Str("channelID", peer.ChannelID).
+										Str("peerID", rid).
+										Str("id", clm.ID).
+										Msg("Could not verify identity of claimed from peer, disconnecting")
+
+									if err := peer.Conn.Close(); err != nil {
+										log.Debug().
+											Err(err).
+											Str("channelID", peer.ChannelID).
+											Str("peerID", rid).
+											Msg("Could not close connection to peer")
+									}
+
+									return
+								}
+
+								// The peer has claimed our ID, i.e. because it was in another network partition when we claimed it
+								if id != "" && a.config.IsIDClaimed(map[string]struct{}{clm.ID: {}}, id) {
+									if rank < getRank(peer.PeerID) {
+										log.Debug().
+											Str("channelID", peer.ChannelID).
+											Str("peerID", rid).
+											Str("id", clm.ID).
+											Msg("Peer has claimed our ID, but we won the tie-break; telling it to re-claim")
+
+										// The peer might not have claimed its ID yet when it received our claim, so re-send it
+										if err := conn.encode(a.newClaimed(community, id)); err != nil {
+											log.Debug().
+												Err(err).
+												Str("channelID", peer.ChannelID).
+												Str("peerID", rid).
+												Msg("Could not write claimed to peer, stopping")
+
+											return
+										}
+
+										continue
+									}
+
+									log.Debug().
+										Str("channelID", peer.ChannelID).
+										Str("peerID", rid).
+										Str("id", clm.ID).
+										Msg("Peer has claimed our ID and won the tie-break, re-claiming")
+
+									if a.config.OnConflict != nil {
+										a.config.OnConflict(id)
+									}
+
+									a.adapter.reconnect()
+
+									return
+								}
+
+								// The peer has claimed our previous ID while we are renaming; it stays ours unless the peer wins the tie-break
+								candidatesLock.Lock()
+								if previousID != "" && a.config.IsIDClaimed(map[string]struct{}{clm.ID: {}}, previousID) {
+									if rank < getRank(peer.PeerID) {
+										held := previousID
+										candidatesLock.Unlock()
+
+										log.Debug().
+											Str("channelID", peer.ChannelID).
+											Str("peerID", rid).
+											Str("id", clm.ID).
+											Msg("Peer has claimed our previous ID, but we won the tie-break; telling it to re-claim")
+
+										if err := conn.encode(a.newClaimed(community, held)); err != nil {
+											log.Debug().
+												Err(err).
+												Str("channelID", peer.ChannelID).
+												Str("peerID", rid).
+												Msg("Could not write claimed to peer, stopping")
+
+											return
+										}
+
+										continue
+									}
+
+									log.Debug().
+										Str("channelID", peer.ChannelID).
+										Str("peerID", rid).
+										Str("id", clm.ID).
+										Msg("Peer has claimed our previous ID and won the tie-break, releasing it")
+
+									if a.config.OnConflict != nil {
+										a.config.OnConflict(previousID)
+									}
+
+									previousID = ""
+								}
+								candidatesLock.Unlock()
+
+								previous := rid
+								if previous != peer.PeerID && previous != clm.ID {
+									a.directory.remove(previous, peer.PeerID)
+								}
+
 								rid = clm.ID
 
+								a.directory.set(DirectoryEntry{
+									Name:      clm.ID,
+									PeerID:    peer.PeerID,
+									PublicKey: peer.PublicKey,
+								})
+
 								if _, ok := peers[rid]; !ok {
 									log.Debug().
 										Err(err).
@@ -457,24 +800,42 @@ func (a *NamedAdapter) Open() (chan string, error) {
 								if _, ok := peers[rid]; !ok {
 									peers[rid] = map[string]*Peer{}
 								}
-								for key, value := range peers[peer.PeerID] {
+								for key, value := range peers[previous] {
 									peers[rid][key] = value
 
-									if value.ChannelID != a.config.IDChannel {
-										namedPeers <- &Peer{
+									// Connections of renamed peers have already been accepted with their previous ID
+									if value.ChannelID != a.config.IDChannel && previous == peer.PeerID {
+										// Don't block while holding the lock, since the receiving loop might be waiting for it
+										go func(p *Peer) {
+											namedPeers <- p
+										}(&Peer{
 											PeerID:    rid,
 											ChannelID: value.ChannelID,
 											Conn:      value.Conn,
-										}
+											PublicKey: value.PublicKey,
+										})
 									}
 								}
-								delete(peers, peer.PeerID)
+								if previous != rid {
+									delete(peers, previous)
+								}
 								peersLock.Unlock()
+
+								if previous != peer.PeerID && previous != rid {
+									log.Debug().
+										Str("channelID", peer.ChannelID).
+										Str("peerID", rid).
+										Str("previousID", previous).
+										Msg("Peer has renamed itself")
+
+									if a.config.OnRename != nil {
+										a.config.OnRename(previous, rid)
+									}
+								}
 							default:
 								log.Debug().
 									Str("channelID", peer.ChannelID).
 									Str("peerID", rid).
-									Str("type", message.Type).
 									Msg("Got message with unknown type from peer, continuing")
 
 								continue
@@ -489,6 +850,33 @@ func (a *NamedAdapter) Open() (chan string, error) {
 	return a.names, nil
 }
 
+// Rename claims one of the candidates as the new ID on the existing connections, which stay open.
+// The new ID will be sent to the channel returned by Open; the previous ID is defended until then and kept if all candidates have already been claimed.
+// Failed renames are only reported through the returned error; if the previous ID has been lost while renaming, the adapter reconnects and claims one of its original names.
+func (a *NamedAdapter) Rename(candidates []string) error {
+	if len(candidates) <= 0 {
+		return ErrMissingNames
+	}
+
+	r := rename{
+		candidates: candidates,
+		result:     make(chan error, 1),
+	}
+
+	select {
+	case <-a.ctx.Done():
+		return a.ctx.Err()
+	case a.renames <- r:
+	}
+
+	select {
+	case <-a.ctx.Done():
+		return a.ctx.Err()
+	case err := <-r.result:
+		return err
+	}
+}
+
 // Close disconnects the adapter from the signaler
 func (a *NamedAdapter) Close() error {
 	log.Trace().Msg("Closing adapter")
@@ -505,3 +893,13 @@ func (a *NamedAdapter) Err() chan error {
 func (a *NamedAdapter) Accept() chan *Peer {
 	return a.acceptedPeers
 }
+
+// KeyUsage returns when each key of the keyring has last been used to decrypt signaling messages
+func (a *NamedAdapter) KeyUsage() ([]KeyUsage, error) {
+	return a.adapter.KeyUsage()
+}
+
+// CompressionStats returns the compression statistics for each compressed channel
+func (a *NamedAdapter) CompressionStats() map[string]CompressionStats {
+	return a.adapter.CompressionStats()
+}
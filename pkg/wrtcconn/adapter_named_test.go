@@ -0,0 +1,58 @@
+package wrtcconn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcknowledgements(t *testing.T) {
+	tests := []struct {
+		name       string
+		expected   int
+		acks       []string
+		resetAfter int // Amount of acknowledgements after which the peer backs off, which requires a new greeting (0 doesn't reset)
+		wantEarly  bool
+	}{
+		{"all peers acknowledged", 2, []string{"alice", "bob"}, 0, true},
+		{"peer has not acknowledged yet", 2, []string{"alice"}, 0, false},
+		{"duplicate acknowledgement", 2, []string{"alice", "alice"}, 0, false},
+		{"acknowledgement of previous greeting", 2, []string{"alice", "bob"}, 1, false},
+		{"all peers acknowledged next greeting", 2, []string{"alice", "alice", "bob"}, 1, true},
+		{"unknown amount of peers", -2, []string{"alice", "bob"}, 0, false},
+		{"no other peers", 0, []string{"alice"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Names are claimed once the kicks timeout fires, which is moved up once all peers have acknowledged
+			ready := time.NewTimer(time.Hour)
+			defer ready.Stop()
+
+			acks := newAcknowledgements(tt.expected)
+			for i, peerID := range tt.acks {
+				if tt.resetAfter > 0 && i == tt.resetAfter {
+					acks.reset()
+				}
+
+				if acks.add(peerID) {
+					if i != len(tt.acks)-1 {
+						t.Fatalf("add() claimed early after %v of %v acknowledgements", i+1, len(tt.acks))
+					}
+
+					ready.Reset(0)
+				}
+			}
+
+			select {
+			case <-ready.C:
+				if !tt.wantEarly {
+					t.Fatal("claimed before the timeout, want waiting for it")
+				}
+			case <-time.After(time.Millisecond * 100):
+				if tt.wantEarly {
+					t.Fatal("waiting for the timeout, want claiming early")
+				}
+			}
+		})
+	}
+}
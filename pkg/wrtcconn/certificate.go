@@ -0,0 +1,135 @@
+package wrtcconn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	certificateCommonName = "weron"
+	certificateValidity   = time.Hour * 24 * 365 * 10
+
+	fingerprintAlgorithm = "sha-256"
+)
+
+var (
+	ErrMissingFingerprint  = errors.New("peer did not sign a certificate fingerprint")                          // The peer has an identity, but did not sign its certificate fingerprint
+	ErrFingerprintMismatch = errors.New("negotiated certificate does not match the signed fingerprint")         // The certificate used in the DTLS handshake is not the one the peer signed
+	ErrMissingFingerprints = errors.New("certificate does not have a " + fingerprintAlgorithm + " fingerprint") // The certificate could not be fingerprinted
+)
+
+// GenerateCertificate creates a DTLS certificate which can be used for all of an adapter's peer connections
+func GenerateCertificate() (*webrtc.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	return webrtc.NewCertificate(key, x509.Certificate{
+		Issuer:       pkix.Name{CommonName: certificateCommonName},
+		Subject:      pkix.Name{CommonName: certificateCommonName},
+		NotBefore:    time.Now().Add(-time.Hour * 24),
+		NotAfter:     time.Now().Add(certificateValidity),
+		SerialNumber: serialNumber,
+		Version:      2,
+	})
+}
+
+// LoadOrCreateCertificate reads a PEM-encoded DTLS certificate from a file, or generates and writes one if it doesn't exist yet
+func LoadOrCreateCertificate(path string) (*webrtc.Certificate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		certificate, err := GenerateCertificate()
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := certificate.PEM()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(path, []byte(p), 0600); err != nil {
+			return nil, err
+		}
+
+		return certificate, nil
+	}
+
+	return webrtc.CertificateFromPEM(string(content))
+}
+
+func getCertificateFingerprint(certificate *webrtc.Certificate) (string, error) {
+	fingerprints, err := certificate.GetFingerprints()
+	if err != nil {
+		return "", err
+	}
+
+	for _, fingerprint := range fingerprints {
+		if fingerprint.Algorithm == fingerprintAlgorithm {
+			return fingerprint.Value, nil
+		}
+	}
+
+	return "", ErrMissingFingerprints
+}
+
+func getFingerprint(der []byte) string {
+	hash := sha256.Sum256(der)
+
+	parts := make([]string, len(hash))
+	for i, b := range hash {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// verifyCertificate checks that the certificate negotiated with a peer is the one whose fingerprint the peer has signed
+func verifyCertificate(conn *webrtc.PeerConnection, knownPeers KnownPeers, peerID string, publicKey ed25519.PublicKey, fingerprint string, required bool) error {
+	return verifyFingerprint(conn.SCTP().Transport().GetRemoteCertificate(), knownPeers, peerID, publicKey, fingerprint, required)
+}
+
+func verifyFingerprint(remoteCertificate []byte, knownPeers KnownPeers, peerID string, publicKey ed25519.PublicKey, fingerprint string, required bool) error {
+	// Peers without an identity can't sign their fingerprint, which is only acceptable if they don't need an identity
+	if publicKey == nil {
+		return checkMissingIdentity(knownPeers, peerID, required)
+	}
+
+	if fingerprint == "" {
+		return ErrMissingFingerprint
+	}
+
+	if !strings.EqualFold(getFingerprint(remoteCertificate), fingerprint) {
+		return ErrFingerprintMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package wrtcconn
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyFingerprint(t *testing.T) {
+	pinned, _ := newTestIdentity(t)
+
+	certificate := []byte("remote certificate")
+	fingerprint := getFingerprint(certificate)
+
+	tests := []struct {
+		name        string
+		known       map[string]ed25519.PublicKey
+		publicKey   ed25519.PublicKey
+		fingerprint string
+		required    bool
+		want        error
+	}{
+		{"signed fingerprint", nil, pinned, fingerprint, false, nil},
+		{"signed fingerprint in other case", nil, pinned, strings.ToUpper(fingerprint), false, nil},
+		{"substituted certificate", nil, pinned, getFingerprint([]byte("other certificate")), false, ErrFingerprintMismatch},
+		{"missing fingerprint", nil, pinned, "", false, ErrMissingFingerprint},
+		{"stripped identity of pinned peer", map[string]ed25519.PublicKey{"alice": pinned}, nil, "", false, ErrMissingIdentity},
+		{"stripped identity with required identities", nil, nil, "", true, ErrMissingIdentity},
+		{"peer without identity", map[string]ed25519.PublicKey{"bob": pinned}, nil, "", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyFingerprint(certificate, newTestKnownPeers(t, tt.known), "alice", tt.publicKey, tt.fingerprint, tt.required); !errors.Is(err, tt.want) {
+				t.Fatalf("verifyFingerprint() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
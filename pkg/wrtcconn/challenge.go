@@ -0,0 +1,253 @@
+package wrtcconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
+	"github.com/pojntfx/weron/internal/encryption"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	queryPassword = "password"
+	maxChallenges = 3
+)
+
+var (
+	errUnexpectedChallengeMessage = errors.New("unexpected challenge message")
+	errInvalidServerSignature     = errors.New("signaler could not prove that it knows the password")
+	errChallengeNotSelected       = errors.New("signaler did not select the challenge subprotocol")
+	errTooManyChallenges          = errors.New("signaler has sent too many challenges")
+	errPasswordRequested          = errors.New("signaler requested the password, which is only sent with legacy authentication")
+	errCreationRequested          = errors.New("signaler requested to create the community, which is disabled")
+)
+
+// getRedactedURL removes the password from the signaler URL so that it can be logged and sent to signalers which support challenges
+func getRedactedURL(u *url.URL) *url.URL {
+	ru := *u
+	q := ru.Query()
+	q.Del(queryPassword)
+	ru.RawQuery = q.Encode()
+
+	return &ru
+}
+
+// getSignalerHeader returns the headers with which the adapter connects to the signaler
+func (a *Adapter) getSignalerHeader() (http.Header, error) {
+	if a.config.IDToken == nil {
+		return nil, nil
+	}
+
+	token, err := a.config.IDToken()
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+
+	return header, nil
+}
+
+// dialSignaler connects to the signaler and joins the community; the password is only sent in the URL to signalers which don't support challenges if the legacy authentication is enabled
+func (a *Adapter) dialSignaler(ctx context.Context, dialer websocket.Dialer, u *url.URL, community string) (*websocket.Conn, int, bool, error) {
+	header, err := a.getSignalerHeader()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	// The challenge is requested on every connection, so that a signaler which has failed once isn't treated as a legacy signaler forever
+	challengeDialer := dialer
+	challengeDialer.Subprotocols = []string{websocketapi.SubprotocolChallenge}
+
+	conn, res, err := challengeDialer.DialContext(ctx, getRedactedURL(u).String(), header)
+	if err == nil {
+		accepted, err := a.answerChallenge(conn, community, u.Query().Get(queryPassword))
+		if err != nil {
+			_ = conn.Close()
+
+			return nil, 0, false, err
+		}
+
+		return conn, accepted.Clients, accepted.Routing, nil
+	}
+
+	if !a.config.LegacyAuthentication || !isLegacySignaler(res) {
+		return nil, 0, false, withStatus(err, res)
+	}
+
+	log.Warn().Str("address", getRedactedURL(u).String()).Msg("Signaler does not support challenges, falling back to sending the password in the URL")
+
+	conn, res, err = dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		return nil, 0, false, withStatus(err, res)
+	}
+
+	// Signalers which don't report the amount of clients in the community have an unknown amount of clients
+	clients, err := strconv.Atoi(res.Header.Get(websocketapi.HeaderClients))
+	if err != nil {
+		clients = -1
+	}
+
+	return conn, clients, res.Header.Get(websocketapi.HeaderRouting) != "", nil
+}
+
+// isLegacySignaler returns whether a signaler has rejected a client which has requested a challenge like signalers which don't support challenges do, that is with an internal error; signalers which support challenges set the challenge header even if they reject the client with an internal error
+func isLegacySignaler(res *http.Response) bool {
+	return res != nil &&
+		res.StatusCode == http.StatusInternalServerError &&
+		res.Header.Get(websocketapi.HeaderChallenge) == ""
+}
+
+// withStatus adds the status with which the signaler has rejected the client to a dial error, i.e. because the community is full
+func withStatus(err error, res *http.Response) error {
+	if res == nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", err, res.Status)
+}
+
+// answerChallenge proves that the client knows the password of the community; the signaler challenges the client again if the community has been created or its password has been changed in the meantime
+func (a *Adapter) answerChallenge(conn *websocket.Conn, community string, password string) (*websocketapi.Accepted, error) {
+	if conn.Subprotocol() != websocketapi.SubprotocolChallenge {
+		return nil, errChallengeNotSelected
+	}
+
+	messageType, p, err := a.readChallengeMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxChallenges; i++ {
+		if messageType != websocketapi.TypeChallenge {
+			return nil, errUnexpectedChallengeMessage
+		}
+
+		var challenge websocketapi.Challenge
+		if err := json.Unmarshal(p, &challenge); err != nil {
+			return nil, err
+		}
+
+		authMessage := encryption.GetAuthMessage(community, challenge.Nonce)
+
+		response, serverKeys, err := getChallengeResponse(&challenge, password, authMessage, a.config.LegacyAuthentication, a.config.CreateCommunity)
+		if err != nil {
+			return nil, err
+		}
+
+		rp, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(a.config.Timeout)); err != nil {
+			return nil, err
+		}
+
+		if err := conn.WriteMessage(websocket.TextMessage, rp); err != nil {
+			return nil, err
+		}
+
+		messageType, p, err = a.readChallengeMessage(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		if messageType == websocketapi.TypeChallenge {
+			continue
+		}
+
+		if messageType != websocketapi.TypeAccepted {
+			return nil, errUnexpectedChallengeMessage
+		}
+
+		var accepted websocketapi.Accepted
+		if err := json.Unmarshal(p, &accepted); err != nil {
+			return nil, err
+		}
+
+		// Only signalers which know the verifier can sign the challenge, which prevents impersonation of the signaler
+		if len(serverKeys) > 0 &&
+			(accepted.Verifier < 0 ||
+				accepted.Verifier >= len(serverKeys) ||
+				!encryption.VerifyServerSignature(serverKeys[accepted.Verifier], authMessage, accepted.Signature)) {
+			return nil, errInvalidServerSignature
+		}
+
+		return &accepted, nil
+	}
+
+	return nil, errTooManyChallenges
+}
+
+// getChallengeResponse answers a challenge with the password; it also returns the server keys with which the signaler's signature can be checked. Only challenges with verifiers prove that the signaler knows the password, so the password itself is only sent with legacy authentication, and a verifier for it only if creating the community is allowed.
+func getChallengeResponse(challenge *websocketapi.Challenge, password string, authMessage []byte, legacy bool, create bool) (interface{}, [][]byte, error) {
+	switch {
+	case challenge.Create:
+		if !create {
+			return nil, nil, errCreationRequested
+		}
+
+		verifier, err := encryption.NewVerifier(password)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Signalers which predate verifiers and share the database with the signaler can only check the password against its bcrypt hash
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return websocketapi.NewVerifier(*verifier, string(hashedPassword)), nil, nil
+	case len(challenge.Verifiers) == 0:
+		// Communities which have been created before verifiers were introduced still need the password, but it is sent over the connection instead of in the URL
+		if !legacy {
+			return nil, nil, errPasswordRequested
+		}
+
+		return websocketapi.NewPassword(password), nil, nil
+	default:
+		proofs := [][]byte{}
+		serverKeys := [][]byte{}
+		for _, verifier := range challenge.Verifiers {
+			proof, serverKey, err := encryption.GetProof(password, verifier.Salt, verifier.Iterations, authMessage)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			proofs = append(proofs, proof)
+			serverKeys = append(serverKeys, serverKey)
+		}
+
+		return websocketapi.NewProof(proofs), serverKeys, nil
+	}
+}
+
+// readChallengeMessage reads a message of the challenge and returns its type; rejections are returned as *websocket.CloseError
+func (a *Adapter) readChallengeMessage(conn *websocket.Conn) (string, []byte, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(a.config.Timeout)); err != nil {
+		return "", nil, err
+	}
+
+	_, p, err := conn.ReadMessage()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var message websocketapi.Message
+	if err := json.Unmarshal(p, &message); err != nil {
+		return "", nil, err
+	}
+
+	return message.Type, p, nil
+}
@@ -0,0 +1,237 @@
+package wrtcconn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
+	"github.com/pojntfx/weron/internal/encryption"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestSignalerURL(t *testing.T, handler http.HandlerFunc) *url.URL {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse("ws" + strings.TrimPrefix(srv.URL, "http") + "?community=mycommunity&password=mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func TestDialSignaler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		status               int
+		challengeHeader      bool
+		legacyAuthentication bool
+		wantErr              bool
+		wantLegacy           bool
+	}{
+		{"signaler with challenges fails", http.StatusInternalServerError, true, true, true, false},
+		{"legacy signaler without legacy authentication", http.StatusInternalServerError, false, false, true, false},
+		{"legacy signaler with legacy authentication", http.StatusInternalServerError, false, true, false, true},
+		{"legacy signaler rejects the client", http.StatusUnauthorized, false, true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var challenges, legacy atomic.Int64
+			u := newTestSignalerURL(t, func(rw http.ResponseWriter, r *http.Request) {
+				if slices.Contains(websocket.Subprotocols(r), websocketapi.SubprotocolChallenge) {
+					challenges.Add(1)
+
+					if tt.challengeHeader {
+						rw.Header().Set(websocketapi.HeaderChallenge, websocketapi.SubprotocolChallenge)
+					}
+
+					rw.WriteHeader(tt.status)
+
+					return
+				}
+
+				legacy.Add(1)
+
+				if r.URL.Query().Get(queryPassword) != "mypassword" {
+					rw.WriteHeader(http.StatusUnauthorized)
+
+					return
+				}
+
+				conn, err := (&websocket.Upgrader{}).Upgrade(rw, r, http.Header{
+					websocketapi.HeaderClients: []string{"3"},
+				})
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				_, _, _ = conn.ReadMessage()
+			})
+
+			a := &Adapter{
+				config: &AdapterConfig{
+					Timeout:              time.Second * 5,
+					LegacyAuthentication: tt.legacyAuthentication,
+				},
+			}
+
+			// The signaler must be asked for a challenge on every connection, even if it has been detected as a legacy signaler before
+			for i := int64(1); i <= 2; i++ {
+				conn, clients, _, err := a.dialSignaler(context.Background(), websocket.Dialer{}, u, "mycommunity")
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("dialSignaler() error = %v, want error %v", err, tt.wantErr)
+				}
+
+				if err == nil {
+					_ = conn.Close()
+
+					if clients != 3 {
+						t.Fatalf("dialSignaler() clients = %v, want 3", clients)
+					}
+				}
+
+				if challenges.Load() != i {
+					t.Fatalf("signaler has been asked for %v challenges, want %v", challenges.Load(), i)
+				}
+
+				if got := legacy.Load() == i; got != tt.wantLegacy {
+					t.Fatalf("client sent the password in the URL: %v, want %v", got, tt.wantLegacy)
+				}
+			}
+		})
+	}
+}
+
+func TestAnswerChallengeAfterConcurrentCreation(t *testing.T) {
+	verifier, err := encryption.NewVerifier("mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverErrs := make(chan error, 1)
+	u := newTestSignalerURL(t, func(rw http.ResponseWriter, r *http.Request) {
+		serverErrs <- func() error {
+			conn, err := (&websocket.Upgrader{}).Upgrade(rw, r, http.Header{
+				"Sec-Websocket-Protocol": []string{websocketapi.SubprotocolChallenge},
+			})
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			if err := conn.WriteJSON(websocketapi.NewChallenge([]byte("first"), nil, true)); err != nil {
+				return err
+			}
+
+			var created websocketapi.Verifier
+			if err := conn.ReadJSON(&created); err != nil {
+				return err
+			}
+
+			// Signalers which predate verifiers can only check the password with its hash
+			if err := bcrypt.CompareHashAndPassword([]byte(created.PasswordHash), []byte("mypassword")); err != nil {
+				return err
+			}
+
+			// Another client has created the community in the meantime, so the client has to prove that it knows its password
+			nonce := []byte("second")
+			if err := conn.WriteJSON(websocketapi.NewChallenge(nonce, []websocketapi.VerifierParameters{{Salt: verifier.Salt, Iterations: verifier.Iterations}}, false)); err != nil {
+				return err
+			}
+
+			var proof websocketapi.Proof
+			if err := conn.ReadJSON(&proof); err != nil {
+				return err
+			}
+
+			authMessage := encryption.GetAuthMessage("mycommunity", nonce)
+			if len(proof.Proofs) != 1 || !verifier.VerifyProof(authMessage, proof.Proofs[0]) {
+				return errInvalidServerSignature
+			}
+
+			return conn.WriteJSON(websocketapi.NewAccepted(2, true, 0, encryption.GetServerSignature(verifier.ServerKey, authMessage)))
+		}()
+	})
+
+	a := &Adapter{
+		config: &AdapterConfig{
+			Timeout:         time.Second * 5,
+			CreateCommunity: true,
+		},
+	}
+
+	conn, clients, _, err := a.dialSignaler(context.Background(), websocket.Dialer{}, u, "mycommunity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := <-serverErrs; err != nil {
+		t.Fatal(err)
+	}
+
+	if clients != 2 {
+		t.Fatalf("dialSignaler() clients = %v, want 2", clients)
+	}
+}
+
+func TestGetChallengeResponse(t *testing.T) {
+	verifier, err := encryption.NewVerifier("mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		challenge websocketapi.Challenge
+		legacy    bool
+		create    bool
+		wantType  string
+		wantErr   error
+	}{
+		{"proof", websocketapi.Challenge{Verifiers: []websocketapi.VerifierParameters{{Salt: verifier.Salt, Iterations: verifier.Iterations}}}, false, false, websocketapi.TypeProof, nil},
+		{"password without legacy authentication", websocketapi.Challenge{}, false, true, "", errPasswordRequested},
+		{"password with legacy authentication", websocketapi.Challenge{}, true, false, websocketapi.TypePassword, nil},
+		{"creation without creating communities", websocketapi.Challenge{Create: true}, true, false, "", errCreationRequested},
+		{"creation", websocketapi.Challenge{Create: true}, false, true, websocketapi.TypeVerifier, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Signalers which don't know the password must not be able to make the client reveal it
+			response, _, err := getChallengeResponse(&tt.challenge, "mypassword", encryption.GetAuthMessage("mycommunity", []byte("nonce")), tt.legacy, tt.create)
+			if err != tt.wantErr {
+				t.Fatalf("getChallengeResponse() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			p, err := json.Marshal(response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var message websocketapi.Message
+			if err := json.Unmarshal(p, &message); err != nil {
+				t.Fatal(err)
+			}
+
+			if message.Type != tt.wantType {
+				t.Fatalf("getChallengeResponse() type = %v, want %v", message.Type, tt.wantType)
+			}
+		})
+	}
+}
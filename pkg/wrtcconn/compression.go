@@ -0,0 +1,265 @@
+package wrtcconn
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionNone   = ""       // Send payloads as-is
+	CompressionZstd   = "zstd"   // Compress payloads with Zstandard
+	CompressionSnappy = "snappy" // Compress payloads with Snappy
+
+	compressionProtocolPrefix = "weron/compression/" // Prefix of the data channel protocol which declares the compression algorithm
+
+	maxMessageSize = 65536              // Maximum size of a frame on the wire (the default SCTP message size limit)
+	maxPayloadSize = maxMessageSize - 1 // Maximum size of a payload, which leaves space for the frame header
+
+	frameRaw        = byte(0) // Frame contains an uncompressed payload
+	frameCompressed = byte(1) // Frame contains a compressed payload
+)
+
+var (
+	ErrUnsupportedCompression = errors.New("unsupported compression algorithm") // The specified compression algorithm is not supported
+	ErrInvalidCompressedFrame = errors.New("invalid compressed frame")          // The received frame could not be decompressed
+	ErrPayloadTooLarge        = errors.New("payload too large")                 // The payload does not fit into a single frame
+)
+
+// CompressionStats are statistics on payload compression for a channel
+type CompressionStats struct {
+	UncompressedBytes int64 // Count of payload bytes written before compression
+	CompressedBytes   int64 // Count of payload bytes written to the wire, including uncompressed payloads
+	Compressed        int64 // Count of payloads which were written compressed
+	Skipped           int64 // Count of payloads which were written uncompressed because they did not shrink
+}
+
+// Ratio returns the ratio of bytes on the wire to bytes before compression
+func (s CompressionStats) Ratio() float64 {
+	if s.UncompressedBytes <= 0 {
+		return 1
+	}
+
+	return float64(s.CompressedBytes) / float64(s.UncompressedBytes)
+}
+
+type compressionCounters struct {
+	uncompressedBytes atomic.Int64
+	compressedBytes   atomic.Int64
+	compressed        atomic.Int64
+	skipped           atomic.Int64
+}
+
+func (c *compressionCounters) stats() CompressionStats {
+	return CompressionStats{
+		UncompressedBytes: c.uncompressedBytes.Load(),
+		CompressedBytes:   c.compressedBytes.Load(),
+		Compressed:        c.compressed.Load(),
+		Skipped:           c.skipped.Load(),
+	}
+}
+
+type codec interface {
+	encode(dst, src []byte) []byte
+	decode(src []byte) ([]byte, error)
+}
+
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCodec() (*zstdCodec, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdCodec{encoder, decoder}, nil
+}
+
+func (c *zstdCodec) encode(dst, src []byte) []byte {
+	return c.encoder.EncodeAll(src, dst)
+}
+
+func (c *zstdCodec) decode(src []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(src, nil)
+}
+
+type snappyCodec struct{}
+
+func (c *snappyCodec) encode(dst, src []byte) []byte {
+	return append(dst, snappy.Encode(nil, src)...)
+}
+
+func (c *snappyCodec) decode(src []byte) ([]byte, error) {
+	n, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > maxMessageSize {
+		return nil, ErrInvalidCompressedFrame
+	}
+
+	return snappy.Decode(nil, src)
+}
+
+// codecs lazily creates and caches the codecs for an adapter
+type codecs struct {
+	lock   sync.Mutex
+	codecs map[string]codec
+}
+
+func (c *codecs) get(compression string) (codec, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.codecs == nil {
+		c.codecs = map[string]codec{}
+	}
+
+	if cd, ok := c.codecs[compression]; ok {
+		return cd, nil
+	}
+
+	var cd codec
+	switch compression {
+	case CompressionZstd:
+		z, err := newZstdCodec()
+		if err != nil {
+			return nil, err
+		}
+
+		cd = z
+	case CompressionSnappy:
+		cd = &snappyCodec{}
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+
+	c.codecs[compression] = cd
+
+	return cd, nil
+}
+
+func validateCompression(compression string) error {
+	switch compression {
+	case CompressionNone, CompressionZstd, CompressionSnappy:
+		return nil
+	default:
+		return ErrUnsupportedCompression
+	}
+}
+
+func getCompressionProtocol(compression string) string {
+	if compression == CompressionNone {
+		return ""
+	}
+
+	return compressionProtocolPrefix + compression
+}
+
+func parseCompressionProtocol(protocol string) (string, error) {
+	if !strings.HasPrefix(protocol, compressionProtocolPrefix) {
+		return CompressionNone, nil
+	}
+
+	compression := strings.TrimPrefix(protocol, compressionProtocolPrefix)
+
+	return compression, validateCompression(compression)
+}
+
+// compressedConn compresses each written message and decompresses each read message
+type compressedConn struct {
+	io.ReadWriteCloser
+	codec    codec
+	counters *compressionCounters
+
+	readLock sync.Mutex
+	buf      []byte
+}
+
+func newCompressedConn(conn io.ReadWriteCloser, cd codec, counters *compressionCounters) *compressedConn {
+	return &compressedConn{
+		ReadWriteCloser: conn,
+		codec:           cd,
+		counters:        counters,
+
+		buf: make([]byte, maxMessageSize+1),
+	}
+}
+
+func (c *compressedConn) Write(p []byte) (int, error) {
+	if len(p) > maxPayloadSize {
+		return 0, ErrPayloadTooLarge
+	}
+
+	frame := c.codec.encode([]byte{frameCompressed}, p)
+
+	// Skip payloads that don't shrink
+	if len(frame)-1 >= len(p) {
+		frame = append(append(frame[:0], frameRaw), p...)
+
+		c.counters.skipped.Add(1)
+	} else {
+		c.counters.compressed.Add(1)
+	}
+
+	if _, err := c.ReadWriteCloser.Write(frame); err != nil {
+		return 0, err
+	}
+
+	c.counters.uncompressedBytes.Add(int64(len(p)))
+	c.counters.compressedBytes.Add(int64(len(frame) - 1))
+
+	return len(p), nil
+}
+
+// Read reads exactly one message; like the underlying data channel, it returns io.ErrShortBuffer and drops the message if it doesn't fit into p
+func (c *compressedConn) Read(p []byte) (int, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	n, err := c.ReadWriteCloser.Read(c.buf)
+	if err != nil {
+		return 0, err
+	}
+
+	if n <= 0 {
+		return 0, ErrInvalidCompressedFrame
+	}
+
+	var payload []byte
+	switch c.buf[0] {
+	case frameRaw:
+		payload = c.buf[1:n]
+	case frameCompressed:
+		payload, err = c.codec.decode(c.buf[1:n])
+		if err != nil {
+			return 0, err
+		}
+
+		if len(payload) > maxPayloadSize {
+			return 0, ErrInvalidCompressedFrame
+		}
+	default:
+		return 0, ErrInvalidCompressedFrame
+	}
+
+	if len(payload) > len(p) {
+		return 0, io.ErrShortBuffer
+	}
+
+	return copy(p, payload), nil
+}
@@ -0,0 +1,155 @@
+package wrtcconn
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// channelConn is an in-memory connection which preserves message boundaries like a data channel
+type channelConn struct {
+	messages chan []byte
+}
+
+func newChannelConn() *channelConn {
+	return &channelConn{make(chan []byte, 16)}
+}
+
+func (c *channelConn) Read(p []byte) (int, error) {
+	m, ok := <-c.messages
+	if !ok {
+		return 0, io.EOF
+	}
+
+	if len(m) > len(p) {
+		return 0, io.ErrShortBuffer
+	}
+
+	return copy(p, m), nil
+}
+
+func (c *channelConn) Write(p []byte) (int, error) {
+	c.messages <- append([]byte{}, p...)
+
+	return len(p), nil
+}
+
+func (c *channelConn) Close() error {
+	close(c.messages)
+
+	return nil
+}
+
+func TestCompressedConn(t *testing.T) {
+	compressible := bytes.Repeat([]byte("weron"), 1000)
+	incompressible := []byte{0x8f, 0x21, 0xd3, 0x07}
+
+	tests := []struct {
+		name        string
+		compression string
+		payload     []byte
+		readSize    int
+		wantFrame   byte
+		wantWrite   error
+		wantRead    error
+	}{
+		{"zstd compresses repetitive payloads", CompressionZstd, compressible, maxPayloadSize, frameCompressed, nil, nil},
+		{"snappy compresses repetitive payloads", CompressionSnappy, compressible, maxPayloadSize, frameCompressed, nil, nil},
+		{"zstd sends payloads which don't shrink raw", CompressionZstd, incompressible, maxPayloadSize, frameRaw, nil, nil},
+		{"snappy sends payloads which don't shrink raw", CompressionSnappy, incompressible, maxPayloadSize, frameRaw, nil, nil},
+		{"empty payloads", CompressionSnappy, []byte{}, maxPayloadSize, frameRaw, nil, nil},
+		{"largest payload fits into a frame", CompressionZstd, bytes.Repeat([]byte{1}, maxPayloadSize), maxPayloadSize, frameCompressed, nil, nil},
+		{"payloads larger than a frame are rejected", CompressionZstd, make([]byte, maxMessageSize), maxPayloadSize, 0, ErrPayloadTooLarge, nil},
+		{"messages larger than the read buffer are not split", CompressionZstd, compressible, len(compressible) - 1, frameCompressed, nil, io.ErrShortBuffer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd, err := (&codecs{}).get(tt.compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counters := &compressionCounters{}
+			raw := newChannelConn()
+			conn := newCompressedConn(raw, cd, counters)
+
+			if _, err := conn.Write(tt.payload); !errors.Is(err, tt.wantWrite) {
+				t.Fatalf("Write() error = %v, want %v", err, tt.wantWrite)
+			} else if err != nil {
+				return
+			}
+
+			frame := <-raw.messages
+			if len(frame) > maxMessageSize {
+				t.Fatalf("frame is %v bytes, want at most %v", len(frame), maxMessageSize)
+			}
+
+			if frame[0] != tt.wantFrame {
+				t.Fatalf("frame type = %v, want %v", frame[0], tt.wantFrame)
+			}
+			raw.messages <- frame
+
+			buf := make([]byte, tt.readSize)
+			n, err := conn.Read(buf)
+			if !errors.Is(err, tt.wantRead) {
+				t.Fatalf("Read() error = %v, want %v", err, tt.wantRead)
+			} else if err != nil {
+				return
+			}
+
+			if !bytes.Equal(buf[:n], tt.payload) {
+				t.Fatalf("Read() = %v bytes, want %v bytes", n, len(tt.payload))
+			}
+
+			if stats := counters.stats(); stats.UncompressedBytes != int64(len(tt.payload)) || stats.CompressedBytes != int64(len(frame)-1) {
+				t.Fatalf("stats = %+v, want %v uncompressed and %v compressed bytes", stats, len(tt.payload), len(frame)-1)
+			}
+		})
+	}
+}
+
+func TestCompressedConnRejectsInvalidFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"empty frame", []byte{}},
+		{"unknown frame type", []byte{2, 1, 2, 3}},
+		{"corrupt compressed payload", []byte{frameCompressed, 0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := newChannelConn()
+			conn := newCompressedConn(raw, &snappyCodec{}, &compressionCounters{})
+
+			raw.messages <- tt.frame
+
+			if _, err := conn.Read(make([]byte, maxPayloadSize)); err == nil {
+				t.Fatal("Read() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestCompressionStatsRatio(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats CompressionStats
+		want  float64
+	}{
+		{"no payloads", CompressionStats{}, 1},
+		{"halved", CompressionStats{UncompressedBytes: 100, CompressedBytes: 50}, 0.5},
+		{"skipped", CompressionStats{UncompressedBytes: 100, CompressedBytes: 100}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.Ratio(); got != tt.want {
+				t.Fatalf("Ratio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package wrtcconn
+
+import (
+	"crypto/ed25519"
+	"sort"
+	"sync"
+)
+
+// DirectoryEntry is a name which has been claimed by a connected peer
+type DirectoryEntry struct {
+	Name      string            // Name which has been claimed
+	PeerID    string            // Underlying ID of the peer which has claimed the name
+	PublicKey ed25519.PublicKey // Verified identity of the peer (nil if the peer has not presented an identity)
+}
+
+type directory struct {
+	lock    sync.Mutex
+	entries map[string]DirectoryEntry
+	changes chan struct{}
+}
+
+func newDirectory() *directory {
+	return &directory{
+		entries: map[string]DirectoryEntry{},
+		changes: make(chan struct{}, 1),
+	}
+}
+
+func (d *directory) notify() {
+	// Changes are coalesced so that slow consumers don't block name negotiation
+	select {
+	case d.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (d *directory) set(entry DirectoryEntry) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if old, ok := d.entries[entry.Name]; ok && old.PeerID == entry.PeerID {
+		return
+	}
+
+	d.entries[entry.Name] = entry
+
+	d.notify()
+}
+
+// remove removes a name if it is still claimed by the peer with the underlying ID
+func (d *directory) remove(name string, peerID string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if entry, ok := d.entries[name]; !ok || entry.PeerID != peerID {
+		return
+	}
+
+	delete(d.entries, name)
+
+	d.notify()
+}
+
+func (d *directory) lookup(name string) (DirectoryEntry, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	entry, ok := d.entries[name]
+
+	return entry, ok
+}
+
+func (d *directory) names() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	names := []string{}
+	for name := range d.entries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Lookup returns the directory entry of the connected peer which has claimed a name
+func (a *NamedAdapter) Lookup(name string) (DirectoryEntry, bool) {
+	return a.directory.lookup(name)
+}
+
+// Names returns the sorted names which have been claimed by connected peers (excluding our own name)
+func (a *NamedAdapter) Names() []string {
+	return a.directory.names()
+}
+
+// Changes returns a channel which receives a value when the directory has changed; changes are coalesced, so use Names and Lookup to get the current directory
+func (a *NamedAdapter) Changes() <-chan struct{} {
+	return a.directory.changes
+}
@@ -0,0 +1,379 @@
+package wrtcconn
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
+	v1 "github.com/pojntfx/weron/pkg/api/webrtc/v1"
+)
+
+const (
+	pemTypePrivateKey = "PRIVATE KEY"
+
+	signaturePrefix = "weron/identity/" // Prefix of all signed messages to prevent signatures from being reused in other protocols
+)
+
+var (
+	ErrMissingIdentity    = errors.New("peer did not present an identity")                    // The peer did not sign its messages, but identities are required
+	ErrInvalidSignature   = errors.New("invalid identity signature")                          // The peer's signature could not be verified
+	ErrIdentityMismatch   = errors.New("identity does not match the known identity for peer") // The peer presented a different identity than the one that is known for its ID
+	ErrUntrustedIdentity  = errors.New("identity has not been trusted")                       // The peer presented an unknown identity which has not been trusted
+	ErrInvalidIdentityKey = errors.New("invalid identity key")                                // The identity key file does not contain an Ed25519 private key
+)
+
+// KnownPeers stores the identities of peers which have been trusted
+type KnownPeers interface {
+	Get(peerID string) (ed25519.PublicKey, bool, error) // Get returns the known identity for a peer ID
+	Add(peerID string, key ed25519.PublicKey) error     // Add trusts an identity for a peer ID
+}
+
+// scopedKnownPeers stores the identities of peers in a community on a signaler; peers in different communities can use the same IDs, so their identities must not be mixed up
+type scopedKnownPeers struct {
+	knownPeers KnownPeers
+	scope      string
+}
+
+// newScopedKnownPeers scopes the known peers store to the community on the signaler
+func newScopedKnownPeers(knownPeers KnownPeers, u *url.URL, community string) KnownPeers {
+	if knownPeers == nil {
+		return nil
+	}
+
+	// The query contains the password and the fragment is never sent, so they don't identify the signaler
+	raddr := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   u.Path,
+	}
+
+	return &scopedKnownPeers{
+		knownPeers: knownPeers,
+		scope:      strings.TrimSuffix(raddr.String(), "/") + "/" + url.PathEscape(community) + "/",
+	}
+}
+
+func (k *scopedKnownPeers) Get(peerID string) (ed25519.PublicKey, bool, error) {
+	return k.knownPeers.Get(k.scope + url.PathEscape(peerID))
+}
+
+func (k *scopedKnownPeers) Add(peerID string, key ed25519.PublicKey) error {
+	return k.knownPeers.Add(k.scope+url.PathEscape(peerID), key)
+}
+
+// FileKnownPeers is a known peers store backed by a JSON file; adapters key the identities by the signaler and community they have been trusted in
+type FileKnownPeers struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileKnownPeers creates the known peers store
+func NewFileKnownPeers(path string) *FileKnownPeers {
+	return &FileKnownPeers{
+		path: path,
+	}
+}
+
+func (k *FileKnownPeers) read() (map[string][]byte, error) {
+	peers := map[string][]byte{}
+
+	content, err := os.ReadFile(k.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return peers, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &peers); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+// Get returns the known identity for a peer ID
+func (k *FileKnownPeers) Get(peerID string) (ed25519.PublicKey, bool, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	peers, err := k.read()
+	if err != nil {
+		return nil, false, err
+	}
+
+	key, ok := peers[peerID]
+
+	return ed25519.PublicKey(key), ok, nil
+}
+
+// Add trusts an identity for a peer ID
+func (k *FileKnownPeers) Add(peerID string, key ed25519.PublicKey) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	peers, err := k.read()
+	if err != nil {
+		return err
+	}
+
+	peers[peerID] = key
+
+	content, err := json.MarshalIndent(peers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(k.path, content, 0600)
+}
+
+// LoadOrCreateIdentity reads a PEM-encoded Ed25519 identity key from a file, or generates and writes one if it doesn't exist yet
+func LoadOrCreateIdentity(path string) (ed25519.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{
+			Type:  pemTypePrivateKey,
+			Bytes: der,
+		}), 0600); err != nil {
+			return nil, err
+		}
+
+		return key, nil
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != pemTypePrivateKey {
+		return nil, ErrInvalidIdentityKey
+	}
+
+	rawKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := rawKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidIdentityKey
+	}
+
+	return key, nil
+}
+
+func getSignedMessage(parts ...[]byte) []byte {
+	return append([]byte(signaturePrefix), bytes.Join(parts, []byte{0})...)
+}
+
+func sign(identity ed25519.PrivateKey, message []byte) (ed25519.PublicKey, []byte) {
+	if identity == nil {
+		return nil, nil
+	}
+
+	return identity.Public().(ed25519.PublicKey), ed25519.Sign(identity, message)
+}
+
+func verify(publicKey, signature, message []byte, required bool) (ed25519.PublicKey, error) {
+	if len(publicKey) == 0 {
+		if required {
+			return nil, ErrMissingIdentity
+		}
+
+		return nil, nil
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, message, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return ed25519.PublicKey(publicKey), nil
+}
+
+// checkMissingIdentity returns an error if a peer which did not present an identity needs one, either because identities are required or because an identity is known for its ID
+func checkMissingIdentity(knownPeers KnownPeers, peerID string, required bool) error {
+	if required {
+		return ErrMissingIdentity
+	}
+
+	if knownPeers == nil {
+		return nil
+	}
+
+	// Peers with a known identity can't drop it to impersonate themselves
+	_, ok, err := knownPeers.Get(peerID)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return ErrMissingIdentity
+	}
+
+	return nil
+}
+
+func trustIdentity(
+	knownPeers KnownPeers,
+	onUnknownIdentity func(peerID string, key ed25519.PublicKey) bool,
+	peerID string,
+	key ed25519.PublicKey,
+	required bool,
+) error {
+	if key == nil {
+		return checkMissingIdentity(knownPeers, peerID, required)
+	}
+
+	if knownPeers == nil {
+		if onUnknownIdentity != nil && !onUnknownIdentity(peerID, key) {
+			return ErrUntrustedIdentity
+		}
+
+		return nil
+	}
+
+	known, ok, err := knownPeers.Get(peerID)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		if !known.Equal(key) {
+			return ErrIdentityMismatch
+		}
+
+		return nil
+	}
+
+	// Trust on first use unless the handler rejects the identity
+	if onUnknownIdentity != nil && !onUnknownIdentity(peerID, key) {
+		return ErrUntrustedIdentity
+	}
+
+	return knownPeers.Add(peerID, key)
+}
+
+func getIntroductionMessage(community string, introduction *websocketapi.Introduction) []byte {
+	return getSignedMessage([]byte(introduction.Type), []byte(community), []byte(introduction.From))
+}
+
+func getExchangeMessage(community string, exchange *websocketapi.Exchange) []byte {
+	return getSignedMessage([]byte(exchange.Type), []byte(community), []byte(exchange.From), []byte(exchange.To), exchange.Payload, []byte(exchange.Fingerprint))
+}
+
+func (a *Adapter) signIntroduction(community string, introduction *websocketapi.Introduction) *websocketapi.Introduction {
+	introduction.Routable = a.isRouting()
+	introduction.PublicKey, introduction.Signature = sign(a.config.Identity, getIntroductionMessage(community, introduction))
+
+	return introduction
+}
+
+func (a *Adapter) signExchange(community string, exchange *websocketapi.Exchange) *websocketapi.Exchange {
+	// Offers and answers bind the DTLS certificate to the identity
+	if exchange.Type != websocketapi.TypeCandidate {
+		exchange.Fingerprint = a.fingerprint
+	}
+
+	exchange.Routable = a.isRouting()
+	exchange.PublicKey, exchange.Signature = sign(a.config.Identity, getExchangeMessage(community, exchange))
+
+	return exchange
+}
+
+// verifyIntroduction verifies the introduction's signature and trusts its identity for the peer ID
+func (a *Adapter) verifyIntroduction(community string, introduction *websocketapi.Introduction) (ed25519.PublicKey, error) {
+	key, err := verify(introduction.PublicKey, introduction.Signature, getIntroductionMessage(community, introduction), a.config.RequireIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, trustIdentity(a.knownPeers, a.config.OnUnknownIdentity, introduction.From, key, a.config.RequireIdentity)
+}
+
+// verifyExchange verifies the exchange's signature; if trust is set, its identity is trusted for the peer ID, otherwise it must match the expected identity
+func (a *Adapter) verifyExchange(community string, exchange *websocketapi.Exchange, expected ed25519.PublicKey, trust bool) (ed25519.PublicKey, error) {
+	key, err := verify(exchange.PublicKey, exchange.Signature, getExchangeMessage(community, exchange), a.config.RequireIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	if trust {
+		return key, trustIdentity(a.knownPeers, a.config.OnUnknownIdentity, exchange.From, key, a.config.RequireIdentity)
+	}
+
+	if !bytes.Equal(key, expected) {
+		return nil, ErrIdentityMismatch
+	}
+
+	return key, nil
+}
+
+func getClaimedMessage(community string, id string) []byte {
+	return getSignedMessage([]byte(v1.TypeClaimed), []byte(community), []byte(id))
+}
+
+func (a *NamedAdapter) newClaimed(community string, id string) *v1.Claimed {
+	claimed := v1.NewClaimed(id)
+
+	publicKey, signature := sign(a.config.Identity, getClaimedMessage(community, id))
+	if publicKey != nil {
+		claimed.PublicKey = base64.StdEncoding.EncodeToString(publicKey)
+		claimed.Signature = base64.StdEncoding.EncodeToString(signature)
+	}
+
+	return claimed
+}
+
+// verifyClaimed verifies that the claim has been signed with the peer's identity and trusts the identity for the claimed ID
+func (a *NamedAdapter) verifyClaimed(community string, claimed *v1.Claimed, expected ed25519.PublicKey) error {
+	publicKey, err := base64.StdEncoding.DecodeString(claimed.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(claimed.Signature)
+	if err != nil {
+		return err
+	}
+
+	key, err := verify(publicKey, signature, getClaimedMessage(community, claimed.ID), a.config.RequireIdentity)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(key, expected) {
+		return ErrIdentityMismatch
+	}
+
+	return trustIdentity(a.knownPeers, a.config.OnUnknownIdentity, claimed.ID, key, a.config.RequireIdentity)
+}
@@ -0,0 +1,162 @@
+package wrtcconn
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func newTestIdentity(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return publicKey, privateKey
+}
+
+func newTestKnownPeers(t *testing.T, peers map[string]ed25519.PublicKey) KnownPeers {
+	knownPeers := NewFileKnownPeers(filepath.Join(t.TempDir(), "known-peers.json"))
+	for peerID, key := range peers {
+		if err := knownPeers.Add(peerID, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return knownPeers
+}
+
+func TestTrustIdentity(t *testing.T) {
+	pinned, _ := newTestIdentity(t)
+	other, _ := newTestIdentity(t)
+
+	trustAll := func(peerID string, key ed25519.PublicKey) bool { return true }
+	trustNone := func(peerID string, key ed25519.PublicKey) bool { return false }
+
+	tests := []struct {
+		name              string
+		known             map[string]ed25519.PublicKey
+		noKnownPeers      bool
+		onUnknownIdentity func(peerID string, key ed25519.PublicKey) bool
+		key               ed25519.PublicKey
+		required          bool
+		want              error
+		wantPinned        ed25519.PublicKey
+	}{
+		{"pinned identity", map[string]ed25519.PublicKey{"alice": pinned}, false, nil, pinned, false, nil, pinned},
+		{"other identity for pinned peer", map[string]ed25519.PublicKey{"alice": pinned}, false, trustAll, other, false, ErrIdentityMismatch, pinned},
+		{"missing identity for pinned peer", map[string]ed25519.PublicKey{"alice": pinned}, false, nil, nil, false, ErrMissingIdentity, pinned},
+		{"missing identity for unknown peer", map[string]ed25519.PublicKey{"bob": pinned}, false, nil, nil, false, nil, nil},
+		{"missing identity without known peers", nil, true, nil, nil, false, nil, nil},
+		{"missing required identity", nil, false, nil, nil, true, ErrMissingIdentity, nil},
+		{"missing required identity without known peers", nil, true, nil, nil, true, ErrMissingIdentity, nil},
+		{"unknown identity is trusted on first use", nil, false, nil, other, false, nil, other},
+		{"unknown identity is trusted by the handler", nil, false, trustAll, other, false, nil, other},
+		{"unknown identity is rejected by the handler", nil, false, trustNone, other, false, ErrUntrustedIdentity, nil},
+		{"unknown identity without known peers is rejected by the handler", nil, true, trustNone, other, false, ErrUntrustedIdentity, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var knownPeers KnownPeers
+			if !tt.noKnownPeers {
+				knownPeers = newTestKnownPeers(t, tt.known)
+			}
+
+			if err := trustIdentity(knownPeers, tt.onUnknownIdentity, "alice", tt.key, tt.required); !errors.Is(err, tt.want) {
+				t.Fatalf("trustIdentity() error = %v, want %v", err, tt.want)
+			}
+
+			if knownPeers == nil {
+				return
+			}
+
+			key, ok, err := knownPeers.Get("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok != (tt.wantPinned != nil) || (ok && !key.Equal(tt.wantPinned)) {
+				t.Fatalf("pinned identity = %v, want %v", key, tt.wantPinned)
+			}
+		})
+	}
+}
+
+func TestScopedKnownPeers(t *testing.T) {
+	first, _ := newTestIdentity(t)
+	second, _ := newTestIdentity(t)
+
+	knownPeers := newTestKnownPeers(t, nil)
+
+	signaler, err := url.Parse("wss://weron.up.railway.app/?community=mycommunity&password=mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSignaler, err := url.Parse("wss://example.com/?community=mycommunity&password=mypassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice has been pinned in mycommunity on the first signaler
+	if err := trustIdentity(newScopedKnownPeers(knownPeers, signaler, "mycommunity"), nil, "alice", first, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		signaler  *url.URL
+		community string
+		key       ed25519.PublicKey
+		want      error
+	}{
+		{"same community", signaler, "mycommunity", first, nil},
+		{"other identity in same community", signaler, "mycommunity", second, ErrIdentityMismatch},
+		{"other community", signaler, "othercommunity", second, nil},
+		{"other signaler", otherSignaler, "mycommunity", second, nil},
+		{"community which looks like a peer ID", signaler, "mycommunity/alice", second, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := trustIdentity(newScopedKnownPeers(knownPeers, tt.signaler, tt.community), nil, "alice", tt.key, false); !errors.Is(err, tt.want) {
+				t.Fatalf("trustIdentity() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	publicKey, privateKey := newTestIdentity(t)
+	message := getSignedMessage([]byte("introduction"), []byte("mycommunity"), []byte("alice"))
+
+	_, signature := sign(privateKey, message)
+
+	tests := []struct {
+		name      string
+		publicKey []byte
+		signature []byte
+		message   []byte
+		required  bool
+		want      error
+	}{
+		{"valid signature", publicKey, signature, message, false, nil},
+		{"signature for other message", publicKey, signature, getSignedMessage([]byte("introduction"), []byte("othercommunity"), []byte("alice")), false, ErrInvalidSignature},
+		{"truncated public key", publicKey[1:], signature, message, false, ErrInvalidSignature},
+		{"missing signature", publicKey, nil, message, false, ErrInvalidSignature},
+		{"missing identity", nil, nil, message, false, nil},
+		{"missing required identity", nil, nil, message, true, ErrMissingIdentity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verify(tt.publicKey, tt.signature, tt.message, tt.required); !errors.Is(err, tt.want) {
+				t.Fatalf("verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package wrtcconn
+
+import (
+	"time"
+
+	"github.com/pojntfx/weron/internal/encryption"
+)
+
+const (
+	defaultKeyUnusedTimeout = time.Hour
+	maxKeyUsageInterval     = time.Minute
+)
+
+// KeyUsage describes when a key has last been used to decrypt signaling messages
+type KeyUsage struct {
+	ID       string    // ID of the key
+	Primary  bool      // Whether the key is used to encrypt signaling messages
+	LastUsed time.Time // Time at which a signaling message has last been decrypted with the key (zero if it hasn't been used yet)
+}
+
+// GenerateKey creates a random community key, i.e. to rotate the current key
+func GenerateKey() (string, error) {
+	return encryption.GenerateKey()
+}
+
+// GetKeyID returns the ID of a community key, which is derived from the key and the community
+func GetKeyID(key string, community string) (string, error) {
+	return encryption.GetKeyID([]byte(key), community)
+}
+
+func (a *Adapter) newKeyring(community string) *encryption.Keyring {
+	keys := [][]byte{}
+	for _, key := range a.config.Keys {
+		keys = append(keys, []byte(key))
+	}
+
+	return encryption.NewKeyring(community, []byte(a.key), keys...)
+}
+
+// KeyUsage returns when each key of the keyring has last been used to decrypt signaling messages
+func (a *Adapter) KeyUsage() ([]KeyUsage, error) {
+	a.keyringLock.Lock()
+	keyring := a.keyring
+	a.keyringLock.Unlock()
+
+	if keyring == nil {
+		return []KeyUsage{}, nil
+	}
+
+	rawUsage, err := keyring.Usage()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := []KeyUsage{}
+	for _, u := range rawUsage {
+		usage = append(usage, KeyUsage{
+			ID:       u.ID,
+			Primary:  u.Primary,
+			LastUsed: u.LastUsed,
+		})
+	}
+
+	return usage, nil
+}
+
+// watchKeyUsage calls OnKeyUnused once for every additional key which hasn't been used for KeyUnusedTimeout
+func (a *Adapter) watchKeyUsage(opened time.Time) {
+	if a.config.OnKeyUnused == nil || len(a.config.Keys) == 0 {
+		return
+	}
+
+	timeout := a.config.KeyUnusedTimeout
+	if timeout <= 0 {
+		timeout = defaultKeyUnusedTimeout
+	}
+
+	interval := timeout / 2
+	if interval > maxKeyUsageInterval {
+		interval = maxKeyUsageInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	notified := map[string]bool{}
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			usage, err := a.KeyUsage()
+			if err != nil {
+				return
+			}
+
+			for _, u := range usage {
+				if u.Primary {
+					continue
+				}
+
+				lastUsed := u.LastUsed
+				if lastUsed.Before(opened) {
+					lastUsed = opened
+				}
+
+				if time.Since(lastUsed) < timeout {
+					// The key has been used again, so notify again once it is unused
+					notified[u.ID] = false
+
+					continue
+				}
+
+				if !notified[u.ID] {
+					notified[u.ID] = true
+
+					a.config.OnKeyUnused(u.ID)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package wrtcconn
+
+import (
+	"io"
+	"sync"
+
+	v1 "github.com/pojntfx/weron/pkg/api/webrtc/v1"
+)
+
+// messageConn encodes and decodes ID negotiation messages; it sends JSON until the peer has announced that it supports a preferred codec
+type messageConn struct {
+	io.ReadWriteCloser
+	codecs []string // Codecs to decode, in order of preference
+
+	codecLock sync.Mutex
+	codec     string
+
+	readLock sync.Mutex
+	buf      []byte
+}
+
+func newMessageConn(conn io.ReadWriteCloser, codecs []string) *messageConn {
+	return &messageConn{
+		ReadWriteCloser: conn,
+		codecs:          codecs,
+
+		codec: v1.CodecJSON,
+
+		buf: make([]byte, maxMessageSize+1),
+	}
+}
+
+// negotiate selects the most preferred codec which the peer supports
+func (c *messageConn) negotiate(theirs []string) {
+	c.codecLock.Lock()
+	defer c.codecLock.Unlock()
+
+	for _, ours := range c.codecs {
+		for _, codec := range theirs {
+			if ours == codec {
+				c.codec = ours
+
+				return
+			}
+		}
+	}
+}
+
+func (c *messageConn) encode(message interface{}) error {
+	c.codecLock.Lock()
+	codec := c.codec
+	c.codecLock.Unlock()
+
+	frame, err := v1.Encode(codec, message)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Write(frame)
+
+	return err
+}
+
+// read reads the next frame, which is a single data channel message in any codec
+func (c *messageConn) read() ([]byte, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	n, err := c.Read(c.buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, c.buf[:n]...), nil
+}
+
+func (a *NamedAdapter) getCodecs() []string {
+	if a.config.Codec == v1.CodecJSON {
+		return []string{v1.CodecJSON}
+	}
+
+	return []string{a.config.Codec, v1.CodecJSON}
+}
@@ -0,0 +1,59 @@
+package wrtcconn
+
+import (
+	"testing"
+
+	v1 "github.com/pojntfx/weron/pkg/api/webrtc/v1"
+)
+
+func TestMessageConnNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		ours   []string
+		theirs []string // Codecs from the peer's greeting
+		want   string
+	}{
+		{"peer which supports CBOR", []string{v1.CodecCBOR, v1.CodecJSON}, []string{v1.CodecCBOR, v1.CodecJSON}, v1.CodecCBOR},
+		{"JSON-only peer", []string{v1.CodecCBOR, v1.CodecJSON}, []string{v1.CodecJSON}, v1.CodecJSON},
+		{"peer which predates codecs", []string{v1.CodecCBOR, v1.CodecJSON}, nil, v1.CodecJSON},
+		{"peer with unknown codecs", []string{v1.CodecCBOR, v1.CodecJSON}, []string{"xml"}, v1.CodecJSON},
+		{"JSON-only adapter", []string{v1.CodecJSON}, []string{v1.CodecCBOR, v1.CodecJSON}, v1.CodecJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newMessageConn(newChannelConn(), tt.ours)
+
+			// Greetings are sent before the peer's codecs are known, so they must always be readable by JSON-only peers
+			if err := conn.encode(v1.NewBackoff()); err != nil {
+				t.Fatal(err)
+			}
+
+			conn.negotiate(tt.theirs)
+
+			if err := conn.encode(v1.NewBackoff()); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range []string{v1.CodecJSON, tt.want} {
+				frame, err := conn.read()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				message, codec, err := v1.Decode(frame)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if _, ok := message.(*v1.Backoff); !ok {
+					t.Fatalf("Decode() = %T, want %T", message, &v1.Backoff{})
+				}
+
+				if codec != want {
+					t.Fatalf("frame %v codec = %v, want %v", i, codec, want)
+				}
+			}
+		})
+	}
+}
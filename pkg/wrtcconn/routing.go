@@ -0,0 +1,47 @@
+package wrtcconn
+
+import (
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
+)
+
+// resetRoutes forgets all routable peers, i.e. after reconnecting to a signaler which might not support routing
+func (a *Adapter) resetRoutes(routing bool) {
+	a.routesLock.Lock()
+	defer a.routesLock.Unlock()
+
+	a.routing = routing
+	a.routes = map[string]struct{}{}
+}
+
+// setRoutable records whether the signaler delivers routed frames to a peer
+func (a *Adapter) setRoutable(peerID string, routable bool) {
+	a.routesLock.Lock()
+	defer a.routesLock.Unlock()
+
+	if routable {
+		a.routes[peerID] = struct{}{}
+	} else {
+		delete(a.routes, peerID)
+	}
+}
+
+func (a *Adapter) isRouting() bool {
+	a.routesLock.Lock()
+	defer a.routesLock.Unlock()
+
+	return a.routing
+}
+
+// route prefixes a sealed envelope with the recipient token of its peer if both our and the peer's signaler route frames; otherwise, it is broadcast to the community
+func (a *Adapter) route(community string, to string, envelope []byte) ([]byte, error) {
+	a.routesLock.Lock()
+	_, routable := a.routes[to]
+	routing := a.routing
+	a.routesLock.Unlock()
+
+	if to == "" || !routing || !routable {
+		return envelope, nil
+	}
+
+	return websocketapi.NewRoutedFrame(websocketapi.GetRecipientToken(community, to), envelope)
+}
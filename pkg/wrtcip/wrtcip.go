@@ -233,6 +233,11 @@ func (a *Adapter) Close() error {
 	return a.adapter.Close()
 }
 
+// CompressionStats returns the compression statistics for each compressed channel
+func (a *Adapter) CompressionStats() map[string]wrtcconn.CompressionStats {
+	return a.adapter.CompressionStats()
+}
+
 // Wait starts the transmission loop
 func (a *Adapter) Wait() error {
 	peers := map[string]*peerWithIP{}
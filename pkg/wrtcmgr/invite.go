@@ -0,0 +1,160 @@
+package wrtcmgr
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	InviteURLPrefix = "weron://invite/" // Prefix of invite URLs; the token follows the prefix
+
+	inviteVersion   = 1
+	inviteSeparator = "."
+	invitePrefix    = "weron/invite/" // Prefix of signed invites to prevent signatures from being reused in other protocols
+)
+
+var (
+	ErrInvalidInvite            = errors.New("invalid invite")                                    // The invite is malformed
+	ErrInvalidInviteSignature   = errors.New("invalid invite signature")                          // The invite's signature could not be verified
+	ErrUnsupportedInviteVersion = errors.New("unsupported invite version")                        // The invite has been created with an unknown version
+	ErrInviteExpired            = errors.New("invite has expired")                                // The invite's expiry is in the past
+	ErrInviteIssuerMismatch     = errors.New("invite has not been signed by the expected issuer") // The invite has not been signed by the expected issuer
+	ErrMissingInviteIssuer      = errors.New("missing expected invite issuer")                    // No issuer has been given to verify the invite with
+	ErrMissingInviteIdentity    = errors.New("missing identity to sign the invite with")          // No identity has been given to sign the invite with
+)
+
+// InviteIssuers stores the issuers which are trusted for communities
+type InviteIssuers interface {
+	Get(community string) (ed25519.PublicKey, bool, error) // Get returns the trusted issuer for a community
+	Add(community string, issuer ed25519.PublicKey) error  // Add trusts an issuer for a community
+}
+
+// Invite bundles everything that is required to join a community
+type Invite struct {
+	Version    int       `json:"version"`       // Version of the invite format
+	Signaler   string    `json:"signaler"`      // Address of the signaler
+	Community  string    `json:"community"`     // ID of the community to join
+	Password   string    `json:"password"`      // Password for the community
+	Key        string    `json:"key"`           // Encryption key for the community
+	ICE        []string  `json:"ice,omitempty"` // STUN and TURN servers to use
+	ForceRelay bool      `json:"forceRelay"`    // Whether to force usage of TURN servers
+	Expires    time.Time `json:"expires"`       // Time after which clients refuse to use the invite (zero if the invite doesn't expire); this is only advisory, since the signaler doesn't know about invites and anyone with the token can still join with the password and key it contains
+	Issuer     []byte    `json:"issuer"`        // Ed25519 public key of the issuer of the invite
+	CreatedAt  time.Time `json:"createdAt"`     // Time at which the invite has been created
+}
+
+// CreateInvite signs the invite with the identity and encodes it into a token; recipients verify the invite with the identity's public key, so it should be stable
+func CreateInvite(invite Invite, identity ed25519.PrivateKey) (string, error) {
+	if identity == nil {
+		return "", ErrMissingInviteIdentity
+	}
+
+	invite.Version = inviteVersion
+	invite.Issuer = identity.Public().(ed25519.PublicKey)
+	if invite.CreatedAt.IsZero() {
+		invite.CreatedAt = time.Now()
+	}
+
+	payload, err := json.Marshal(invite)
+	if err != nil {
+		return "", err
+	}
+
+	signature := ed25519.Sign(identity, append([]byte(invitePrefix), payload...))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + inviteSeparator + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// GetInviteURL returns the URL form of an invite token
+func GetInviteURL(token string) string {
+	return InviteURLPrefix + token
+}
+
+// ParseInvite verifies and decodes an invite token or URL; the invite must have been signed by the expected issuer
+func ParseInvite(token string, issuer ed25519.PublicKey) (*Invite, error) {
+	if len(issuer) != ed25519.PublicKeySize {
+		return nil, ErrMissingInviteIssuer
+	}
+
+	invite, err := parseInvite(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(issuer, invite.Issuer) {
+		return nil, ErrInviteIssuerMismatch
+	}
+
+	return invite, nil
+}
+
+// ParseInviteTOFU verifies and decodes an invite token or URL without an expected issuer.
+// The issuer is trusted on first use for the invite's community, after which invites for the community must have been signed by it; pinned is true if the issuer has been trusted by this call.
+func ParseInviteTOFU(token string, issuers InviteIssuers) (invite *Invite, pinned bool, err error) {
+	invite, err = parseInvite(token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	known, ok, err := issuers.Get(invite.Community)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ok {
+		if !bytes.Equal(known, invite.Issuer) {
+			return nil, false, ErrInviteIssuerMismatch
+		}
+
+		return invite, false, nil
+	}
+
+	if err := issuers.Add(invite.Community, invite.Issuer); err != nil {
+		return nil, false, err
+	}
+
+	return invite, true, nil
+}
+
+// parseInvite decodes an invite token or URL and verifies that it has been signed by the issuer it contains; anyone can create such an invite, so the issuer must be checked
+func parseInvite(token string) (*Invite, error) {
+	token = strings.TrimPrefix(strings.TrimSpace(token), InviteURLPrefix)
+
+	parts := strings.Split(token, inviteSeparator)
+	if len(parts) != 2 {
+		return nil, ErrInvalidInvite
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidInvite
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidInvite
+	}
+
+	var invite Invite
+	if err := json.Unmarshal(payload, &invite); err != nil {
+		return nil, ErrInvalidInvite
+	}
+
+	if invite.Version != inviteVersion {
+		return nil, ErrUnsupportedInviteVersion
+	}
+
+	if len(invite.Issuer) != ed25519.PublicKeySize || !ed25519.Verify(invite.Issuer, append([]byte(invitePrefix), payload...), signature) {
+		return nil, ErrInvalidInviteSignature
+	}
+
+	if !invite.Expires.IsZero() && time.Now().After(invite.Expires) {
+		return nil, ErrInviteExpired
+	}
+
+	return &invite, nil
+}
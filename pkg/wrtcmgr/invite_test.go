@@ -0,0 +1,155 @@
+package wrtcmgr
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memoryInviteIssuers map[string]ed25519.PublicKey
+
+func (m memoryInviteIssuers) Get(community string) (ed25519.PublicKey, bool, error) {
+	issuer, ok := m[community]
+
+	return issuer, ok, nil
+}
+
+func (m memoryInviteIssuers) Add(community string, issuer ed25519.PublicKey) error {
+	m[community] = issuer
+
+	return nil
+}
+
+func newTestIdentity(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return publicKey, privateKey
+}
+
+func newTestInvite(t *testing.T, identity ed25519.PrivateKey, expires time.Time) string {
+	token, err := CreateInvite(Invite{
+		Signaler:  "wss://weron.up.railway.app/",
+		Community: "mycommunity",
+		Password:  "mypassword",
+		Key:       "mykey",
+		Expires:   expires,
+	}, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// tamperInvite replaces a value in the invite's payload and re-signs it with another identity, as an attacker without the issuer's identity would
+func tamperInvite(t *testing.T, token string, old, replacement string) string {
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, inviteSeparator)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var invite Invite
+	if err := json.Unmarshal([]byte(strings.Replace(string(payload), old, replacement, 1)), &invite); err != nil {
+		t.Fatal(err)
+	}
+
+	_, attacker := newTestIdentity(t)
+
+	return newTestInviteFrom(t, invite, attacker)
+}
+
+func newTestInviteFrom(t *testing.T, invite Invite, identity ed25519.PrivateKey) string {
+	token, err := CreateInvite(invite, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestParseInvite(t *testing.T) {
+	issuer, identity := newTestIdentity(t)
+	other, _ := newTestIdentity(t)
+
+	valid := newTestInvite(t, identity, time.Now().Add(time.Hour))
+	parts := strings.Split(valid, inviteSeparator)
+
+	tests := []struct {
+		name   string
+		token  string
+		issuer ed25519.PublicKey
+		want   error
+	}{
+		{"valid invite", valid, issuer, nil},
+		{"valid invite URL", GetInviteURL(valid), issuer, nil},
+		{"invite without expiry", newTestInvite(t, identity, time.Time{}), issuer, nil},
+		{"missing issuer", valid, nil, ErrMissingInviteIssuer},
+		{"other issuer", valid, other, ErrInviteIssuerMismatch},
+		{"re-signed with changed signaler", tamperInvite(t, valid, "weron.up.railway.app", "attacker.example.com"), issuer, ErrInviteIssuerMismatch},
+		{"changed payload", base64.RawURLEncoding.EncodeToString([]byte(`{"version":1}`)) + inviteSeparator + parts[1], issuer, ErrInvalidInviteSignature},
+		{"expired invite", newTestInvite(t, identity, time.Now().Add(-time.Hour)), issuer, ErrInviteExpired},
+		{"missing signature", parts[0], issuer, ErrInvalidInvite},
+		{"invalid encoding", "!" + inviteSeparator + parts[1], issuer, ErrInvalidInvite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invite, err := ParseInvite(tt.token, tt.issuer)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseInvite() error = %v, want %v", err, tt.want)
+			}
+
+			if err == nil && (invite.Community != "mycommunity" || invite.Password != "mypassword" || invite.Key != "mykey") {
+				t.Fatalf("ParseInvite() = %+v, want invite to mycommunity", invite)
+			}
+		})
+	}
+}
+
+func TestParseInviteTOFU(t *testing.T) {
+	issuer, identity := newTestIdentity(t)
+	_, attacker := newTestIdentity(t)
+
+	tests := []struct {
+		name       string
+		known      memoryInviteIssuers
+		identity   ed25519.PrivateKey
+		want       error
+		wantPinned bool
+	}{
+		{"first use", memoryInviteIssuers{}, identity, nil, true},
+		{"pinned issuer", memoryInviteIssuers{"mycommunity": issuer}, identity, nil, false},
+		{"other issuer for pinned community", memoryInviteIssuers{"mycommunity": issuer}, attacker, ErrInviteIssuerMismatch, false},
+		{"other community is pinned", memoryInviteIssuers{"othercommunity": issuer}, attacker, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, pinned, err := ParseInviteTOFU(newTestInvite(t, tt.identity, time.Now().Add(time.Hour)), tt.known)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseInviteTOFU() error = %v, want %v", err, tt.want)
+			}
+
+			if pinned != tt.wantPinned {
+				t.Fatalf("ParseInviteTOFU() pinned = %v, want %v", pinned, tt.wantPinned)
+			}
+
+			if key, ok := tt.known["mycommunity"]; !ok || (tt.want == nil && !key.Equal(tt.identity.Public().(ed25519.PublicKey))) {
+				t.Fatalf("trusted issuer = %v", key)
+			}
+		})
+	}
+}
+
+func TestCreateInviteRequiresIdentity(t *testing.T) {
+	if _, err := CreateInvite(Invite{Community: "mycommunity"}, nil); !errors.Is(err, ErrMissingInviteIdentity) {
+		t.Fatalf("CreateInvite() error = %v, want %v", err, ErrMissingInviteIdentity)
+	}
+}
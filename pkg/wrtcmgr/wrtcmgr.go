@@ -1,26 +1,39 @@
 package wrtcmgr
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pojntfx/weron/internal/persisters"
+	v1 "github.com/pojntfx/weron/pkg/api/management/v1"
 )
 
 var (
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
 )
 
+// ManagerConfig configures the manager
+type ManagerConfig struct {
+	TLSConfig *tls.Config // TLS configuration to connect to the signaler with, i.e. to trust a private CA or to present a client certificate (optional)
+}
+
 // Manager manages a signaling server
 type Manager struct {
 	url      string
 	username string
 	password string
+	config   *ManagerConfig
 	ctx      context.Context
+
+	hc *http.Client
 }
 
 // NewManager creates the manager
@@ -28,54 +41,124 @@ func NewManager(
 	url string,
 	username string,
 	password string,
+	config *ManagerConfig,
 	ctx context.Context,
 ) *Manager {
+	if config == nil {
+		config = &ManagerConfig{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLSConfig
+
 	return &Manager{
 		url:      url,
 		username: username,
 		password: password,
+		config:   config,
 		ctx:      ctx,
+
+		hc: &http.Client{
+			Transport: transport,
+		},
 	}
 }
 
-// CreatePersistentCommunity creates a persistent community, which will not be automatically deleted after the last peer leaves
-func (m *Manager) CreatePersistentCommunity(community string, password string) (*persisters.Community, error) {
-	hc := &http.Client{}
-
+// do sends a request to the management API; failed requests return a *v1.Error if the signaler has sent one
+func (m *Manager) do(method string, path []string, query url.Values, body interface{}, res interface{}) error {
 	u, err := url.Parse(m.url)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	q := u.Query()
-	q.Set("community", community)
-	q.Set("password", password)
-	u.RawQuery = q.Encode()
+	u = u.JoinPath(path...)
+	u.RawQuery = query.Encode()
+
+	reqBody := io.Reader(http.NoBody)
+	if body != nil {
+		j, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reqBody = bytes.NewReader(j)
+	}
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), http.NoBody)
+	req, err := http.NewRequestWithContext(m.ctx, method, u.String(), reqBody)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	req.SetBasicAuth(m.username, m.password)
 
-	res, err := hc.Do(req)
-	if err != nil {
-		return nil, err
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
-	if res.Body != nil {
-		defer res.Body.Close()
+
+	r, err := m.hc.Do(req)
+	if err != nil {
+		return err
 	}
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New(res.Status)
+	if r.Body != nil {
+		defer r.Body.Close()
 	}
 
-	body, err := ioutil.ReadAll(res.Body)
+	resBody, err := io.ReadAll(r.Body)
 	if err != nil {
+		return err
+	}
+
+	if r.StatusCode < http.StatusOK || r.StatusCode >= http.StatusMultipleChoices {
+		apiErr := &v1.Error{}
+		if err := json.Unmarshal(resBody, apiErr); err != nil || apiErr.Message == "" {
+			// Signalers which don't support the management API (or proxies in front of them) don't send an error object
+			return errors.New(r.Status)
+		}
+
+		return apiErr
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.Unmarshal(resBody, res)
+}
+
+// CreatePersistentCommunity creates a persistent community, which will not be automatically deleted after the last peer leaves
+func (m *Manager) CreatePersistentCommunity(community string, password string) (*persisters.Community, error) {
+	return m.CreatePersistentCommunityWithPolicy(community, password, persisters.Policy{})
+}
+
+// CreatePersistentCommunityWithPolicy creates a persistent community with a policy
+func (m *Manager) CreatePersistentCommunityWithPolicy(community string, password string, policy persisters.Policy) (*persisters.Community, error) {
+	c := persisters.Community{}
+	if err := m.do(
+		http.MethodPost,
+		[]string{v1.PathCommunities},
+		url.Values{},
+		v1.CreateCommunity{
+			ID:       community,
+			Password: password,
+			Policy:   policy,
+		},
+		&c,
+	); err != nil {
 		return nil, err
 	}
 
+	return &c, nil
+}
+
+// GetCommunity queries a single community
+func (m *Manager) GetCommunity(community string) (*persisters.Community, error) {
 	c := persisters.Community{}
-	if err := json.Unmarshal(body, &c); err != nil {
+	if err := m.do(
+		http.MethodGet,
+		[]string{v1.PathCommunities, url.PathEscape(community)},
+		url.Values{},
+		nil,
+		&c,
+	); err != nil {
 		return nil, err
 	}
 
@@ -84,72 +167,123 @@ func (m *Manager) CreatePersistentCommunity(community string, password string) (
 
 // ListCommunities queries all communities
 func (m *Manager) ListCommunities() ([]persisters.Community, error) {
-	u, err := url.Parse(m.url)
-	if err != nil {
-		return nil, err
-	}
+	c := []persisters.Community{}
+	for {
+		q := url.Values{}
+		q.Set(v1.QueryLimit, strconv.Itoa(v1.MaxLimit))
+		q.Set(v1.QueryOffset, strconv.Itoa(len(c)))
 
-	hc := &http.Client{}
+		page := v1.Communities{}
+		if err := m.do(
+			http.MethodGet,
+			[]string{v1.PathCommunities},
+			q,
+			nil,
+			&page,
+		); err != nil {
+			return nil, err
+		}
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
-	if err != nil {
-		return nil, err
-	}
-	req.SetBasicAuth(m.username, m.password)
+		c = append(c, page.Communities...)
 
-	res, err := hc.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New(res.Status)
+		// Communities which have been created or deleted between pages can lead to duplicates or gaps, but not to an infinite loop
+		if len(page.Communities) == 0 || len(c) >= page.Total {
+			return c, nil
+		}
 	}
+}
+
+// UpdateCommunityPassword changes the password of a community without kicking its peers; the previous password keeps working for the grace period
+func (m *Manager) UpdateCommunityPassword(community string, password string, grace time.Duration) error {
+	return m.do(
+		http.MethodPut,
+		[]string{v1.PathCommunities, url.PathEscape(community), v1.PathPassword},
+		url.Values{},
+		v1.UpdatePassword{
+			Password:    password,
+			GracePeriod: int64((grace + time.Second - 1) / time.Second), // Round up so that short grace periods don't revoke the previous password immediately
+		},
+		nil,
+	)
+}
 
-	body, err := ioutil.ReadAll(res.Body)
+// ListClients queries the clients of a community which are connected to any of the signalers which share the broker; use GetClients to check whether all signalers have listed their clients
+func (m *Manager) ListClients(community string) ([]v1.Client, error) {
+	c, err := m.GetClients(community)
 	if err != nil {
 		return nil, err
 	}
 
-	c := []persisters.Community{}
-	if err := json.Unmarshal(body, &c); err != nil {
+	return c.Clients, nil
+}
+
+// GetClients queries the clients of a community and the signalers which have listed them
+func (m *Manager) GetClients(community string) (*v1.Clients, error) {
+	c := v1.Clients{}
+	if err := m.do(
+		http.MethodGet,
+		[]string{v1.PathCommunities, url.PathEscape(community), v1.PathClients},
+		url.Values{},
+		nil,
+		&c,
+	); err != nil {
 		return nil, err
 	}
 
-	return c, nil
+	return &c, nil
 }
 
-// DeleteCommunity deletes a community and kicks all peers that joined it
-func (m *Manager) DeleteCommunity(community string) error {
-	hc := &http.Client{}
+// KickClient disconnects a single client from all signalers; the client can reconnect if it still knows the password of the community
+func (m *Manager) KickClient(community string, client string) error {
+	return m.do(
+		http.MethodDelete,
+		[]string{v1.PathCommunities, url.PathEscape(community), v1.PathClients, url.PathEscape(client)},
+		url.Values{},
+		nil,
+		nil,
+	)
+}
 
-	u, err := url.Parse(m.url)
-	if err != nil {
-		return err
-	}
+// ListAuditEvents queries the most recent audit events, newest first; limit <= 0 queries all audit events
+func (m *Manager) ListAuditEvents(limit int) ([]persisters.AuditEvent, error) {
+	e := []persisters.AuditEvent{}
+	for {
+		pageLimit := v1.MaxLimit
+		if limit > 0 && limit-len(e) < pageLimit {
+			pageLimit = limit - len(e)
+		}
 
-	q := u.Query()
-	q.Set("community", community)
-	u.RawQuery = q.Encode()
+		q := url.Values{}
+		q.Set(v1.QueryLimit, strconv.Itoa(pageLimit))
+		q.Set(v1.QueryOffset, strconv.Itoa(len(e)))
 
-	req, err := http.NewRequest(http.MethodDelete, u.String(), http.NoBody)
-	if err != nil {
-		return err
-	}
-	req.SetBasicAuth(m.username, m.password)
+		page := v1.AuditEvents{}
+		if err := m.do(
+			http.MethodGet,
+			[]string{v1.PathAudit},
+			q,
+			nil,
+			&page,
+		); err != nil {
+			return nil, err
+		}
 
-	res, err := hc.Do(req)
-	if err != nil {
-		return err
-	}
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-	if res.StatusCode != http.StatusOK {
-		return errors.New(res.Status)
+		e = append(e, page.Events...)
+
+		// Audit events which are recorded between pages shift the older ones, which can lead to duplicates, but not to an infinite loop
+		if len(page.Events) == 0 || len(e) >= page.Total || (limit > 0 && len(e) >= limit) {
+			return e, nil
+		}
 	}
+}
 
-	return nil
+// DeleteCommunity deletes a community and kicks all peers that joined it
+func (m *Manager) DeleteCommunity(community string) error {
+	return m.do(
+		http.MethodDelete,
+		[]string{v1.PathCommunities, url.PathEscape(community)},
+		url.Values{},
+		nil,
+		nil,
+	)
 }
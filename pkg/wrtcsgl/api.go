@@ -0,0 +1,560 @@
+package wrtcsgl
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pojntfx/weron/internal/brokers"
+	"github.com/pojntfx/weron/internal/persisters"
+	v1 "github.com/pojntfx/weron/pkg/api/management/v1"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxRequestBodySize = 1024 * 1024
+)
+
+var (
+	errManagementAPIDisabled = errors.New("management API is disabled")
+	errUnauthorized          = errors.New("wrong username or password")
+	errNotFound              = errors.New("not found")
+	errMethodNotAllowed      = errors.New("method not allowed")
+	errCommunityNotFound     = errors.New("community not found")
+	errInvalidLimit          = errors.New("invalid limit")
+	errInvalidOffset         = errors.New("invalid offset")
+	errInvalidRequestBody    = errors.New("invalid request body")
+	errInvalidGracePeriod    = errors.New("invalid grace period")
+)
+
+// writeJSON writes a response of the management API
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		log.Debug().
+			Err(err).
+			Msg("Could not marshal API response")
+
+		rw.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+
+	if _, err := rw.Write(j); err != nil {
+		log.Debug().
+			Err(err).
+			Msg("Could not write API response")
+	}
+}
+
+// writeAPIError writes an error of the management API; internal errors are only logged so that they don't leak details of the persister or broker
+func writeAPIError(rw http.ResponseWriter, status int, err error) {
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Debug().
+			Err(err).
+			Msg("Could not handle API request")
+
+		message = http.StatusText(status)
+	}
+
+	writeJSON(rw, status, v1.NewError(status, message))
+}
+
+// authorizeAPI checks whether the management API is enabled and whether the client is allowed to use it with at least the minimum role; it returns the client for the audit log
+func (s *Signaler) authorizeAPI(rw http.ResponseWriter, r *http.Request, minimum role) (principal, bool) {
+	if !s.managementAPIEnabled {
+		writeAPIError(rw, http.StatusNotImplemented, errManagementAPIDisabled)
+
+		return principal{}, false
+	}
+
+	u, p, ok := r.BasicAuth()
+	if err := s.auth.Validate(u, p); !ok || err != nil {
+		s.metrics.authFailures.WithLabelValues(authFailureManagement).Inc()
+
+		writeAPIError(rw, http.StatusUnauthorized, errUnauthorized)
+
+		return principal{}, false
+	}
+
+	actor := s.getPrincipal(u, p)
+	if actor.role < minimum {
+		writeAPIError(rw, http.StatusForbidden, errForbidden)
+
+		return principal{}, false
+	}
+
+	return actor, true
+}
+
+// authorizeCommunity checks whether the client is allowed to change a community
+func (s *Signaler) authorizeCommunity(rw http.ResponseWriter, actor principal, community string) bool {
+	ok, err := s.canManageCommunity(actor, community)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeAPIError(rw, http.StatusNotFound, errCommunityNotFound)
+
+			return false
+		}
+
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return false
+	}
+
+	if !ok {
+		writeAPIError(rw, http.StatusForbidden, errForbidden)
+
+		return false
+	}
+
+	return true
+}
+
+// deleteCommunity deletes a community and kicks its clients from all signalers
+func (s *Signaler) deleteCommunity(community string) error {
+	if err := s.db.DeleteCommunity(s.ctx, community); err != nil {
+		return err
+	}
+
+	return s.broker.PublishKick(s.ctx, brokers.Kick{
+		Community: community,
+	})
+}
+
+func (s *Signaler) handleAPINotFound(rw http.ResponseWriter, r *http.Request) {
+	writeAPIError(rw, http.StatusNotFound, errNotFound)
+}
+
+func (s *Signaler) handleOpenAPI(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	if _, err := rw.Write(v1.OpenAPI); err != nil {
+		log.Debug().
+			Err(err).
+			Msg("Could not write OpenAPI document")
+	}
+}
+
+func (s *Signaler) handleCommunities(rw http.ResponseWriter, r *http.Request) {
+	actor, ok := s.authorizeAPI(rw, r, getMinimumRole(r.Method))
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.listCommunities(rw, r)
+	case http.MethodPost:
+		s.createCommunity(rw, r, actor)
+	default:
+		rw.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPost}, ", "))
+
+		writeAPIError(rw, http.StatusMethodNotAllowed, errMethodNotAllowed)
+	}
+}
+
+func (s *Signaler) handleCommunity(rw http.ResponseWriter, r *http.Request) {
+	actor, ok := s.authorizeAPI(rw, r, getMinimumRole(r.Method))
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getCommunity(rw, r)
+	case http.MethodDelete:
+		s.deleteCommunityByID(rw, r, actor)
+	default:
+		rw.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodDelete}, ", "))
+
+		writeAPIError(rw, http.StatusMethodNotAllowed, errMethodNotAllowed)
+	}
+}
+
+func (s *Signaler) handleCommunityPassword(rw http.ResponseWriter, r *http.Request) {
+	actor, ok := s.authorizeAPI(rw, r, roleOperator)
+	if !ok {
+		return
+	}
+
+	if r.Method != http.MethodPut {
+		rw.Header().Set("Allow", http.MethodPut)
+
+		writeAPIError(rw, http.StatusMethodNotAllowed, errMethodNotAllowed)
+
+		return
+	}
+
+	var req v1.UpdatePassword
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxRequestBodySize)).Decode(&req); err != nil {
+		writeAPIError(rw, http.StatusBadRequest, errInvalidRequestBody)
+
+		return
+	}
+
+	if strings.TrimSpace(req.Password) == "" {
+		writeAPIError(rw, http.StatusBadRequest, errMissingPassword)
+
+		return
+	}
+
+	if req.GracePeriod < 0 {
+		writeAPIError(rw, http.StatusBadRequest, errInvalidGracePeriod)
+
+		return
+	}
+
+	// Connected clients are not kicked; only new joins have to use the new password
+	community := r.PathValue("id")
+	if !s.authorizeCommunity(rw, actor, community) {
+		return
+	}
+
+	if err := s.db.UpdateCommunityPassword(s.ctx, community, req.Password, time.Duration(req.GracePeriod)*time.Second); err != nil {
+		if err == sql.ErrNoRows {
+			writeAPIError(rw, http.StatusNotFound, errCommunityNotFound)
+
+			return
+		}
+
+		if err == persisters.ErrMissingVerifier {
+			writeAPIError(rw, http.StatusConflict, err)
+
+			return
+		}
+
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	s.recordAuditEvent(r, actor.id, persisters.AuditActionUpdatePassword, getCommunityTarget(community))
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Signaler) handleCommunityClients(rw http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authorizeAPI(rw, r, roleViewer); !ok {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+
+		writeAPIError(rw, http.StatusMethodNotAllowed, errMethodNotAllowed)
+
+		return
+	}
+
+	community := r.PathValue("id")
+	if _, err := s.db.GetCommunity(s.ctx, community); err != nil {
+		if err == sql.ErrNoRows {
+			writeAPIError(rw, http.StatusNotFound, errCommunityNotFound)
+
+			return
+		}
+
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	clients, err := s.getClients(r.Context(), community)
+	if err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, clients)
+}
+
+func (s *Signaler) handleCommunityClient(rw http.ResponseWriter, r *http.Request) {
+	actor, ok := s.authorizeAPI(rw, r, roleOperator)
+	if !ok {
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		rw.Header().Set("Allow", http.MethodDelete)
+
+		writeAPIError(rw, http.StatusMethodNotAllowed, errMethodNotAllowed)
+
+		return
+	}
+
+	community, client := r.PathValue("id"), r.PathValue("client")
+	if !s.authorizeCommunity(rw, actor, community) {
+		return
+	}
+
+	// The client can be connected to any signaler, so the kick is accepted even if it isn't connected to this one
+	if err := s.broker.PublishKick(s.ctx, brokers.Kick{
+		Client: &brokers.ClientKick{
+			Community: community,
+			ID:        client,
+		},
+	}); err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	s.recordAuditEvent(r, actor.id, persisters.AuditActionKickClient, getClientTarget(community, client))
+
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Signaler) handleAudit(rw http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authorizeAPI(rw, r, roleAdmin); !ok {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+
+		writeAPIError(rw, http.StatusMethodNotAllowed, errMethodNotAllowed)
+
+		return
+	}
+
+	q := r.URL.Query()
+
+	limit, err := getIntQuery(q, v1.QueryLimit, v1.DefaultLimit, 1, v1.MaxLimit, errInvalidLimit)
+	if err != nil {
+		writeAPIError(rw, http.StatusBadRequest, err)
+
+		return
+	}
+
+	offset, err := getIntQuery(q, v1.QueryOffset, 0, 0, 0, errInvalidOffset)
+	if err != nil {
+		writeAPIError(rw, http.StatusBadRequest, err)
+
+		return
+	}
+
+	events, total, err := s.db.GetAuditEvents(s.ctx, offset, limit)
+	if err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, v1.AuditEvents{
+		Events: events,
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// getIntQuery parses an integer query parameter, falling back to the default value if it is not set
+func getIntQuery(q url.Values, key string, defaultValue int, min int, max int, invalid error) (int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i < min || (max > 0 && i > max) {
+		return 0, invalid
+	}
+
+	return i, nil
+}
+
+// matchesFilters checks whether a community matches the persistence and label filters of a list request
+func matchesFilters(c persisters.Community, persistent *bool, labels map[string]string) bool {
+	if persistent != nil && c.Persistent != *persistent {
+		return false
+	}
+
+	for key, value := range labels {
+		if candidate, ok := c.Labels[key]; !ok || candidate != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Signaler) listCommunities(rw http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, err := getIntQuery(q, v1.QueryLimit, v1.DefaultLimit, 1, v1.MaxLimit, errInvalidLimit)
+	if err != nil {
+		writeAPIError(rw, http.StatusBadRequest, err)
+
+		return
+	}
+
+	offset, err := getIntQuery(q, v1.QueryOffset, 0, 0, 0, errInvalidOffset)
+	if err != nil {
+		writeAPIError(rw, http.StatusBadRequest, err)
+
+		return
+	}
+
+	var persistent *bool
+	if v := q.Get(v1.QueryPersistent); v != "" {
+		p, err := strconv.ParseBool(v)
+		if err != nil {
+			writeAPIError(rw, http.StatusBadRequest, err)
+
+			return
+		}
+
+		persistent = &p
+	}
+
+	labels := map[string]string{}
+	for _, label := range q[v1.QueryLabel] {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			writeAPIError(rw, http.StatusBadRequest, errInvalidLabel)
+
+			return
+		}
+
+		labels[key] = value
+	}
+
+	communities, err := s.db.GetCommunities(s.ctx)
+	if err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	filtered := []persisters.Community{}
+	for _, c := range communities {
+		if matchesFilters(c, persistent, labels) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	// Pages are only stable if the communities are sorted
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].ID < filtered[j].ID
+	})
+
+	start := offset
+	if start > len(filtered) {
+		start = len(filtered)
+	}
+
+	end := start + limit
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+
+	writeJSON(rw, http.StatusOK, v1.Communities{
+		Communities: filtered[start:end],
+		Total:       len(filtered),
+		Offset:      offset,
+		Limit:       limit,
+	})
+}
+
+func (s *Signaler) createCommunity(rw http.ResponseWriter, r *http.Request, actor principal) {
+	var req v1.CreateCommunity
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxRequestBodySize)).Decode(&req); err != nil {
+		writeAPIError(rw, http.StatusBadRequest, errInvalidRequestBody)
+
+		return
+	}
+
+	if strings.TrimSpace(req.ID) == "" {
+		writeAPIError(rw, http.StatusBadRequest, errMissingCommunity)
+
+		return
+	}
+
+	if strings.TrimSpace(req.Password) == "" {
+		writeAPIError(rw, http.StatusBadRequest, errMissingPassword)
+
+		return
+	}
+
+	if req.MaxClients < 0 {
+		writeAPIError(rw, http.StatusBadRequest, errInvalidMaxClients)
+
+		return
+	}
+
+	if req.OIDCRequired && len(s.joinAuthns) == 0 {
+		writeAPIError(rw, http.StatusBadRequest, errOIDCNotConfigured)
+
+		return
+	}
+
+	c, err := s.db.CreatePersistentCommunity(s.ctx, req.ID, req.Password, req.Policy, s.getOwner(actor))
+	if err != nil {
+		if err == persisters.ErrCommunityExists {
+			writeAPIError(rw, http.StatusConflict, err)
+
+			return
+		}
+
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	s.recordAuditEvent(r, actor.id, persisters.AuditActionCreateCommunity, getCommunityTarget(c.ID))
+
+	rw.Header().Set("Location", v1.PathCommunities+"/"+url.PathEscape(c.ID))
+
+	writeJSON(rw, http.StatusCreated, c)
+}
+
+func (s *Signaler) getCommunity(rw http.ResponseWriter, r *http.Request) {
+	c, err := s.db.GetCommunity(s.ctx, r.PathValue("id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeAPIError(rw, http.StatusNotFound, errCommunityNotFound)
+
+			return
+		}
+
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, c)
+}
+
+func (s *Signaler) deleteCommunityByID(rw http.ResponseWriter, r *http.Request, actor principal) {
+	community := r.PathValue("id")
+	if !s.authorizeCommunity(rw, actor, community) {
+		return
+	}
+
+	if err := s.deleteCommunity(community); err != nil {
+		if err == sql.ErrNoRows {
+			writeAPIError(rw, http.StatusNotFound, errCommunityNotFound)
+
+			return
+		}
+
+		writeAPIError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	s.recordAuditEvent(r, actor.id, persisters.AuditActionDeleteCommunity, getCommunityTarget(community))
+
+	rw.WriteHeader(http.StatusNoContent)
+}
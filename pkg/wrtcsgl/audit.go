@@ -0,0 +1,83 @@
+package wrtcsgl
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pojntfx/weron/internal/persisters"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	claimSubject = "sub"
+
+	auditTargetCommunities = "communities"
+	auditTargetClients     = "clients"
+)
+
+var (
+	errInvalidToken = errors.New("invalid token")
+)
+
+// getTokenClaims returns the claims of a JWT; the signature is not checked, so the token must have been validated before
+func getTokenClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errInvalidToken
+	}
+
+	return claims, nil
+}
+
+// getCommunityTarget returns the audit target of a community
+func getCommunityTarget(community string) string {
+	return auditTargetCommunities + "/" + url.PathEscape(community)
+}
+
+// getClientTarget returns the audit target of a client of a community
+func getClientTarget(community, client string) string {
+	return getCommunityTarget(community) + "/" + auditTargetClients + "/" + url.PathEscape(client)
+}
+
+// recordAuditEvent records a management operation which has succeeded; the operation has already been done, so failures are only logged
+func (s *Signaler) recordAuditEvent(r *http.Request, actor, action, target string) {
+	event := persisters.AuditEvent{
+		Timestamp: time.Now().UTC(),
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		SourceIP:  getRemoteIP(r, s.config.TrustForwardedFor),
+	}
+
+	if err := s.db.AddAuditEvent(s.ctx, event); err != nil {
+		log.Error().
+			Err(err).
+			Str("actor", event.Actor).
+			Str("action", event.Action).
+			Str("target", event.Target).
+			Msg("Could not record audit event")
+
+		return
+	}
+
+	log.Info().
+		Str("actor", event.Actor).
+		Str("action", event.Action).
+		Str("target", event.Target).
+		Str("sourceIP", event.SourceIP).
+		Msg("Recorded audit event")
+}
@@ -0,0 +1,216 @@
+package wrtcsgl
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pojntfx/go-auth-utils/pkg/authn"
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
+	"github.com/pojntfx/weron/internal/encryption"
+	"github.com/pojntfx/weron/internal/persisters"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	challengeNonceSize          = 32
+	maxChallenges               = 3
+	maxChallengeResponseSize    = 64 * 1024
+	closeReasonInternalError    = "internal error"
+	closeReasonInvalidChallenge = "invalid challenge response"
+)
+
+var (
+	errInvalidChallengeResponse = errors.New("invalid challenge response")
+	errTooManyChallenges        = errors.New("community has changed during too many challenges")
+
+	errCommunityChanged = errors.New("community has changed since the challenge has been sent")
+)
+
+// isChallengeRequested returns whether the client wants to join with a challenge instead of sending the password in the URL
+func isChallengeRequested(r *http.Request) bool {
+	return slices.Contains(websocket.Subprotocols(r), websocketapi.SubprotocolChallenge)
+}
+
+// getChallengeCloseCode returns the close code and reason with which a failed challenge is reported to the client
+func getChallengeCloseCode(err error) (int, string) {
+	switch err {
+	case authn.ErrWrongPassword, persisters.ErrEphemeralCommunitiesDisabled:
+		return websocketapi.CloseWrongPassword, err.Error()
+	case persisters.ErrCommunityFull:
+		return websocketapi.CloseCommunityFull, err.Error()
+	case persisters.ErrCommunityExpired:
+		return websocketapi.CloseCommunityExpired, err.Error()
+	case errEphemeralCommunitiesQuotaExceeded:
+		return websocketapi.CloseTooManyRequests, err.Error()
+	case errInvalidChallengeResponse:
+		return websocket.ClosePolicyViolation, closeReasonInvalidChallenge
+	default:
+		return websocket.CloseInternalServerErr, closeReasonInternalError
+	}
+}
+
+// writeChallengeMessage sends a message of the challenge to the client
+func (s *Signaler) writeChallengeMessage(conn *websocket.Conn, v interface{}) error {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(s.config.Heartbeat)); err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(websocket.TextMessage, p)
+}
+
+// authenticateChallenge challenges the client to prove that it knows the password of the community and adds it to the community; the bcrypt hash of the password is never checked for communities with a verifier
+func (s *Signaler) authenticateChallenge(conn *websocket.Conn, community string, ip string) (*websocketapi.Accepted, error) {
+	for i := 0; i < maxChallenges; i++ {
+		accepted, err := s.challenge(conn, community, ip)
+		if err == errCommunityChanged {
+			continue
+		}
+
+		return accepted, err
+	}
+
+	return nil, errTooManyChallenges
+}
+
+// challenge sends one challenge to the client; it returns errCommunityChanged if the community has been created or its password has been changed since the challenge has been sent, in which case the client is challenged again
+func (s *Signaler) challenge(conn *websocket.Conn, community string, ip string) (*websocketapi.Accepted, error) {
+	verifiers, err := s.db.GetCommunityVerifiers(s.ctx, community)
+	create := false
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		if !s.config.EphemeralCommunities {
+			return nil, persisters.ErrEphemeralCommunitiesDisabled
+		}
+
+		create = true
+	}
+
+	nonce := make([]byte, challengeNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	parameters := []websocketapi.VerifierParameters{}
+	for _, verifier := range verifiers {
+		parameters = append(parameters, websocketapi.VerifierParameters{
+			Salt:       verifier.Salt,
+			Iterations: verifier.Iterations,
+		})
+	}
+
+	if err := s.writeChallengeMessage(conn, websocketapi.NewChallenge(nonce, parameters, create)); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadLimit(maxChallengeResponseSize)
+	if err := conn.SetReadDeadline(time.Now().Add(s.config.Heartbeat)); err != nil {
+		return nil, err
+	}
+
+	_, p, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	var message websocketapi.Message
+	if err := json.Unmarshal(p, &message); err != nil {
+		return nil, errInvalidChallengeResponse
+	}
+
+	authMessage := encryption.GetAuthMessage(community, nonce)
+
+	switch message.Type {
+	case websocketapi.TypeProof:
+		if create {
+			return nil, errInvalidChallengeResponse
+		}
+
+		var proof websocketapi.Proof
+		if err := json.Unmarshal(p, &proof); err != nil {
+			return nil, errInvalidChallengeResponse
+		}
+
+		// The client doesn't know which password is current during a rotation, so it sends a proof for every verifier
+		for i, verifier := range verifiers {
+			if i >= len(proof.Proofs) || !verifier.VerifyProof(authMessage, proof.Proofs[i]) {
+				continue
+			}
+
+			// Joining an existing community doesn't count towards the quota for new ephemeral communities
+			clients, err := s.db.AddClientsToVerifiedCommunity(s.ctx, community, verifier, "", false)
+			if err != nil {
+				if err == authn.ErrWrongPassword || err == persisters.ErrEphemeralCommunitiesDisabled {
+					return nil, errCommunityChanged
+				}
+
+				return nil, err
+			}
+
+			return websocketapi.NewAccepted(clients, true, i, encryption.GetServerSignature(verifier.ServerKey, authMessage)), nil
+		}
+
+		return nil, authn.ErrWrongPassword
+	case websocketapi.TypeVerifier:
+		if !create {
+			return nil, errInvalidChallengeResponse
+		}
+
+		var verifier websocketapi.Verifier
+		if err := json.Unmarshal(p, &verifier); err != nil || !verifier.Verifier.IsValid() {
+			return nil, errInvalidChallengeResponse
+		}
+
+		// The hash can't be checked against the verifier, but a client which sends a wrong one only locks out the clients of signalers which predate verifiers from its own community
+		if _, err := bcrypt.Cost([]byte(verifier.PasswordHash)); err != nil {
+			return nil, errInvalidChallengeResponse
+		}
+
+		clients, err := s.joinCommunity(ip, func(upsert bool) (int, error) {
+			return s.db.AddClientsToVerifiedCommunity(s.ctx, community, verifier.Verifier, verifier.PasswordHash, upsert)
+		})
+		if err != nil {
+			// Another client has created the community since the challenge has been sent, so the client has to prove that it knows its password instead
+			if err == authn.ErrWrongPassword || err == persisters.ErrCommunityExists {
+				return nil, errCommunityChanged
+			}
+
+			return nil, err
+		}
+
+		return websocketapi.NewAccepted(clients, true, 0, nil), nil
+	case websocketapi.TypePassword:
+		// Only communities without a verifier can't be joined with a proof
+		if create || len(verifiers) > 0 {
+			return nil, errInvalidChallengeResponse
+		}
+
+		var password websocketapi.Password
+		if err := json.Unmarshal(p, &password); err != nil {
+			return nil, errInvalidChallengeResponse
+		}
+
+		clients, err := s.joinCommunity(ip, func(upsert bool) (int, error) {
+			return s.db.AddClientsToCommunity(s.ctx, community, password.Password, upsert)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return websocketapi.NewAccepted(clients, true, 0, nil), nil
+	default:
+		return nil, errInvalidChallengeResponse
+	}
+}
@@ -0,0 +1,286 @@
+package wrtcsgl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
+	"github.com/pojntfx/weron/internal/encryption"
+	"github.com/pojntfx/weron/internal/persisters"
+	"github.com/pojntfx/weron/internal/persisters/memory"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// serveChallenge starts a signaler which only challenges clients to join mycommunity
+func serveChallenge(t *testing.T, db persisters.CommunitiesPersister) string {
+	s := &Signaler{
+		ctx: context.Background(),
+		db:  db,
+		config: &SignalerConfig{
+			EphemeralCommunities: true,
+			Heartbeat:            time.Second * 5,
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(rw, r, http.Header{
+			"Sec-Websocket-Protocol": []string{websocketapi.SubprotocolChallenge},
+		})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		accepted, err := s.authenticateChallenge(conn, "mycommunity", "127.0.0.1")
+		if err != nil {
+			code, reason := getChallengeCloseCode(err)
+
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+
+			return
+		}
+
+		if err := s.writeChallengeMessage(conn, accepted); err != nil {
+			return
+		}
+
+		// Wait for the client to close the connection
+		_, _, _ = conn.ReadMessage()
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// answer answers a challenge like a client which knows the password does
+func answer(t *testing.T, challenge *websocketapi.Challenge, password string, authMessage []byte) (interface{}, [][]byte) {
+	switch {
+	case challenge.Create:
+		verifier, err := encryption.NewVerifier(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return websocketapi.NewVerifier(*verifier, string(hashedPassword)), nil
+	case len(challenge.Verifiers) == 0:
+		return websocketapi.NewPassword(password), nil
+	default:
+		proofs, serverKeys := [][]byte{}, [][]byte{}
+		for _, verifier := range challenge.Verifiers {
+			proof, serverKey, err := encryption.GetProof(password, verifier.Salt, verifier.Iterations, authMessage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			proofs = append(proofs, proof)
+			serverKeys = append(serverKeys, serverKey)
+		}
+
+		return websocketapi.NewProof(proofs), serverKeys
+	}
+}
+
+func TestAuthenticateChallenge(t *testing.T) {
+	createCommunity := func(password string) func(t *testing.T, db persisters.CommunitiesPersister) {
+		return func(t *testing.T, db persisters.CommunitiesPersister) {
+			if _, err := db.CreatePersistentCommunity(context.Background(), "mycommunity", password, persisters.Policy{}, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	joinCommunity := func(password string) func(t *testing.T, db persisters.CommunitiesPersister) {
+		return func(t *testing.T, db persisters.CommunitiesPersister) {
+			if _, err := db.AddClientsToCommunity(context.Background(), "mycommunity", password, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name           string
+		setup          func(t *testing.T, db persisters.CommunitiesPersister)
+		beforeAnswer   func(t *testing.T, db persisters.CommunitiesPersister) // Called before the first challenge is answered
+		response       func(t *testing.T, challenge *websocketapi.Challenge, authMessage []byte) interface{}
+		wantChallenges int
+		wantClients    int
+		wantCode       int
+	}{
+		{
+			name:           "create ephemeral community",
+			wantChallenges: 1,
+			wantClients:    1,
+		},
+		{
+			name:           "join with proof",
+			setup:          createCommunity("mypassword"),
+			wantChallenges: 1,
+			wantClients:    1,
+		},
+		{
+			name:           "join with wrong password",
+			setup:          createCommunity("otherpassword"),
+			wantChallenges: 1,
+			wantCode:       websocketapi.CloseWrongPassword,
+		},
+		{
+			name:           "community created by another client in the meantime",
+			beforeAnswer:   joinCommunity("mypassword"),
+			wantChallenges: 2,
+			wantClients:    2,
+		},
+		{
+			name:           "community created by another client with another password in the meantime",
+			beforeAnswer:   joinCommunity("otherpassword"),
+			wantChallenges: 2,
+			wantCode:       websocketapi.CloseWrongPassword,
+		},
+		{
+			name: "verifier without password hash",
+			response: func(t *testing.T, challenge *websocketapi.Challenge, authMessage []byte) interface{} {
+				verifier, err := encryption.NewVerifier("mypassword")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return websocketapi.NewVerifier(*verifier, "")
+			},
+			wantChallenges: 1,
+			wantCode:       websocket.ClosePolicyViolation,
+		},
+		{
+			name:  "verifier for existing community",
+			setup: createCommunity("mypassword"),
+			response: func(t *testing.T, challenge *websocketapi.Challenge, authMessage []byte) interface{} {
+				response, _ := answer(t, &websocketapi.Challenge{Create: true}, "mypassword", authMessage)
+
+				return response
+			},
+			wantChallenges: 1,
+			wantCode:       websocket.ClosePolicyViolation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.NewCommunitiesPersister()
+			if tt.setup != nil {
+				tt.setup(t, db)
+			}
+
+			conn, _, err := (&websocket.Dialer{
+				Subprotocols: []string{websocketapi.SubprotocolChallenge},
+			}).Dial(serveChallenge(t, db), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			challenges := 0
+			for {
+				_, p, err := conn.ReadMessage()
+				if err != nil {
+					var closeErr *websocket.CloseError
+					if !errors.As(err, &closeErr) || closeErr.Code != tt.wantCode {
+						t.Fatalf("ReadMessage() error = %v, want close code %v", err, tt.wantCode)
+					}
+
+					break
+				}
+
+				var message websocketapi.Message
+				if err := json.Unmarshal(p, &message); err != nil {
+					t.Fatal(err)
+				}
+
+				if message.Type == websocketapi.TypeAccepted {
+					var accepted websocketapi.Accepted
+					if err := json.Unmarshal(p, &accepted); err != nil {
+						t.Fatal(err)
+					}
+
+					if tt.wantCode != 0 || accepted.Clients != tt.wantClients {
+						t.Fatalf("accepted with %v clients, want %v clients and close code %v", accepted.Clients, tt.wantClients, tt.wantCode)
+					}
+
+					break
+				}
+
+				var challenge websocketapi.Challenge
+				if err := json.Unmarshal(p, &challenge); err != nil {
+					t.Fatal(err)
+				}
+
+				challenges++
+				if challenges == 1 && tt.beforeAnswer != nil {
+					tt.beforeAnswer(t, db)
+				}
+
+				authMessage := encryption.GetAuthMessage("mycommunity", challenge.Nonce)
+
+				var response interface{}
+				if tt.response != nil {
+					response = tt.response(t, &challenge, authMessage)
+				} else {
+					response, _ = answer(t, &challenge, "mypassword", authMessage)
+				}
+
+				if err := conn.WriteJSON(response); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if challenges != tt.wantChallenges {
+				t.Fatalf("got %v challenges, want %v", challenges, tt.wantChallenges)
+			}
+		})
+	}
+}
+
+func TestAuthenticateChallengeSignature(t *testing.T) {
+	db := memory.NewCommunitiesPersister()
+	if _, err := db.CreatePersistentCommunity(context.Background(), "mycommunity", "mypassword", persisters.Policy{}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, _, err := (&websocket.Dialer{
+		Subprotocols: []string{websocketapi.SubprotocolChallenge},
+	}).Dial(serveChallenge(t, db), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var challenge websocketapi.Challenge
+	if err := conn.ReadJSON(&challenge); err != nil {
+		t.Fatal(err)
+	}
+
+	authMessage := encryption.GetAuthMessage("mycommunity", challenge.Nonce)
+
+	response, serverKeys := answer(t, &challenge, "mypassword", authMessage)
+	if err := conn.WriteJSON(response); err != nil {
+		t.Fatal(err)
+	}
+
+	var accepted websocketapi.Accepted
+	if err := conn.ReadJSON(&accepted); err != nil {
+		t.Fatal(err)
+	}
+
+	// Clients only trust signalers which can prove that they know the verifier
+	if accepted.Verifier < 0 || accepted.Verifier >= len(serverKeys) || !encryption.VerifyServerSignature(serverKeys[accepted.Verifier], authMessage, accepted.Signature) {
+		t.Fatalf("accepted with invalid signature for verifier %v", accepted.Verifier)
+	}
+}
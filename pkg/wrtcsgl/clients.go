@@ -0,0 +1,122 @@
+package wrtcsgl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pojntfx/weron/internal/brokers"
+	v1 "github.com/pojntfx/weron/pkg/api/management/v1"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	clientsTimeout = time.Second * 5 // Time to wait for all signalers to list their clients
+)
+
+// getReplica returns the ID with which the signaler is distinguished from the other signalers which share the broker
+func getReplica() string {
+	id := uuid.New().String()[:8]
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return id
+	}
+
+	return fmt.Sprintf("%v-%v", hostname, id)
+}
+
+// getLocalClients returns the clients of a community which are connected to this signaler
+func (s *Signaler) getLocalClients(community string) []v1.Client {
+	s.connectionsLock.Lock()
+	defer s.connectionsLock.Unlock()
+
+	clients := []v1.Client{}
+	for id, c := range s.connections[community] {
+		clients = append(clients, v1.Client{
+			ID:             id,
+			RemoteIP:       c.ip,
+			UserAgent:      c.userAgent,
+			ConnectedSince: c.connectedSince,
+			BytesReceived:  c.bytesReceived.Load(),
+			BytesSent:      c.bytesSent.Load(),
+			Replica:        s.replica,
+		})
+	}
+
+	return clients
+}
+
+// respondToClientsRequests lists the clients which are connected to this signaler for the signalers which handle the clients requests of the management API
+func (s *Signaler) respondToClientsRequests(requests chan brokers.ClientsRequest) {
+	for request := range requests {
+		ctx, cancel := context.WithTimeout(s.ctx, clientsTimeout)
+
+		if err := s.broker.PublishClientsResponse(ctx, request.ID, brokers.ClientsResponse{
+			Replica: s.replica,
+			Clients: s.getLocalClients(request.Community),
+		}); err != nil {
+			log.Debug().
+				Err(err).
+				Str("community", request.Community).
+				Msg("Could not list clients for other signaler")
+		}
+
+		cancel()
+	}
+}
+
+// getClients returns the clients of a community which are connected to any of the signalers which share the broker, sorted by the time at which they have connected; signalers which don't respond in time are missing from the replicas
+func (s *Signaler) getClients(ctx context.Context, community string) (*v1.Clients, error) {
+	ctx, cancel := context.WithTimeout(ctx, clientsTimeout)
+	defer cancel()
+
+	request := uuid.New().String()
+
+	errs := make(chan error, 1)
+	responses, closeResponses, err := s.broker.SubscribeToClientsResponses(ctx, errs, request)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponses()
+
+	replicas, err := s.broker.PublishClientsRequest(ctx, brokers.ClientsRequest{
+		ID:        request,
+		Community: community,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clients := &v1.Clients{
+		Clients:  []v1.Client{},
+		Replicas: []string{},
+		Complete: true,
+	}
+
+	for len(clients.Replicas) < replicas && clients.Complete {
+		select {
+		case <-ctx.Done():
+			clients.Complete = false
+		case err := <-errs:
+			return nil, err
+		case response := <-responses:
+			clients.Replicas = append(clients.Replicas, response.Replica)
+			clients.Clients = append(clients.Clients, response.Clients...)
+		}
+	}
+
+	sort.Strings(clients.Replicas)
+	sort.Slice(clients.Clients, func(i, j int) bool {
+		if clients.Clients[i].ConnectedSince.Equal(clients.Clients[j].ConnectedSince) {
+			return clients.Clients[i].ID < clients.Clients[j].ID
+		}
+
+		return clients.Clients[i].ConnectedSince.Before(clients.Clients[j].ConnectedSince)
+	})
+
+	return clients, nil
+}
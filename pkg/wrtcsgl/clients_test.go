@@ -0,0 +1,111 @@
+package wrtcsgl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojntfx/weron/internal/brokers"
+	"github.com/pojntfx/weron/internal/brokers/process"
+)
+
+// newTestReplica starts a signaler which shares the broker and has the given clients in mycommunity
+func newTestReplica(t *testing.T, broker brokers.CommunitiesBroker, replica string, clients map[string]time.Time) *Signaler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	s := &Signaler{
+		ctx:         ctx,
+		broker:      broker,
+		replica:     replica,
+		connections: map[string]map[string]*connection{"mycommunity": {}},
+	}
+
+	for id, connectedSince := range clients {
+		c := newConnection(nil, "127.0.0.1", "weron")
+		c.connectedSince = connectedSince
+
+		s.connections["mycommunity"][id] = c
+	}
+
+	requests, closeRequests := broker.SubscribeToClientsRequests(ctx, make(chan error, 1))
+	t.Cleanup(func() {
+		_ = closeRequests()
+	})
+
+	go s.respondToClientsRequests(requests)
+
+	return s
+}
+
+func TestGetClients(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		silent       bool // Whether there is another signaler which doesn't respond
+		wantClients  []string
+		wantReplicas []string
+		wantComplete bool
+	}{
+		{"all signalers respond", false, []string{"b", "a", "c"}, []string{"replica1", "replica2"}, true},
+		{"signaler doesn't respond", true, nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := process.NewCommunitiesBroker()
+			t.Cleanup(func() {
+				_ = broker.Close()
+			})
+
+			s := newTestReplica(t, broker, "replica1", map[string]time.Time{"a": now.Add(time.Second), "b": now})
+			newTestReplica(t, broker, "replica2", map[string]time.Time{"c": now.Add(time.Second * 2)})
+
+			if tt.silent {
+				_, closeRequests := broker.SubscribeToClientsRequests(context.Background(), make(chan error, 1))
+				defer closeRequests()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+			defer cancel()
+
+			clients, err := s.getClients(ctx, "mycommunity")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if clients.Complete != tt.wantComplete {
+				t.Fatalf("getClients() complete = %v, want %v", clients.Complete, tt.wantComplete)
+			}
+
+			if !tt.wantComplete {
+				return
+			}
+
+			if len(clients.Replicas) != len(tt.wantReplicas) {
+				t.Fatalf("getClients() replicas = %v, want %v", clients.Replicas, tt.wantReplicas)
+			}
+
+			for i, replica := range tt.wantReplicas {
+				if clients.Replicas[i] != replica {
+					t.Fatalf("getClients() replicas = %v, want %v", clients.Replicas, tt.wantReplicas)
+				}
+			}
+
+			if len(clients.Clients) != len(tt.wantClients) {
+				t.Fatalf("getClients() = %v clients, want %v", len(clients.Clients), len(tt.wantClients))
+			}
+
+			for i, id := range tt.wantClients {
+				if clients.Clients[i].ID != id {
+					t.Fatalf("getClients() client %v = %v, want %v", i, clients.Clients[i].ID, id)
+				}
+			}
+
+			if clients.Clients[2].Replica != "replica2" {
+				t.Fatalf("getClients() client c replica = %v, want replica2", clients.Clients[2].Replica)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package wrtcsgl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// HealthPath is the path at which the liveness of the signaler is reported
+	HealthPath = "/healthz"
+
+	// ReadinessPath is the path at which the readiness of the signaler is reported
+	ReadinessPath = "/readyz"
+
+	checkOK = "ok"
+
+	readinessTimeout = time.Second * 5
+)
+
+// Readiness describes whether the signaler can accept new clients
+type Readiness struct {
+	Ready     bool   `json:"ready"`     // Whether the signaler can accept new clients
+	Draining  bool   `json:"draining"`  // Whether the signaler is shutting down
+	Persister string `json:"persister"` // Result of pinging the persister ("ok" or the error)
+	Broker    string `json:"broker"`    // Result of pinging the broker ("ok" or the error)
+}
+
+func (s *Signaler) handleHealth(rw http.ResponseWriter, r *http.Request) {
+	if _, err := fmt.Fprint(rw, checkOK); err != nil {
+		log.Debug().
+			Err(err).
+			Msg("Could not write health")
+	}
+}
+
+func (s *Signaler) handleReadiness(rw http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	readiness := Readiness{
+		Ready:     true,
+		Draining:  s.draining.Load(),
+		Persister: checkOK,
+		Broker:    checkOK,
+	}
+
+	if readiness.Draining {
+		readiness.Ready = false
+	}
+
+	if err := s.db.Ping(ctx); err != nil {
+		readiness.Ready = false
+		readiness.Persister = err.Error()
+	}
+
+	if err := s.broker.Ping(ctx); err != nil {
+		readiness.Ready = false
+		readiness.Broker = err.Error()
+	}
+
+	j, err := json.Marshal(readiness)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+
+		log.Debug().
+			Err(err).
+			Msg("Could not marshal readiness")
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if !readiness.Ready {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if _, err := fmt.Fprint(rw, string(j)); err != nil {
+		log.Debug().
+			Err(err).
+			Msg("Could not write readiness")
+	}
+}
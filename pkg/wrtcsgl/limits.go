@@ -0,0 +1,171 @@
+package wrtcsgl
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pojntfx/weron/internal/persisters"
+	"golang.org/x/time/rate"
+)
+
+const (
+	limitFrameSize                    = "frame_size"
+	limitMessageRate                  = "message_rate"
+	limitConnectionsPerIP             = "connections_per_ip"
+	limitEphemeralCommunitiesPerIP    = "ephemeral_communities_per_ip"
+	ephemeralCommunitiesLimitInterval = time.Hour
+
+	limiterPruneInterval = time.Minute * 10
+
+	closeReasonMessageRate = "message rate limit exceeded"
+)
+
+// getRemoteIP returns the IP of the client, which is taken from the X-Forwarded-For header if the signaler runs behind a trusted proxy
+func getRemoteIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		// The last entry has been added by the proxy in front of us; the previous ones can be spoofed by the client
+		if forwardedFor := r.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+			parts := strings.Split(forwardedFor[len(forwardedFor)-1], ",")
+
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// connectionCounter counts the open connections per IP
+type connectionCounter struct {
+	lock        sync.Mutex
+	connections map[string]int
+}
+
+func newConnectionCounter() *connectionCounter {
+	return &connectionCounter{
+		connections: map[string]int{},
+	}
+}
+
+// add counts a new connection for the IP if the IP has less than max connections; max <= 0 disables the limit
+func (c *connectionCounter) add(ip string, max int) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if max > 0 && c.connections[ip] >= max {
+		return false
+	}
+
+	c.connections[ip]++
+
+	return true
+}
+
+func (c *connectionCounter) remove(ip string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.connections[ip]--
+	if c.connections[ip] <= 0 {
+		delete(c.connections, ip)
+	}
+}
+
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipLimiters rate limits an action per IP
+type ipLimiters struct {
+	limit rate.Limit
+	burst int
+
+	lock       sync.Mutex
+	limiters   map[string]*ipLimiter
+	lastPruned time.Time
+}
+
+func newIPLimiters(limit rate.Limit, burst int) *ipLimiters {
+	return &ipLimiters{
+		limit: limit,
+		burst: burst,
+
+		limiters:   map[string]*ipLimiter{},
+		lastPruned: time.Now(),
+	}
+}
+
+func (l *ipLimiters) get(ip string) *rate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	// Limiters which haven't been used for long enough to be refilled are equal to new ones, so they can be forgotten
+	if now.Sub(l.lastPruned) > limiterPruneInterval {
+		refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+
+		for candidate, limiter := range l.limiters {
+			if now.Sub(limiter.lastSeen) > refill {
+				delete(l.limiters, candidate)
+			}
+		}
+
+		l.lastPruned = now
+	}
+
+	limiter, ok := l.limiters[ip]
+	if !ok {
+		limiter = &ipLimiter{
+			limiter: rate.NewLimiter(l.limit, l.burst),
+		}
+
+		l.limiters[ip] = limiter
+	}
+	limiter.lastSeen = now
+
+	return limiter.limiter
+}
+
+// newMessageLimiter creates a limiter for the messages of one connection; it returns nil if the rate is not limited
+func newMessageLimiter(messagesPerSecond float64, burst int) *rate.Limiter {
+	if messagesPerSecond <= 0 {
+		return nil
+	}
+
+	if burst <= 0 {
+		burst = int(math.Ceil(messagesPerSecond))
+	}
+
+	return rate.NewLimiter(rate.Limit(messagesPerSecond), burst)
+}
+
+// joinCommunity adds a client to a community with add; new ephemeral communities count towards the quota of the remote IP
+func (s *Signaler) joinCommunity(ip string, add func(upsert bool) (int, error)) (int, error) {
+	if !s.config.EphemeralCommunities || s.communityLimiters == nil {
+		return add(s.config.EphemeralCommunities)
+	}
+
+	// Joining existing communities doesn't count towards the quota
+	clients, err := add(false)
+	if err != persisters.ErrEphemeralCommunitiesDisabled {
+		return clients, err
+	}
+
+	if !s.communityLimiters.get(ip).Allow() {
+		return 0, errEphemeralCommunitiesQuotaExceeded
+	}
+
+	return add(true)
+}
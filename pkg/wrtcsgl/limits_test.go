@@ -0,0 +1,157 @@
+package wrtcsgl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pojntfx/weron/internal/persisters"
+	"github.com/pojntfx/weron/internal/persisters/memory"
+	"golang.org/x/time/rate"
+)
+
+func TestConnectionCounter(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		open int // Connections which are open before the next one is added
+		want bool
+	}{
+		{"below the limit", 2, 1, true},
+		{"at the limit", 2, 2, false},
+		{"no limit", 0, 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConnectionCounter()
+			for i := 0; i < tt.open; i++ {
+				if !c.add("127.0.0.1", tt.max) {
+					t.Fatalf("add() rejected connection %v, want accepted", i+1)
+				}
+			}
+
+			if got := c.add("127.0.0.1", tt.max); got != tt.want {
+				t.Fatalf("add() = %v, want %v", got, tt.want)
+			}
+
+			// Other IPs have their own limit
+			if !c.add("127.0.0.2", tt.max) {
+				t.Fatal("add() rejected connection from other IP")
+			}
+			c.remove("127.0.0.2")
+
+			if !tt.want {
+				// Closing a connection frees up its slot
+				c.remove("127.0.0.1")
+
+				if !c.add("127.0.0.1", tt.max) {
+					t.Fatal("add() rejected connection after another one has been removed")
+				}
+			}
+
+			for c.connections["127.0.0.1"] > 0 {
+				c.remove("127.0.0.1")
+			}
+
+			if len(c.connections) != 0 {
+				t.Fatalf("connections = %v after all have been removed, want none", c.connections)
+			}
+		})
+	}
+}
+
+func TestIPLimitersPrune(t *testing.T) {
+	tests := []struct {
+		name        string
+		idle        time.Duration // Time since the limiter has last been used
+		sincePruned time.Duration // Time since the limiters have last been pruned
+		wantEvicted bool
+	}{
+		{"idle limiter", time.Hour, limiterPruneInterval * 2, true},
+		{"limiter which is still refilling", time.Second, limiterPruneInterval * 2, false},
+		{"idle limiter before the prune interval", time.Hour, time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The burst is refilled after two seconds
+			l := newIPLimiters(rate.Limit(1), 2)
+
+			// Drain the limiter so that evicting it would reset its quota
+			limiter := l.get("127.0.0.1")
+			limiter.AllowN(time.Now(), 2)
+
+			now := time.Now()
+			l.limiters["127.0.0.1"].lastSeen = now.Add(-tt.idle)
+			l.lastPruned = now.Add(-tt.sincePruned)
+
+			_ = l.get("127.0.0.2")
+
+			if _, ok := l.limiters["127.0.0.1"]; ok == tt.wantEvicted {
+				t.Fatalf("limiter evicted = %v, want %v", !ok, tt.wantEvicted)
+			}
+
+			if !tt.wantEvicted && l.get("127.0.0.1") != limiter {
+				t.Fatal("get() returned a new limiter for a limiter which has not been evicted")
+			}
+		})
+	}
+}
+
+func TestJoinCommunity(t *testing.T) {
+	tests := []struct {
+		name                 string
+		ephemeralCommunities bool
+		perHour              int
+		ip                   string
+		community            string
+		want                 error
+		wantClients          int
+	}{
+		{"new community below the quota", true, 2, "127.0.0.1", "newcommunity", nil, 1},
+		{"new community at the quota", true, 1, "127.0.0.1", "newcommunity", errEphemeralCommunitiesQuotaExceeded, 0},
+		{"existing community at the quota", true, 1, "127.0.0.1", "mycommunity", nil, 2},
+		{"new community from other IP", true, 1, "127.0.0.2", "newcommunity", nil, 1},
+		{"new community without quota", true, 0, "127.0.0.1", "newcommunity", nil, 1},
+		{"ephemeral communities disabled", false, 1, "127.0.0.2", "newcommunity", persisters.ErrEphemeralCommunitiesDisabled, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.NewCommunitiesPersister()
+
+			s := &Signaler{
+				config: &SignalerConfig{
+					EphemeralCommunities: tt.ephemeralCommunities,
+				},
+			}
+
+			if tt.perHour > 0 {
+				s.communityLimiters = newIPLimiters(rate.Every(ephemeralCommunitiesLimitInterval/time.Duration(tt.perHour)), tt.perHour)
+			}
+
+			add := func(community string) func(upsert bool) (int, error) {
+				return func(upsert bool) (int, error) {
+					return db.AddClientsToCommunity(context.Background(), community, "mypassword", upsert)
+				}
+			}
+
+			// 127.0.0.1 has already created mycommunity, which uses up one community of its quota
+			if tt.ephemeralCommunities {
+				if _, err := s.joinCommunity("127.0.0.1", add("mycommunity")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clients, err := s.joinCommunity(tt.ip, add(tt.community))
+			if err != tt.want {
+				t.Fatalf("joinCommunity() error = %v, want %v", err, tt.want)
+			}
+
+			if clients != tt.wantClients {
+				t.Fatalf("joinCommunity() = %v clients, want %v", clients, tt.wantClients)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package wrtcsgl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pojntfx/weron/internal/brokers"
+	"github.com/pojntfx/weron/internal/encryption"
+	"github.com/pojntfx/weron/internal/persisters"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	metricsNamespace = "weron"
+	metricsSubsystem = "signaler"
+
+	// MetricsPath is the path at which metrics are served
+	MetricsPath = "/metrics"
+
+	directionReceived = "received" // Messages which have been received from clients
+	directionSent     = "sent"     // Messages which have been sent to clients
+
+	authFailureCommunity  = "community"  // Wrong community password or ephemeral communities disabled
+	authFailureManagement = "management" // Invalid management API credentials
+
+	communityTypeEphemeral  = "ephemeral"
+	communityTypePersistent = "persistent"
+
+	communitiesInterval = time.Second * 15 // Interval in which the communities in the persister are counted, so that scrapes don't scan the persister
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+
+	clients               *prometheus.GaugeVec
+	communities           *prometheus.GaugeVec
+	relayedMessages       *prometheus.CounterVec
+	relayedBytes          *prometheus.CounterVec
+	brokerPublishDuration *prometheus.HistogramVec
+	brokerPublishErrors   *prometheus.CounterVec
+	persisterDuration     *prometheus.HistogramVec
+	authFailures          *prometheus.CounterVec
+	kicks                 prometheus.Counter
+	limits                *prometheus.GaugeVec
+	limitRejections       *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+
+		clients: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "clients",
+			Help:      "Amount of clients connected to this signaler by community",
+		}, []string{"community"}),
+		communities: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "communities",
+			Help:      "Amount of communities in the persister by type (refreshed every " + communitiesInterval.String() + ")",
+		}, []string{"type"}),
+		relayedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "relayed_messages_total",
+			Help:      "Amount of signaling messages which have been relayed by direction",
+		}, []string{"direction"}),
+		relayedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "relayed_bytes_total",
+			Help:      "Amount of signaling message bytes which have been relayed by direction",
+		}, []string{"direction"}),
+		brokerPublishDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "broker_publish_duration_seconds",
+			Help:      "Latency of publishing to the broker by operation",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		brokerPublishErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "broker_publish_errors_total",
+			Help:      "Amount of failed publishes to the broker by operation",
+		}, []string{"operation"}),
+		persisterDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "persister_operation_duration_seconds",
+			Help:      "Latency of persister operations by operation and whether they have failed",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "failed"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "auth_failures_total",
+			Help:      "Amount of rejected requests due to invalid credentials by endpoint",
+		}, []string{"endpoint"}),
+		kicks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "kicks_total",
+			Help:      "Amount of clients which have been kicked from this signaler because their community has been deleted or they have been kicked individually",
+		}),
+		limits: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "limit",
+			Help:      "Configured limits of the signaler (0 if the limit is disabled)",
+		}, []string{"limit"}),
+		limitRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "limit_rejections_total",
+			Help:      "Amount of clients which have been rejected or disconnected by limit",
+		}, []string{"limit"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.clients,
+		m.communities,
+		m.relayedMessages,
+		m.relayedBytes,
+		m.brokerPublishDuration,
+		m.brokerPublishErrors,
+		m.persisterDuration,
+		m.authFailures,
+		m.kicks,
+		m.limits,
+		m.limitRejections,
+	)
+
+	return m
+}
+
+// countCommunities counts the communities in the persister by type until the context is cancelled; the last counts are kept if counting fails
+func (m *metrics) countCommunities(ctx context.Context, db persisters.CommunitiesPersister, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		communities, err := db.GetCommunities(ctx)
+		if err != nil {
+			log.Debug().
+				Err(err).
+				Msg("Could not count communities for metrics, continuing")
+		} else {
+			ephemeral, persistent := 0, 0
+			for _, community := range communities {
+				if community.Persistent {
+					persistent++
+				} else {
+					ephemeral++
+				}
+			}
+
+			m.communities.WithLabelValues(communityTypeEphemeral).Set(float64(ephemeral))
+			m.communities.WithLabelValues(communityTypePersistent).Set(float64(persistent))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// instrumentedPersister records the latency of all persister operations
+type instrumentedPersister struct {
+	persisters.CommunitiesPersister
+	duration *prometheus.HistogramVec
+}
+
+func (p *instrumentedPersister) observe(operation string, start time.Time, err error) {
+	failed := "false"
+	if err != nil {
+		failed = "true"
+	}
+
+	p.duration.WithLabelValues(operation, failed).Observe(time.Since(start).Seconds())
+}
+
+func (p *instrumentedPersister) AddClientsToCommunity(ctx context.Context, community string, password string, upsert bool) (clients int, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("add_clients_to_community", start, err)
+	}()
+
+	return p.CommunitiesPersister.AddClientsToCommunity(ctx, community, password, upsert)
+}
+
+func (p *instrumentedPersister) AddClientsToVerifiedCommunity(ctx context.Context, community string, verifier encryption.Verifier, passwordHash string, upsert bool) (clients int, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("add_clients_to_verified_community", start, err)
+	}()
+
+	return p.CommunitiesPersister.AddClientsToVerifiedCommunity(ctx, community, verifier, passwordHash, upsert)
+}
+
+func (p *instrumentedPersister) GetCommunityVerifiers(ctx context.Context, community string) (verifiers []encryption.Verifier, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("get_community_verifiers", start, err)
+	}()
+
+	return p.CommunitiesPersister.GetCommunityVerifiers(ctx, community)
+}
+
+func (p *instrumentedPersister) RemoveClientFromCommunity(ctx context.Context, community string) (err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("remove_client_from_community", start, err)
+	}()
+
+	return p.CommunitiesPersister.RemoveClientFromCommunity(ctx, community)
+}
+
+func (p *instrumentedPersister) Cleanup(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("cleanup", start, err)
+	}()
+
+	return p.CommunitiesPersister.Cleanup(ctx)
+}
+
+func (p *instrumentedPersister) GetCommunities(ctx context.Context) (communities []persisters.Community, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("get_communities", start, err)
+	}()
+
+	return p.CommunitiesPersister.GetCommunities(ctx)
+}
+
+func (p *instrumentedPersister) GetCommunity(ctx context.Context, community string) (c *persisters.Community, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("get_community", start, err)
+	}()
+
+	return p.CommunitiesPersister.GetCommunity(ctx, community)
+}
+
+func (p *instrumentedPersister) CreatePersistentCommunity(ctx context.Context, community string, password string, policy persisters.Policy, owner string) (c *persisters.Community, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("create_persistent_community", start, err)
+	}()
+
+	return p.CommunitiesPersister.CreatePersistentCommunity(ctx, community, password, policy, owner)
+}
+
+func (p *instrumentedPersister) UpdateCommunityPassword(ctx context.Context, community string, password string, grace time.Duration) (err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("update_community_password", start, err)
+	}()
+
+	return p.CommunitiesPersister.UpdateCommunityPassword(ctx, community, password, grace)
+}
+
+func (p *instrumentedPersister) DeleteCommunity(ctx context.Context, community string) (err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("delete_community", start, err)
+	}()
+
+	return p.CommunitiesPersister.DeleteCommunity(ctx, community)
+}
+
+func (p *instrumentedPersister) AddAuditEvent(ctx context.Context, event persisters.AuditEvent) (err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("add_audit_event", start, err)
+	}()
+
+	return p.CommunitiesPersister.AddAuditEvent(ctx, event)
+}
+
+func (p *instrumentedPersister) GetAuditEvents(ctx context.Context, offset int, limit int) (events []persisters.AuditEvent, total int, err error) {
+	start := time.Now()
+	defer func() {
+		p.observe("get_audit_events", start, err)
+	}()
+
+	return p.CommunitiesPersister.GetAuditEvents(ctx, offset, limit)
+}
+
+// instrumentedBroker records the latency and errors of all publishes to the broker
+type instrumentedBroker struct {
+	brokers.CommunitiesBroker
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func (b *instrumentedBroker) observe(operation string, start time.Time, err error) {
+	b.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		b.errors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (b *instrumentedBroker) PublishInput(ctx context.Context, input brokers.Input, community string) (err error) {
+	start := time.Now()
+	defer func() {
+		b.observe("input", start, err)
+	}()
+
+	return b.CommunitiesBroker.PublishInput(ctx, input, community)
+}
+
+func (b *instrumentedBroker) PublishKick(ctx context.Context, kick brokers.Kick) (err error) {
+	start := time.Now()
+	defer func() {
+		b.observe("kick", start, err)
+	}()
+
+	return b.CommunitiesBroker.PublishKick(ctx, kick)
+}
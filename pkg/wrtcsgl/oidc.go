@@ -0,0 +1,82 @@
+package wrtcsgl
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	authorizationSchemeBearer = "Bearer"
+)
+
+var (
+	errMissingIDToken    = errors.New("missing ID token")
+	errInvalidIDToken    = errors.New("invalid ID token")
+	errNotInOIDCGroup    = errors.New("not in any of the groups which may join the community")
+	errOIDCNotConfigured = errors.New("communities can't require OIDC if no OIDC issuer is configured")
+)
+
+// getBearerToken returns the bearer token from the Authorization header of a request
+func getBearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, authorizationSchemeBearer) {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// authorizeJoin checks whether a client may join a community; clients of communities which require OIDC have to send a valid ID token as a bearer token before the upgrade
+func (s *Signaler) authorizeJoin(r *http.Request, community string) error {
+	c, err := s.db.GetCommunity(s.ctx, community)
+	if err != nil {
+		// Ephemeral communities which don't exist yet can't require OIDC
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	if !c.OIDCRequired {
+		return nil
+	}
+
+	token := getBearerToken(r)
+	if token == "" {
+		return errMissingIDToken
+	}
+
+	valid := false
+	for _, auth := range s.joinAuthns {
+		if err := auth.Validate("", token); err == nil {
+			valid = true
+
+			break
+		}
+	}
+
+	if !valid {
+		return errInvalidIDToken
+	}
+
+	if len(c.OIDCGroups) == 0 {
+		return nil
+	}
+
+	claims, err := getTokenClaims(token)
+	if err != nil {
+		return errInvalidIDToken
+	}
+
+	for _, group := range getClaimValues(claims, s.rolesClaim) {
+		if slices.Contains(c.OIDCGroups, group) {
+			return nil
+		}
+	}
+
+	return errNotInOIDCGroup
+}
@@ -0,0 +1,174 @@
+package wrtcsgl
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pojntfx/go-auth-utils/pkg/authn"
+	"github.com/pojntfx/go-auth-utils/pkg/authn/oidc"
+	"github.com/pojntfx/weron/internal/persisters"
+	"github.com/pojntfx/weron/internal/persisters/memory"
+)
+
+const (
+	testIssuerKeyID = "test"
+)
+
+// testIssuer is an OIDC issuer which signs ID tokens with RS256
+type testIssuer struct {
+	url string
+	key *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(rw http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+			"issuer":                                srv.URL,
+			"jwks_uri":                              srv.URL + "/jwks",
+			"authorization_endpoint":                srv.URL + "/auth",
+			"token_endpoint":                        srv.URL + "/token",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(rw http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": testIssuerKeyID,
+					"alg": "RS256",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+				},
+			},
+		})
+	})
+
+	return &testIssuer{
+		url: srv.URL,
+		key: key,
+	}
+}
+
+// token signs an ID token for the audience; claims override the default claims
+func (i *testIssuer) token(t *testing.T, key *rsa.PrivateKey, audience string, claims map[string]interface{}) string {
+	payload := map[string]interface{}{
+		"iss": i.url,
+		"aud": audience,
+		"sub": "alice",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for claim, value := range claims {
+		payload[claim] = value
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": testIssuerKeyID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestAuthorizeJoin(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joinAuthn := oidc.NewAuthn(issuer.url, "weron")
+	if err := joinAuthn.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	db := memory.NewCommunitiesPersister()
+	for community, policy := range map[string]persisters.Policy{
+		"opencommunity":  {},
+		"oidccommunity":  {OIDCRequired: true},
+		"groupcommunity": {OIDCRequired: true, OIDCGroups: []string{"weron-users"}},
+	} {
+		if _, err := db.CreatePersistentCommunity(context.Background(), community, "mypassword", policy, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		community     string
+		authorization string
+		want          error
+	}{
+		{"community without OIDC", "opencommunity", "", nil},
+		{"ephemeral community", "newcommunity", "", nil},
+		{"missing token", "oidccommunity", "", errMissingIDToken},
+		{"other authorization scheme", "oidccommunity", "Basic " + issuer.token(t, issuer.key, "weron", nil), errMissingIDToken},
+		{"malformed token", "oidccommunity", "Bearer notatoken", errInvalidIDToken},
+		{"token signed with other key", "oidccommunity", "Bearer " + issuer.token(t, otherKey, "weron", nil), errInvalidIDToken},
+		{"wrong audience", "oidccommunity", "Bearer " + issuer.token(t, issuer.key, "otherclient", nil), errInvalidIDToken},
+		{"expired token", "oidccommunity", "Bearer " + issuer.token(t, issuer.key, "weron", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}), errInvalidIDToken},
+		{"wrong issuer claim", "oidccommunity", "Bearer " + issuer.token(t, issuer.key, "weron", map[string]interface{}{"iss": "https://example.com"}), errInvalidIDToken},
+		{"valid token", "oidccommunity", "Bearer " + issuer.token(t, issuer.key, "weron", nil), nil},
+		{"valid token without group", "groupcommunity", "Bearer " + issuer.token(t, issuer.key, "weron", map[string]interface{}{"groups": []string{"other"}}), errNotInOIDCGroup},
+		{"valid token with group", "groupcommunity", "Bearer " + issuer.token(t, issuer.key, "weron", map[string]interface{}{"groups": []string{"other", "weron-users"}}), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Signaler{
+				ctx:        context.Background(),
+				db:         db,
+				joinAuthns: []authn.Authn{joinAuthn},
+				rolesClaim: DefaultOIDCRolesClaim,
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/?community="+tt.community, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			if err := s.authorizeJoin(r, tt.community); err != tt.want {
+				t.Fatalf("authorizeJoin() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package wrtcsgl
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pojntfx/weron/internal/persisters"
+)
+
+const (
+	queryMaxClients     = "maxClients"
+	queryEphemeralJoins = "ephemeralJoins"
+	queryExpiresAt      = "expiresAt"
+	queryDescription    = "description"
+	queryLabels         = "labels"
+	queryOIDCRequired   = "oidcRequired"
+	queryOIDCGroups     = "oidcGroups"
+)
+
+var (
+	errInvalidMaxClients = errors.New("invalid maximum amount of clients")
+	errInvalidLabel      = errors.New("invalid label, expected key=value")
+)
+
+// getPolicyFromQuery parses the policy of a persistent community from the query parameters of the legacy management API
+func getPolicyFromQuery(q url.Values) (persisters.Policy, error) {
+	policy := persisters.Policy{
+		Description: q.Get(queryDescription),
+		Labels:      map[string]string{},
+	}
+
+	if v := q.Get(queryMaxClients); v != "" {
+		maxClients, err := strconv.Atoi(v)
+		if err != nil {
+			return persisters.Policy{}, err
+		}
+
+		if maxClients < 0 {
+			return persisters.Policy{}, errInvalidMaxClients
+		}
+
+		policy.MaxClients = maxClients
+	}
+
+	if v := q.Get(queryEphemeralJoins); v != "" {
+		ephemeralJoins, err := strconv.ParseBool(v)
+		if err != nil {
+			return persisters.Policy{}, err
+		}
+
+		policy.EphemeralJoins = ephemeralJoins
+	}
+
+	if v := q.Get(queryExpiresAt); v != "" {
+		expiresAt, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return persisters.Policy{}, err
+		}
+
+		policy.ExpiresAt = &expiresAt
+	}
+
+	if v := q.Get(queryOIDCRequired); v != "" {
+		oidcRequired, err := strconv.ParseBool(v)
+		if err != nil {
+			return persisters.Policy{}, err
+		}
+
+		policy.OIDCRequired = oidcRequired
+	}
+
+	policy.OIDCGroups = q[queryOIDCGroups]
+
+	for _, label := range q[queryLabels] {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return persisters.Policy{}, errInvalidLabel
+		}
+
+		policy.Labels[key] = value
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,139 @@
+package wrtcsgl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	RoleViewer   = "viewer"   // Can list communities and their clients
+	RoleOperator = "operator" // Can also create and delete communities, change their passwords and kick their clients
+	RoleAdmin    = "admin"    // Can also manage communities which have been created by others and read the audit log
+
+	DefaultOIDCRolesClaim = "groups" // Default claim of the OIDC token which is mapped to roles
+)
+
+type role int
+
+const (
+	roleNone role = iota
+	roleViewer
+	roleOperator
+	roleAdmin
+)
+
+var (
+	errForbidden   = errors.New("forbidden")
+	errInvalidRole = errors.New("invalid role")
+
+	roles = map[string]role{
+		RoleViewer:   roleViewer,
+		RoleOperator: roleOperator,
+		RoleAdmin:    roleAdmin,
+	}
+)
+
+// principal is the client of a management request
+type principal struct {
+	id   string // Basic auth user or OIDC subject
+	role role
+}
+
+// parseRoles parses the mapping from values of the roles claim to roles
+func parseRoles(mapping map[string]string) (map[string]role, error) {
+	parsed := map[string]role{}
+	for value, name := range mapping {
+		r, ok := roles[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", errInvalidRole, name)
+		}
+
+		parsed[value] = r
+	}
+
+	return parsed, nil
+}
+
+// getMinimumRole returns the role which is required for a management request with the method
+func getMinimumRole(method string) role {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return roleViewer
+	default:
+		return roleOperator
+	}
+}
+
+// getClaimValues returns the values of a claim which can be a string or a list of strings, i.e. the groups of the client
+func getClaimValues(claims map[string]interface{}, claim string) []string {
+	values := []string{}
+	switch v := claims[claim].(type) {
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, candidate := range v {
+			if value, ok := candidate.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values
+}
+
+// getPrincipal returns the client of a validated management request; the basic auth user is always an admin
+func (s *Signaler) getPrincipal(username, token string) principal {
+	if !s.oidcEnabled {
+		return principal{username, roleAdmin}
+	}
+
+	claims, err := getTokenClaims(token)
+	if err != nil {
+		return principal{}
+	}
+
+	subject, _ := claims[claimSubject].(string)
+
+	// Without a mapping, all clients with a valid token are admins, like before roles were introduced
+	if len(s.roles) == 0 {
+		return principal{subject, roleAdmin}
+	}
+
+	// Clients with multiple roles get the one with the most permissions
+	p := principal{subject, roleNone}
+	for _, value := range getClaimValues(claims, s.rolesClaim) {
+		if r := s.roles[value]; r > p.role {
+			p.role = r
+		}
+	}
+
+	return p
+}
+
+// canManageCommunity returns whether the principal may change a community; it returns sql.ErrNoRows if the owner of the community has to be checked but the community doesn't exist
+func (s *Signaler) canManageCommunity(p principal, community string) (bool, error) {
+	if p.role < roleOperator {
+		return false, nil
+	}
+
+	if p.role >= roleAdmin || !s.config.OIDCCommunityOwnership {
+		return true, nil
+	}
+
+	c, err := s.db.GetCommunity(s.ctx, community)
+	if err != nil {
+		return false, err
+	}
+
+	return p.id != "" && c.Owner == p.id, nil
+}
+
+// getOwner returns the owner of the communities which are created by the principal
+func (s *Signaler) getOwner(p principal) string {
+	if !s.oidcEnabled || !s.config.OIDCCommunityOwnership {
+		return ""
+	}
+
+	return p.id
+}
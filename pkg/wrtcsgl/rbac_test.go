@@ -0,0 +1,146 @@
+package wrtcsgl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"testing"
+
+	"github.com/pojntfx/weron/internal/persisters"
+	"github.com/pojntfx/weron/internal/persisters/memory"
+)
+
+// newTestToken creates an unsigned token with the claims; signatures are checked before the principal is read from the token
+func newTestToken(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func TestGetPrincipal(t *testing.T) {
+	mapping, err := parseRoles(map[string]string{
+		"weron-viewers":   RoleViewer,
+		"weron-operators": RoleOperator,
+		"weron-admins":    RoleAdmin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		oidcEnabled bool
+		roles       map[string]role
+		rolesClaim  string
+		token       string
+		want        principal
+	}{
+		{"basic auth", false, mapping, DefaultOIDCRolesClaim, "mypassword", principal{"admin", roleAdmin}},
+		{"token without mapping", true, nil, DefaultOIDCRolesClaim, newTestToken(t, map[string]interface{}{"sub": "alice"}), principal{"alice", roleAdmin}},
+		{"token without roles", true, mapping, DefaultOIDCRolesClaim, newTestToken(t, map[string]interface{}{"sub": "alice"}), principal{"alice", roleNone}},
+		{"viewer", true, mapping, DefaultOIDCRolesClaim, newTestToken(t, map[string]interface{}{"sub": "alice", "groups": []interface{}{"weron-viewers"}}), principal{"alice", roleViewer}},
+		{"role as string claim", true, mapping, DefaultOIDCRolesClaim, newTestToken(t, map[string]interface{}{"sub": "alice", "groups": "weron-operators"}), principal{"alice", roleOperator}},
+		{"multiple roles", true, mapping, DefaultOIDCRolesClaim, newTestToken(t, map[string]interface{}{"sub": "alice", "groups": []interface{}{"weron-admins", "weron-viewers", "other"}}), principal{"alice", roleAdmin}},
+		{"unmapped roles", true, mapping, DefaultOIDCRolesClaim, newTestToken(t, map[string]interface{}{"sub": "alice", "groups": []interface{}{"other", 1}}), principal{"alice", roleNone}},
+		{"custom roles claim", true, mapping, "roles", newTestToken(t, map[string]interface{}{"sub": "alice", "groups": "weron-admins", "roles": "weron-viewers"}), principal{"alice", roleViewer}},
+		{"malformed token", true, mapping, DefaultOIDCRolesClaim, "mypassword", principal{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Signaler{
+				oidcEnabled: tt.oidcEnabled,
+				roles:       tt.roles,
+				rolesClaim:  tt.rolesClaim,
+			}
+
+			if got := s.getPrincipal("admin", tt.token); got != tt.want {
+				t.Fatalf("getPrincipal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRolesRejectsUnknownRoles(t *testing.T) {
+	if _, err := parseRoles(map[string]string{"weron-admins": "superuser"}); err == nil {
+		t.Fatal("parseRoles() accepted an unknown role")
+	}
+}
+
+func TestCanManageCommunity(t *testing.T) {
+	db := memory.NewCommunitiesPersister()
+	if _, err := db.CreatePersistentCommunity(context.Background(), "mycommunity", "mypassword", persisters.Policy{}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		ownership bool
+		principal principal
+		community string
+		want      bool
+		wantErr   error
+	}{
+		{"viewer", false, principal{"alice", roleViewer}, "mycommunity", false, nil},
+		{"operator without ownership", false, principal{"bob", roleOperator}, "mycommunity", true, nil},
+		{"owner", true, principal{"alice", roleOperator}, "mycommunity", true, nil},
+		{"owner as viewer", true, principal{"alice", roleViewer}, "mycommunity", false, nil},
+		{"other operator", true, principal{"bob", roleOperator}, "mycommunity", false, nil},
+		{"operator without subject", true, principal{"", roleOperator}, "mycommunity", false, nil},
+		{"admin", true, principal{"bob", roleAdmin}, "mycommunity", true, nil},
+		{"missing community", true, principal{"alice", roleOperator}, "othercommunity", false, sql.ErrNoRows},
+		{"missing community as admin", true, principal{"bob", roleAdmin}, "othercommunity", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Signaler{
+				ctx: context.Background(),
+				db:  db,
+				config: &SignalerConfig{
+					OIDCCommunityOwnership: tt.ownership,
+				},
+			}
+
+			got, err := s.canManageCommunity(tt.principal, tt.community)
+			if err != tt.wantErr {
+				t.Fatalf("canManageCommunity() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("canManageCommunity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOwner(t *testing.T) {
+	tests := []struct {
+		name        string
+		oidcEnabled bool
+		ownership   bool
+		want        string
+	}{
+		{"ownership", true, true, "alice"},
+		{"ownership without OIDC", false, true, ""},
+		{"OIDC without ownership", true, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Signaler{
+				oidcEnabled: tt.oidcEnabled,
+				config: &SignalerConfig{
+					OIDCCommunityOwnership: tt.ownership,
+				},
+			}
+
+			if got := s.getOwner(principal{"alice", roleOperator}); got != tt.want {
+				t.Fatalf("getOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
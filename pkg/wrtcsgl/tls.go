@@ -0,0 +1,173 @@
+package wrtcsgl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	certificateReloadInterval = time.Second * 10
+)
+
+var (
+	errMissingTLSKey         = errors.New("missing TLS key")
+	errMissingTLSCertificate = errors.New("missing TLS certificate")
+	errInvalidTLSClientCA    = errors.New("could not parse any certificates from TLS client CA")
+)
+
+// certificateReloader serves a TLS certificate and reloads it once the certificate or key file changes
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+	lastChecked time.Time
+}
+
+func newCertificateReloader(certFile string, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	modTime, err := r.getModTime()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// getModTime returns the time of the latest change to either the certificate or the key file
+func (r *certificateReloader) getModTime() (time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+
+	return certInfo.ModTime(), nil
+}
+
+func (r *certificateReloader) load(modTime time.Time) error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.certificate = &certificate
+	r.modTime = modTime
+
+	return nil
+}
+
+// GetCertificate returns the current certificate; the files are checked for changes at most once per certificateReloadInterval
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if now := time.Now(); now.Sub(r.lastChecked) > certificateReloadInterval {
+		r.lastChecked = now
+
+		modTime, err := r.getModTime()
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("Could not check TLS certificate for changes, continuing to use the previous certificate")
+		} else if !modTime.Equal(r.modTime) {
+			// Certificates which are only partially written or don't match their key yet are retried on the next check
+			if err := r.load(modTime); err != nil {
+				log.Error().
+					Err(err).
+					Msg("Could not reload TLS certificate, continuing to use the previous certificate")
+			} else {
+				log.Info().
+					Str("certificate", r.certFile).
+					Msg("Reloaded TLS certificate")
+			}
+		}
+	}
+
+	return r.certificate, nil
+}
+
+// getTLSConfig creates the TLS configuration for the listener; it returns nil if TLS is disabled
+func getTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	if certFile == "" {
+		return nil, errMissingTLSCertificate
+	}
+
+	if keyFile == "" {
+		return nil, errMissingTLSKey
+	}
+
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if clientCAFile != "" {
+		clientCA, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(clientCA) {
+			return nil, errInvalidTLSClientCA
+		}
+
+		// Certificates are required by requireClientCertificate instead, so that the health probes can be reached without one
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
+
+// requireClientCertificate rejects requests without a verified client certificate if the TLS configuration verifies client certificates
+func requireClientCertificate(config *tls.Config, next http.Handler) http.Handler {
+	if config == nil || config.ClientCAs == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			rw.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
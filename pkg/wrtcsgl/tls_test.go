@@ -0,0 +1,47 @@
+package wrtcsgl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireClientCertificate(t *testing.T) {
+	verifying := &tls.Config{
+		ClientCAs:  x509.NewCertPool(),
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+
+	tests := []struct {
+		name   string
+		config *tls.Config
+		state  *tls.ConnectionState
+		want   int
+	}{
+		{"TLS disabled", nil, nil, http.StatusOK},
+		{"client certificates not verified", &tls.Config{}, &tls.ConnectionState{}, http.StatusOK},
+		{"verified client certificate", verifying, &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}, http.StatusOK},
+		{"missing client certificate", verifying, &tls.ConnectionState{}, http.StatusUnauthorized},
+		{"plaintext request", verifying, nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := requireClientCertificate(tt.config, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = tt.state
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, r)
+
+			if rw.Code != tt.want {
+				t.Fatalf("requireClientCertificate() status = %v, want %v", rw.Code, tt.want)
+			}
+		})
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -20,18 +22,25 @@ import (
 	"github.com/pojntfx/go-auth-utils/pkg/authn"
 	"github.com/pojntfx/go-auth-utils/pkg/authn/basic"
 	"github.com/pojntfx/go-auth-utils/pkg/authn/oidc"
+	websocketapi "github.com/pojntfx/weron/internal/api/websocket"
 	"github.com/pojntfx/weron/internal/brokers"
 	"github.com/pojntfx/weron/internal/brokers/process"
 	"github.com/pojntfx/weron/internal/brokers/redis"
 	"github.com/pojntfx/weron/internal/persisters"
 	"github.com/pojntfx/weron/internal/persisters/memory"
 	"github.com/pojntfx/weron/internal/persisters/psql"
+	managementv1 "github.com/pojntfx/weron/pkg/api/management/v1"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/time/rate"
 )
 
 var (
 	errMissingCommunity = errors.New("missing community")
 	errMissingPassword  = errors.New("missing password")
 
+	errTooManyConnections                = errors.New("too many connections from remote IP")
+	errEphemeralCommunitiesQuotaExceeded = errors.New("quota for new ephemeral communities from remote IP exceeded")
+
 	upgrader = websocket.Upgrader{}
 
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -40,6 +49,29 @@ var (
 type connection struct {
 	conn   *websocket.Conn
 	closer chan struct{}
+	close  func() // Closes closer; it is safe to call multiple times, i.e. if a client is kicked while its community is being deleted
+
+	ip             string
+	userAgent      string
+	connectedSince time.Time
+	bytesReceived  atomic.Int64
+	bytesSent      atomic.Int64
+}
+
+func newConnection(conn *websocket.Conn, ip string, userAgent string) *connection {
+	closer := make(chan struct{})
+
+	return &connection{
+		conn:   conn,
+		closer: closer,
+		close: sync.OnceFunc(func() {
+			close(closer)
+		}),
+
+		ip:             ip,
+		userAgent:      userAgent,
+		connectedSince: time.Now(),
+	}
 }
 
 // SignalerConfig configures the adapter
@@ -51,6 +83,25 @@ type SignalerConfig struct {
 	APIPassword          string        // Password for the API endpoint; ignored if any of the OIDC parameters are set
 	OIDCIssuer           string        // OpenID Connect issuer
 	OIDCClientID         string        // OpenID Connect client id
+	Metrics              bool          // Whether to expose Prometheus metrics
+	MetricsLaddr         string        // Listening address for the metrics endpoint; if empty, metrics are exposed on the main listener
+	Drain                time.Duration // Time to report the signaler as not ready before shutting down, so that load balancers can stop sending new clients
+
+	MaxFrameSize                int64   // Maximum size of a WebSocket frame from a client in bytes; clients which exceed it are disconnected with close code 1009 (0 disables the limit)
+	MessagesPerSecond           float64 // Maximum rate of messages per connection; clients which exceed it are disconnected with close code 1008 (0 disables the limit)
+	MessageBurst                int     // Maximum amount of messages per connection which may be sent at once (defaults to MessagesPerSecond)
+	MaxConnectionsPerIP         int     // Maximum amount of concurrent connections per remote IP; further connections are rejected with HTTP status 429 (0 disables the limit)
+	EphemeralCommunitiesPerHour int     // Maximum amount of new ephemeral communities per remote IP per hour; further communities are rejected with HTTP status 429 (0 disables the limit)
+	TrustForwardedFor           bool    // Whether to take the remote IP from the X-Forwarded-For header; only enable this behind a trusted proxy
+
+	TLSCertFile     string // Path to the PEM-encoded TLS certificate; if set, the signaler serves HTTPS and secure WebSockets (the certificate and key are reloaded when the files change)
+	TLSKeyFile      string // Path to the PEM-encoded TLS key
+	TLSClientCAFile string // Path to the PEM-encoded CA certificates to verify client certificates with; if set, clients must present a certificate signed by one of them for signaling, the management API and metrics, but not for the health probes
+
+	OIDCRolesClaim         string            // Claim of the OIDC token which contains the groups or roles of the client (defaults to DefaultOIDCRolesClaim)
+	OIDCRoles              map[string]string // Maps values of the roles claim to RoleViewer, RoleOperator or RoleAdmin; if empty, all clients with a valid OIDC token are admins
+	OIDCCommunityOwnership bool              // Whether operators can only manage the communities which they have created; admins can manage all communities
+	OIDCJoinAudiences      []string          // Audiences of the ID tokens with which clients can join communities which require OIDC (defaults to OIDCClientID)
 
 	OnConnect    func(raddr string, community string)                  // Handler to be called when a client has connected to the signaler
 	OnDisconnect func(raddr string, community string, err interface{}) // Handler to be called when a client has disconnected from the signaler
@@ -66,11 +117,26 @@ type Signaler struct {
 
 	errs            chan error
 	connectionsLock sync.Mutex
-	connections     map[string]map[string]connection
+	connections     map[string]map[string]*connection
 	db              persisters.CommunitiesPersister
 	broker          brokers.CommunitiesBroker
 	srv             *http.Server
+	metricsSrv      *http.Server
 	closeKicks      func() error
+	closeClients    func() error
+	metrics         *metrics
+	draining        atomic.Bool
+	replica         string // ID of the signaler among the signalers which share the broker
+
+	connectionCounter *connectionCounter
+	communityLimiters *ipLimiters
+
+	auth                 authn.Authn
+	managementAPIEnabled bool
+	oidcEnabled          bool
+	roles                map[string]role
+	rolesClaim           string
+	joinAuthns           []authn.Authn
 }
 
 // NewSignaler creates the signaler
@@ -92,7 +158,9 @@ func NewSignaler(
 		config:      config,
 		ctx:         ctx,
 
-		errs: make(chan error),
+		errs:    make(chan error),
+		metrics: newMetrics(),
+		replica: getReplica(),
 	}
 }
 
@@ -105,6 +173,15 @@ func (s *Signaler) Open() error {
 		return err
 	}
 
+	tlsConfig, err := getTLSConfig(
+		strings.TrimSpace(s.config.TLSCertFile),
+		strings.TrimSpace(s.config.TLSKeyFile),
+		strings.TrimSpace(s.config.TLSClientCAFile),
+	)
+	if err != nil {
+		return err
+	}
+
 	managementAPIEnabled := true
 	if (strings.TrimSpace(s.config.OIDCIssuer) == "" && strings.TrimSpace(s.config.OIDCClientID) == "") && strings.TrimSpace(s.config.APIPassword) == "" {
 		managementAPIEnabled = false
@@ -118,6 +195,11 @@ func (s *Signaler) Open() error {
 		s.db = psql.NewCommunitiesPersister()
 	}
 
+	s.db = &instrumentedPersister{
+		CommunitiesPersister: s.db,
+		duration:             s.metrics.persisterDuration,
+	}
+
 	if err := s.db.Open(s.postgresURL); err != nil {
 		return err
 	}
@@ -134,29 +216,92 @@ func (s *Signaler) Open() error {
 		s.broker = redis.NewCommunitiesBroker()
 	}
 
+	s.broker = &instrumentedBroker{
+		CommunitiesBroker: s.broker,
+		duration:          s.metrics.brokerPublishDuration,
+		errors:            s.metrics.brokerPublishErrors,
+	}
+
 	if err := s.broker.Open(s.ctx, s.redisURL); err != nil {
 		return err
 	}
 
 	var auth authn.Authn
+	oidcEnabled := false
 	if strings.TrimSpace(s.config.OIDCIssuer) == "" && strings.TrimSpace(s.config.OIDCClientID) == "" {
 		auth = basic.NewAuthn(s.config.APIUsername, s.config.APIPassword)
 	} else {
 		auth = oidc.NewAuthn(s.config.OIDCIssuer, s.config.OIDCClientID)
+		oidcEnabled = true
 	}
 
 	if err := auth.Open(s.ctx); err != nil {
 		return err
 	}
 
-	s.srv = &http.Server{Addr: addr.String()}
+	roles, err := parseRoles(s.config.OIDCRoles)
+	if err != nil {
+		return err
+	}
+
+	joinAuthns := []authn.Authn{}
+	if strings.TrimSpace(s.config.OIDCIssuer) != "" {
+		audiences := s.config.OIDCJoinAudiences
+		if len(audiences) == 0 && strings.TrimSpace(s.config.OIDCClientID) != "" {
+			audiences = []string{s.config.OIDCClientID}
+		}
 
-	s.connections = map[string]map[string]connection{}
+		for _, audience := range audiences {
+			joinAuthn := oidc.NewAuthn(s.config.OIDCIssuer, audience)
+			if err := joinAuthn.Open(s.ctx); err != nil {
+				return err
+			}
+
+			joinAuthns = append(joinAuthns, joinAuthn)
+		}
+	}
+
+	rolesClaim := s.config.OIDCRolesClaim
+	if strings.TrimSpace(rolesClaim) == "" {
+		rolesClaim = DefaultOIDCRolesClaim
+	}
+
+	s.auth = auth
+	s.managementAPIEnabled = managementAPIEnabled
+	s.oidcEnabled = oidcEnabled
+	s.roles = roles
+	s.rolesClaim = rolesClaim
+	s.joinAuthns = joinAuthns
+
+	s.connectionCounter = newConnectionCounter()
+	if s.config.EphemeralCommunitiesPerHour > 0 {
+		s.communityLimiters = newIPLimiters(
+			rate.Every(ephemeralCommunitiesLimitInterval/time.Duration(s.config.EphemeralCommunitiesPerHour)),
+			s.config.EphemeralCommunitiesPerHour,
+		)
+	}
+
+	s.metrics.limits.WithLabelValues(limitFrameSize).Set(float64(s.config.MaxFrameSize))
+	s.metrics.limits.WithLabelValues(limitMessageRate).Set(s.config.MessagesPerSecond)
+	s.metrics.limits.WithLabelValues(limitConnectionsPerIP).Set(float64(s.config.MaxConnectionsPerIP))
+	s.metrics.limits.WithLabelValues(limitEphemeralCommunitiesPerIP).Set(float64(s.config.EphemeralCommunitiesPerHour))
+
+	s.srv = &http.Server{
+		Addr:      addr.String(),
+		TLSConfig: tlsConfig,
+	}
+
+	s.connections = map[string]map[string]*connection{}
 
 	kicks, closeKicks := s.broker.SubscribeToKicks(s.ctx, s.errs)
 	s.closeKicks = closeKicks
 
-	s.srv.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+	clientsRequests, closeClientsRequests := s.broker.SubscribeToClientsRequests(s.ctx, s.errs)
+	s.closeClients = closeClientsRequests
+
+	go s.respondToClientsRequests(clientsRequests)
+
+	signaling := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		raddr := uuid.New().String()
 
 		defer func() {
@@ -202,11 +347,19 @@ func (s *Signaler) Open() error {
 				// List communities
 				u, p, ok := r.BasicAuth()
 				if err := auth.Validate(u, p); !ok || err != nil {
+					s.metrics.authFailures.WithLabelValues(authFailureManagement).Inc()
+
 					rw.WriteHeader(http.StatusUnauthorized)
 
 					panic(fmt.Errorf("%v", http.StatusUnauthorized))
 				}
 
+				if s.getPrincipal(u, p).role < roleViewer {
+					rw.WriteHeader(http.StatusForbidden)
+
+					panic(fmt.Errorf("%v", http.StatusForbidden))
+				}
+
 				pc, err := s.db.GetCommunities(s.ctx)
 				if err != nil {
 					panic(err)
@@ -224,31 +377,156 @@ func (s *Signaler) Open() error {
 				return
 			}
 
-			// Create ephemeral community
+			// Clients only fall back to sending the password in the URL if they can't tell that the signaler supports challenges, so this is announced even if the client is rejected before the upgrade
+			if isChallengeRequested(r) {
+				rw.Header().Set(websocketapi.HeaderChallenge, websocketapi.SubprotocolChallenge)
+			}
+
+			// Create ephemeral community; clients which support challenges don't send the password in the URL
 			password := r.URL.Query().Get("password")
-			if strings.TrimSpace(password) == "" {
+			challenge := strings.TrimSpace(password) == "" && isChallengeRequested(r)
+			if strings.TrimSpace(password) == "" && !challenge {
 				panic(errMissingPassword)
 			}
 
-			if err := s.db.AddClientsToCommunity(s.ctx, community, password, s.config.EphemeralCommunities); err != nil {
-				if err == authn.ErrWrongPassword || err == persisters.ErrEphemeralCommunitiesDisabled {
+			ip := getRemoteIP(r, s.config.TrustForwardedFor)
+
+			if !s.connectionCounter.add(ip, s.config.MaxConnectionsPerIP) {
+				s.metrics.limitRejections.WithLabelValues(limitConnectionsPerIP).Inc()
+
+				rw.WriteHeader(http.StatusTooManyRequests)
+
+				panic(errTooManyConnections)
+			}
+			defer s.connectionCounter.remove(ip)
+
+			// Clients of communities which require OIDC are rejected before the upgrade, so they don't need to support challenges to learn why
+			if err := s.authorizeJoin(r, community); err != nil {
+				if err == errMissingIDToken || err == errInvalidIDToken {
+					s.metrics.authFailures.WithLabelValues(authFailureCommunity).Inc()
+
 					rw.WriteHeader(http.StatusUnauthorized)
 
-					panic(fmt.Errorf("%v", http.StatusUnauthorized))
+					panic(err)
+				} else if err == errNotInOIDCGroup {
+					s.metrics.authFailures.WithLabelValues(authFailureCommunity).Inc()
+
+					rw.WriteHeader(http.StatusForbidden)
+
+					panic(err)
 				} else {
 					panic(err)
 				}
 			}
 
+			// Clients which register a recipient token only receive the routed frames which are addressed to them
+			recipient := r.URL.Query().Get(websocketapi.QueryRecipient)
+			if !websocketapi.IsRecipientToken(recipient) {
+				recipient = ""
+			}
+
+			var (
+				conn     *websocket.Conn
+				accepted *websocketapi.Accepted
+				err      error
+			)
+			if challenge {
+				// Errors are reported with close codes once the connection has been upgraded
+				conn, err = upgrader.Upgrade(rw, r, http.Header{
+					"Sec-Websocket-Protocol": []string{websocketapi.SubprotocolChallenge},
+				})
+				if err != nil {
+					panic(err)
+				}
+
+				accepted, err = s.authenticateChallenge(conn, community, ip)
+				if err != nil {
+					code, reason := getChallengeCloseCode(err)
+					switch code {
+					case websocketapi.CloseWrongPassword:
+						s.metrics.authFailures.WithLabelValues(authFailureCommunity).Inc()
+					case websocketapi.CloseTooManyRequests:
+						s.metrics.limitRejections.WithLabelValues(limitEphemeralCommunitiesPerIP).Inc()
+					}
+
+					log.Debug().
+						Err(err).
+						Str("address", raddr).
+						Str("community", community).
+						Msg("Client failed challenge")
+
+					if err := conn.WriteControl(
+						websocket.CloseMessage,
+						websocket.FormatCloseMessage(code, reason),
+						time.Now().Add(s.config.Heartbeat),
+					); err != nil {
+						log.Debug().
+							Err(err).
+							Str("address", raddr).
+							Msg("Could not send close message to client")
+					}
+
+					if err := conn.Close(); err != nil {
+						panic(err)
+					}
+
+					return
+				}
+			} else {
+				clients, err := s.joinCommunity(ip, func(upsert bool) (int, error) {
+					return s.db.AddClientsToCommunity(s.ctx, community, password, upsert)
+				})
+				if err != nil {
+					if err == errEphemeralCommunitiesQuotaExceeded {
+						s.metrics.limitRejections.WithLabelValues(limitEphemeralCommunitiesPerIP).Inc()
+
+						rw.WriteHeader(http.StatusTooManyRequests)
+
+						panic(err)
+					} else if err == persisters.ErrCommunityFull {
+						rw.WriteHeader(http.StatusConflict)
+
+						panic(err)
+					} else if err == persisters.ErrCommunityExpired {
+						rw.WriteHeader(http.StatusGone)
+
+						panic(err)
+					} else if err == authn.ErrWrongPassword || err == persisters.ErrEphemeralCommunitiesDisabled {
+						s.metrics.authFailures.WithLabelValues(authFailureCommunity).Inc()
+
+						rw.WriteHeader(http.StatusUnauthorized)
+
+						panic(fmt.Errorf("%v", http.StatusUnauthorized))
+					} else {
+						panic(err)
+					}
+				}
+
+				accepted = websocketapi.NewAccepted(clients, true, 0, nil)
+			}
+
 			defer func() {
 				if err := s.db.RemoveClientFromCommunity(s.ctx, community); err != nil {
 					panic(err)
 				}
 			}()
 
-			conn, err := upgrader.Upgrade(rw, r, nil)
-			if err != nil {
-				panic(err)
+			if challenge {
+				if err := s.writeChallengeMessage(conn, accepted); err != nil {
+					if err := conn.Close(); err != nil {
+						panic(err)
+					}
+
+					panic(err)
+				}
+			} else {
+				conn, err = upgrader.Upgrade(rw, r, http.Header{
+					websocketapi.HeaderClients: []string{strconv.Itoa(accepted.Clients)},
+					websocketapi.HeaderRouting: []string{"1"},
+				})
+				if err != nil {
+					panic(err)
+				}
 			}
 
 			defer func() {
@@ -256,6 +534,10 @@ func (s *Signaler) Open() error {
 				delete(s.connections[community], raddr)
 				if len(s.connections[community]) <= 0 {
 					delete(s.connections, community)
+
+					s.metrics.clients.DeleteLabelValues(community)
+				} else {
+					s.metrics.clients.WithLabelValues(community).Set(float64(len(s.connections[community])))
 				}
 				s.connectionsLock.Unlock()
 
@@ -273,14 +555,14 @@ func (s *Signaler) Open() error {
 				}
 			}()
 
+			c := newConnection(conn, ip, r.UserAgent())
+
 			s.connectionsLock.Lock()
 			if _, exists := s.connections[community]; !exists {
-				s.connections[community] = map[string]connection{}
-			}
-			s.connections[community][raddr] = connection{
-				conn:   conn,
-				closer: make(chan struct{}),
+				s.connections[community] = map[string]*connection{}
 			}
+			s.connections[community][raddr] = c
+			s.metrics.clients.WithLabelValues(community).Set(float64(len(s.connections[community])))
 			s.connectionsLock.Unlock()
 
 			log.Debug().
@@ -292,6 +574,11 @@ func (s *Signaler) Open() error {
 				s.config.OnConnect(raddr, community)
 			}
 
+			// This also lifts the limit of the challenge if MaxFrameSize is 0
+			conn.SetReadLimit(s.config.MaxFrameSize)
+
+			messages := newMessageLimiter(s.config.MessagesPerSecond, s.config.MessageBurst)
+
 			if err := conn.SetReadDeadline(time.Now().Add(s.config.Heartbeat)); err != nil {
 				panic(err)
 			}
@@ -307,6 +594,11 @@ func (s *Signaler) Open() error {
 				for {
 					messageType, p, err := conn.ReadMessage()
 					if err != nil {
+						// The client has already been sent close code 1009 by the WebSocket library
+						if err == websocket.ErrReadLimit {
+							s.metrics.limitRejections.WithLabelValues(limitFrameSize).Inc()
+						}
+
 						if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNoStatusReceived) {
 							errs <- err
 						}
@@ -322,10 +614,44 @@ func (s *Signaler) Open() error {
 						Int("type", messageType).
 						Msg("Received message")
 
+					if messages != nil && !messages.Allow() {
+						log.Debug().
+							Str("address", raddr).
+							Str("community", community).
+							Msg("Client exceeded message rate limit, disconnecting")
+
+						s.metrics.limitRejections.WithLabelValues(limitMessageRate).Inc()
+
+						if err := conn.WriteControl(
+							websocket.CloseMessage,
+							websocket.FormatCloseMessage(websocket.ClosePolicyViolation, closeReasonMessageRate),
+							time.Now().Add(s.config.Heartbeat),
+						); err != nil {
+							errs <- err
+
+							return
+						}
+
+						errs <- nil
+
+						return
+					}
+
+					s.metrics.relayedMessages.WithLabelValues(directionReceived).Inc()
+					s.metrics.relayedBytes.WithLabelValues(directionReceived).Add(float64(len(p)))
+					c.bytesReceived.Add(int64(len(p)))
+
+					to := ""
+					if token, envelope, ok := websocketapi.ParseRoutedFrame(p); ok {
+						to = token
+						p = envelope
+					}
+
 					if err := s.broker.PublishInput(s.ctx, brokers.Input{
 						Raddr:       raddr,
 						MessageType: messageType,
 						P:           p,
+						To:          to,
 					}, community); err != nil {
 						errs <- err
 
@@ -334,7 +660,7 @@ func (s *Signaler) Open() error {
 				}
 			}()
 
-			inputs, closeInputs := s.broker.SubscribeToInputs(s.ctx, errs, community)
+			inputs, closeInputs := s.broker.SubscribeToInputs(s.ctx, errs, community, recipient)
 			defer func() {
 				if err := closeInputs(); err != nil {
 					panic(err)
@@ -343,7 +669,7 @@ func (s *Signaler) Open() error {
 
 			for {
 				select {
-				case <-s.connections[community][raddr].closer:
+				case <-c.closer:
 					return
 				case err := <-errs:
 					panic(err)
@@ -357,6 +683,10 @@ func (s *Signaler) Open() error {
 						panic(err)
 					}
 
+					s.metrics.relayedMessages.WithLabelValues(directionSent).Inc()
+					s.metrics.relayedBytes.WithLabelValues(directionSent).Add(float64(len(input.P)))
+					c.bytesSent.Add(int64(len(input.P)))
+
 					if err := conn.SetWriteDeadline(time.Now().Add(s.config.Heartbeat)); err != nil {
 						panic(err)
 					}
@@ -385,11 +715,20 @@ func (s *Signaler) Open() error {
 			// Create persistent community
 			u, p, ok := r.BasicAuth()
 			if err := auth.Validate(u, p); !ok || err != nil {
+				s.metrics.authFailures.WithLabelValues(authFailureManagement).Inc()
+
 				rw.WriteHeader(http.StatusUnauthorized)
 
 				panic(fmt.Errorf("%v", http.StatusUnauthorized))
 			}
 
+			actor := s.getPrincipal(u, p)
+			if actor.role < roleOperator {
+				rw.WriteHeader(http.StatusForbidden)
+
+				panic(fmt.Errorf("%v", http.StatusForbidden))
+			}
+
 			password := r.URL.Query().Get("password")
 			if strings.TrimSpace(password) == "" {
 				panic(errMissingPassword)
@@ -400,15 +739,31 @@ func (s *Signaler) Open() error {
 				panic(errMissingCommunity)
 			}
 
-			c, err := s.db.CreatePersistentCommunity(s.ctx, community, password)
+			policy, err := getPolicyFromQuery(r.URL.Query())
 			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+
 				panic(err)
 			}
 
+			if policy.OIDCRequired && len(s.joinAuthns) == 0 {
+				rw.WriteHeader(http.StatusBadRequest)
+
+				panic(errOIDCNotConfigured)
+			}
+
+			c, err := s.db.CreatePersistentCommunity(s.ctx, community, password, policy, s.getOwner(actor))
+			if err != nil {
+				panic(err)
+			}
+
+			s.recordAuditEvent(r, actor.id, persisters.AuditActionCreateCommunity, getCommunityTarget(c.ID))
+
 			cc := persisters.Community{
 				ID:         c.ID,
 				Clients:    c.Clients,
 				Persistent: c.Persistent,
+				Policy:     c.Policy,
 			}
 
 			j, err := json.Marshal(cc)
@@ -431,6 +786,8 @@ func (s *Signaler) Open() error {
 			// Delete persistent community
 			u, p, ok := r.BasicAuth()
 			if err := auth.Validate(u, p); !ok || err != nil {
+				s.metrics.authFailures.WithLabelValues(authFailureManagement).Inc()
+
 				rw.WriteHeader(http.StatusUnauthorized)
 
 				panic(fmt.Errorf("%v", http.StatusUnauthorized))
@@ -441,7 +798,8 @@ func (s *Signaler) Open() error {
 				panic(errMissingCommunity)
 			}
 
-			if err := s.db.DeleteCommunity(s.ctx, community); err != nil {
+			actor := s.getPrincipal(u, p)
+			if ok, err := s.canManageCommunity(actor, community); err != nil {
 				if err == sql.ErrNoRows {
 					rw.WriteHeader(http.StatusNotFound)
 
@@ -449,14 +807,24 @@ func (s *Signaler) Open() error {
 				} else {
 					panic(err)
 				}
+			} else if !ok {
+				rw.WriteHeader(http.StatusForbidden)
+
+				panic(fmt.Errorf("%v", http.StatusForbidden))
 			}
 
-			if err := s.broker.PublishKick(s.ctx, brokers.Kick{
-				Community: community,
-			}); err != nil {
-				panic(err)
+			if err := s.deleteCommunity(community); err != nil {
+				if err == sql.ErrNoRows {
+					rw.WriteHeader(http.StatusNotFound)
+
+					panic(fmt.Errorf("%v", http.StatusNotFound))
+				} else {
+					panic(err)
+				}
 			}
 
+			s.recordAuditEvent(r, actor.id, persisters.AuditActionDeleteCommunity, getCommunityTarget(community))
+
 			return
 		default:
 			rw.WriteHeader(http.StatusNotImplemented)
@@ -465,27 +833,93 @@ func (s *Signaler) Open() error {
 		}
 	})
 
+	metricsHandler := promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+
+	authenticated := http.NewServeMux()
+	authenticated.Handle("/", signaling)
+	authenticated.HandleFunc(managementv1.PathPrefix+"/", s.handleAPINotFound)
+	authenticated.HandleFunc(managementv1.PathOpenAPI, s.handleOpenAPI)
+	authenticated.HandleFunc(managementv1.PathCommunities, s.handleCommunities)
+	authenticated.HandleFunc(managementv1.PathCommunities+"/{id}", s.handleCommunity)
+	authenticated.HandleFunc(managementv1.PathCommunities+"/{id}/"+managementv1.PathPassword, s.handleCommunityPassword)
+	authenticated.HandleFunc(managementv1.PathCommunities+"/{id}/"+managementv1.PathClients, s.handleCommunityClients)
+	authenticated.HandleFunc(managementv1.PathCommunities+"/{id}/"+managementv1.PathClients+"/{client}", s.handleCommunityClient)
+	authenticated.HandleFunc(managementv1.PathAudit, s.handleAudit)
+
+	// Probes are sent by orchestrators which usually can't present a client certificate
+	mux := http.NewServeMux()
+	mux.Handle("/", requireClientCertificate(tlsConfig, authenticated))
+	mux.HandleFunc(HealthPath, s.handleHealth)
+	mux.HandleFunc(ReadinessPath, s.handleReadiness)
+
+	if s.config.Metrics {
+		if strings.TrimSpace(s.config.MetricsLaddr) == "" {
+			authenticated.Handle(MetricsPath, metricsHandler)
+		} else {
+			metricsAddr, err := net.ResolveTCPAddr("tcp", s.config.MetricsLaddr)
+			if err != nil {
+				return err
+			}
+
+			metricsMux := http.NewServeMux()
+			metricsMux.Handle(MetricsPath, metricsHandler)
+
+			s.metricsSrv = &http.Server{
+				Addr:    metricsAddr.String(),
+				Handler: metricsMux,
+			}
+		}
+
+		go s.metrics.countCommunities(s.ctx, s.db, communitiesInterval)
+	}
+
+	s.srv.Handler = mux
+
 	go func() {
 		for {
 			kick := <-kicks
 
 			s.connectionsLock.Lock()
-			c, ok := s.connections[kick.Community]
-			if !ok {
-				s.connectionsLock.Unlock()
-
-				continue
+			kicked := []*connection{}
+			if kick.Client != nil {
+				if conn, ok := s.connections[kick.Client.Community][kick.Client.ID]; ok {
+					kicked = append(kicked, conn)
+				}
+			} else {
+				for _, conn := range s.connections[kick.Community] {
+					kicked = append(kicked, conn)
+				}
 			}
 			s.connectionsLock.Unlock()
 
-			for _, conn := range c {
-				close(conn.closer)
+			for _, conn := range kicked {
+				conn.close()
+
+				s.metrics.kicks.Inc()
 			}
 		}
 	}()
 
+	if s.metricsSrv != nil {
+		go func() {
+			if err := s.metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.errs <- err
+			}
+		}()
+	}
+
 	go func() {
-		if err := s.srv.ListenAndServe(); err != nil {
+		serve := s.srv.ListenAndServe
+		if s.srv.TLSConfig != nil {
+			// The certificate is provided by the TLS config so that it can be reloaded
+			serve = func() error {
+				return s.srv.ListenAndServeTLS("", "")
+			}
+		}
+
+		if err := serve(); err != nil {
 			if err == http.ErrServerClosed {
 				close(s.errs)
 
@@ -505,6 +939,19 @@ func (s *Signaler) Open() error {
 func (s *Signaler) Close() error {
 	log.Trace().Msg("Closing signaler")
 
+	s.draining.Store(true)
+
+	if s.config.Drain > 0 {
+		log.Debug().
+			Dur("drain", s.config.Drain).
+			Msg("Draining signaler")
+
+		select {
+		case <-s.ctx.Done():
+		case <-time.After(s.config.Drain):
+		}
+	}
+
 	s.connectionsLock.Lock()
 	defer s.connectionsLock.Unlock()
 	for c := range s.connections {
@@ -521,12 +968,26 @@ func (s *Signaler) Close() error {
 		}
 	}
 
+	if err := s.closeClients(); err != nil {
+		if err != context.Canceled {
+			return err
+		}
+	}
+
 	if err := s.broker.Close(); err != nil {
 		if err != context.Canceled && err != rediserr.ErrClosed {
 			return err
 		}
 	}
 
+	if s.metricsSrv != nil {
+		if err := s.metricsSrv.Shutdown(s.ctx); err != nil {
+			if err != context.Canceled {
+				return err
+			}
+		}
+	}
+
 	if err := s.srv.Shutdown(s.ctx); err != nil {
 		if err != context.Canceled {
 			return err